@@ -0,0 +1,98 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var (
+	// ErrSizeMismatch indica que o número de bytes recebidos difere do tamanho anunciado
+	ErrSizeMismatch = errors.New("tamanho do arquivo não confere")
+
+	// ErrChecksumMismatch indica que o SHA-256 dos dados recebidos difere do anunciado
+	ErrChecksumMismatch = errors.New("checksum do arquivo não confere")
+)
+
+// Checksum calcula o SHA-256 do conteúdo lido de r e retorna o hash em
+// hexadecimal junto com o número de bytes lidos
+func Checksum(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.CopyBuffer(h, r, make([]byte, ChunkSize))
+	if err != nil {
+		return "", n, fmt.Errorf("erro ao calcular checksum: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// verifyingReader repassa as leituras de r e, ao atingir io.EOF, confere o
+// tamanho e o SHA-256 dos dados lidos com os valores esperados
+type verifyingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	read     int64
+	size     int64
+	checksum string
+}
+
+// NewVerifyingReader cria um leitor que falha em vez de retornar io.EOF se os
+// dados lidos não tiverem o tamanho e o checksum esperados. Um size negativo
+// ou um checksum vazio desativam a respectiva verificação
+func NewVerifyingReader(r io.Reader, size int64, checksum string) io.Reader {
+	return &verifyingReader{
+		r:        r,
+		hash:     sha256.New(),
+		size:     size,
+		checksum: checksum,
+	}
+}
+
+// Read lê de r atualizando o hash e valida o conteúdo ao final do stream
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.hash.Write(p[:n])
+	v.read += int64(n)
+
+	if v.size >= 0 && v.read > v.size {
+		return n, fmt.Errorf("%w: recebidos mais de %d bytes", ErrSizeMismatch, v.size)
+	}
+
+	if err == io.EOF {
+		if v.size >= 0 && v.read != v.size {
+			return n, fmt.Errorf("%w: esperado %d, recebido %d", ErrSizeMismatch, v.size, v.read)
+		}
+		if v.checksum != "" {
+			if sum := hex.EncodeToString(v.hash.Sum(nil)); sum != v.checksum {
+				return n, fmt.Errorf("%w: esperado %s, calculado %s", ErrChecksumMismatch, v.checksum, sum)
+			}
+		}
+	}
+
+	return n, err
+}
+
+// storedReader confere o conteúdo lido do armazenamento com o tamanho e o
+// SHA-256 registrados no upload, trocando as falhas de verificação por
+// ErrCorrupted
+type storedReader struct {
+	r    io.Reader
+	name string
+}
+
+// verifyStored envolve o conteúdo do arquivo name, lido de r, com a
+// verificação de size e checksum, como NewVerifyingReader
+func verifyStored(r io.Reader, name string, size int64, checksum string) io.Reader {
+	return &storedReader{r: NewVerifyingReader(r, size, checksum), name: name}
+}
+
+// Read lê de r, indicando ErrCorrupted se o conteúdo não conferir
+func (s *storedReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if errors.Is(err, ErrSizeMismatch) || errors.Is(err, ErrChecksumMismatch) {
+		err = fmt.Errorf("%w: %s: %v", ErrCorrupted, s.name, err)
+	}
+	return n, err
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChecksumsDirName é o nome do diretório, dentro do diretório de dados, onde o
+// LocalStorage registra o SHA-256 do conteúdo gravado em cada upload
+const ChecksumsDirName = ".checksums"
+
+// checksumRecord é o registro do conteúdo de um arquivo, gravado no upload em
+// ChecksumsDirName, no mesmo caminho do arquivo, com a expiração e as tags
+// definidas no upload. StoredSize e ModTime identificam o arquivo descrito:
+// um registro que não confere com o arquivo atual é ignorado, e o checksum é
+// recalculado como o de um arquivo gravado antes dos registros existirem
+type checksumRecord struct {
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`                // Tamanho do conteúdo original
+	StoredSize int64     `json:"stored_size"`         // Tamanho do arquivo em disco
+	ModTime    time.Time `json:"mod_time"`            // Data de modificação do arquivo em disco
+	ExpiresAt  time.Time `json:"expires_at,omitzero"` // Expiração definida no upload (ver UploadOptions.TTL)
+
+	// Compressed indica se o arquivo em disco foi compactado pelo
+	// armazenamento; nil nos registros gravados antes do campo existir
+	Compressed *bool `json:"compressed,omitempty"`
+
+	Tags map[string]string `json:"tags,omitempty"` // Tags gravadas no upload (ver UploadOptions.Tags)
+}
+
+// readChecksum lê o registro de name e indica se ele descreve o arquivo stat
+// Registros ausentes, ilegíveis ou de outra versão do arquivo são ignorados
+func (ls *LocalStorage) readChecksum(name string, stat os.FileInfo) (checksumRecord, bool) {
+	return ls.readRecord(ls.checksumPath(name), stat)
+}
+
+// readRecord lê o registro em recordPath, como readChecksum
+func (ls *LocalStorage) readRecord(recordPath string, stat os.FileInfo) (checksumRecord, bool) {
+	file, err := ls.root.Open(recordPath)
+	if err != nil {
+		return checksumRecord{}, false
+	}
+	defer file.Close()
+
+	var record checksumRecord
+	if err := json.NewDecoder(file).Decode(&record); err != nil {
+		return checksumRecord{}, false
+	}
+	if sum, err := hex.DecodeString(record.SHA256); err != nil || len(sum) != sha256.Size {
+		return checksumRecord{}, false
+	}
+
+	if record.StoredSize != stat.Size() || !record.ModTime.Equal(stat.ModTime()) {
+		return checksumRecord{}, false
+	}
+	return record, true
+}
+
+// writeChecksum grava o registro de name, substituindo o anterior
+// O registro não é sincronizado em disco: um registro perdido ou incompleto
+// após uma queda é ignorado por readChecksum
+// NOTA: Esta função assume que o chamador obteve o lock de name com lockPaths
+func (ls *LocalStorage) writeChecksum(name string, record checksumRecord) error {
+	recordPath := ls.checksumPath(name)
+	if err := ls.prepareChecksumDir(filepath.Dir(recordPath)); err != nil {
+		return fmt.Errorf("erro ao criar diretório de checksums de %s: %w", name, err)
+	}
+
+	// Um diretório no lugar do registro é resto de um diretório removido
+	if stat, err := ls.root.Lstat(recordPath); err == nil && stat.IsDir() {
+		if err := removeAll(ls.root, recordPath); err != nil {
+			return fmt.Errorf("erro ao remover checksums de %s: %w", name, err)
+		}
+	}
+
+	tmp, tmpPath, err := createTemp(ls.root, filepath.Join(ChecksumsDirName, tempFilePrefix))
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer tmp.Close()
+
+	// O flock impede que cleanupTempFiles de outro processo remova o
+	// temporário antes do rename
+	err = lockFile(tmp, true)
+	if err == nil {
+		err = json.NewEncoder(tmp).Encode(record)
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = renameInRoot(ls.root, tmpPath, recordPath)
+	}
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return fmt.Errorf("erro ao registrar checksum de %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// removeChecksums remove o registro de name ou, se name for um diretório, os
+// registros de todo o seu conteúdo
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) removeChecksums(name string) error {
+	// O Lstat também falha se um registro ocupar o lugar de um dos
+	// diretórios do caminho, e então não há registros a remover
+	recordPath := ls.checksumPath(name)
+	if _, err := ls.root.Lstat(recordPath); err != nil {
+		return nil
+	}
+
+	if err := removeAll(ls.root, recordPath); err != nil {
+		return fmt.Errorf("erro ao remover checksums de %s: %w", name, err)
+	}
+	return nil
+}
+
+// moveChecksums move os registros de oldName, se existirem, para newName
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) moveChecksums(oldName, newName string) error {
+	if err := ls.removeChecksums(newName); err != nil {
+		return err
+	}
+
+	oldPath := ls.checksumPath(oldName)
+	if _, err := ls.root.Lstat(oldPath); err != nil {
+		return nil
+	}
+
+	newPath := ls.checksumPath(newName)
+	if err := ls.prepareChecksumDir(filepath.Dir(newPath)); err != nil {
+		return fmt.Errorf("erro ao criar diretório de checksums de %s: %w", newName, err)
+	}
+	if err := renameInRoot(ls.root, oldPath, newPath); err != nil {
+		return fmt.Errorf("erro ao mover checksums de %s para %s: %w", oldName, newName, err)
+	}
+
+	return nil
+}
+
+// prepareChecksumDir cria o diretório de registros dir. Um registro deixado
+// por uma queda durante um rename ou uma remoção pode ocupar o lugar de um
+// dos diretórios do caminho; nesse caso ele é removido
+func (ls *LocalStorage) prepareChecksumDir(dir string) error {
+	if err := mkdirAll(ls.root, dir); err == nil {
+		return nil
+	}
+
+	current := ""
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+
+		stat, err := ls.root.Lstat(current)
+		if err != nil {
+			break
+		}
+		if !stat.IsDir() {
+			if err := ls.root.Remove(current); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return mkdirAll(ls.root, dir)
+}
+
+// checksumPath retorna o caminho, relativo ao diretório base, do registro de name
+func (ls *LocalStorage) checksumPath(name string) string {
+	return filepath.Join(ChecksumsDirName, ls.path(name))
+}
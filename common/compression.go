@@ -0,0 +1,182 @@
+package common
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strings"
+)
+
+// MaxCompressionLevel é o maior nível de compressão aceito pelo LocalStorage
+const MaxCompressionLevel = gzip.BestCompression
+
+// Os arquivos compactados pelo LocalStorage são streams gzip comuns cujo
+// cabeçalho tem um subcampo extra (RFC 1952, seção 2.3.1.1) identificado por
+// compressionMarker. O subcampo guarda o tamanho e o SHA-256 do conteúdo
+// original, gravados depois da compressão sobre um espaço reservado; assim as
+// informações de um arquivo compactado saem do cabeçalho, sem descompactá-lo.
+// Se o arquivo foi compactado é decidido pelos registros que o armazenamento
+// grava junto do conteúdo (checksumRecord, os registros de versão e as
+// entradas da lixeira), e não pelo subcampo, que um arquivo .gz enviado por
+// um usuário pode conter. O subcampo só decide para arquivos sem registro,
+// gravados antes dos registros terem esse campo ou cujo registro se perdeu
+// em uma queda
+const (
+	compressionMarker = "GF"
+
+	// compressionDataLen é o tamanho dos dados do subcampo: 8 bytes do
+	// tamanho original, little-endian, seguidos dos 32 bytes do SHA-256
+	compressionDataLen = 8 + 32
+
+	// compressionDataOffset é a posição dos dados do subcampo no arquivo:
+	// 10 bytes do cabeçalho fixo, 2 do tamanho do campo extra e 4 da
+	// identificação e do tamanho do subcampo
+	compressionDataOffset = 10 + 2 + 4
+)
+
+// incompressibleTypes são tipos de conteúdo que já são compactados e não
+// ficam menores com uma nova compressão
+var incompressibleTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/zip":              true,
+	"application/x-zip-compressed": true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/vnd.rar":          true,
+	"application/x-bzip2":          true,
+	"application/x-xz":             true,
+	"application/zstd":             true,
+	"application/x-compress":       true,
+	"application/epub+zip":         true,
+	"application/java-archive":     true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+}
+
+// storedContent descreve o conteúdo original de um arquivo compactado
+type storedContent struct {
+	Size   int64
+	SHA256 string
+}
+
+// compressible indica se vale a pena compactar conteúdo do tipo contentType
+// Imagens, áudio e vídeo, com exceção de formatos sem compressão própria,
+// arquivos compactados e documentos que são pacotes zip são gravados sem alteração
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+
+	if incompressibleTypes[mediaType] {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(mediaType, "application/vnd.oasis.opendocument.") {
+		return false
+	}
+
+	switch mediaType {
+	case "image/svg+xml", "image/bmp", "image/x-ms-bmp", "image/tiff", "audio/wav", "audio/x-wav":
+		return true
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newCompressWriter cria o gzip.Writer que compacta o conteúdo gravado em w,
+// com o subcampo que identifica o arquivo e o espaço reservado para
+// completeCompressed. w deve estar no início do arquivo
+func newCompressWriter(w io.Writer, level int) (*gzip.Writer, error) {
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+
+	extra := make([]byte, 4+compressionDataLen)
+	copy(extra, compressionMarker)
+	binary.LittleEndian.PutUint16(extra[2:], compressionDataLen)
+	zw.Header.Extra = extra
+
+	return zw, nil
+}
+
+// completeCompressed grava o tamanho e o SHA-256 do conteúdo original no
+// cabeçalho de um arquivo criado com newCompressWriter, já fechado
+func completeCompressed(file *os.File, size int64, sum []byte) error {
+	data := make([]byte, compressionDataLen)
+	binary.LittleEndian.PutUint64(data, uint64(size))
+	copy(data[8:], sum)
+
+	_, err := file.WriteAt(data, compressionDataOffset)
+	return err
+}
+
+// openContent retorna um leitor do conteúdo original de file, que deve estar
+// no início. compressed é o que o registro do arquivo diz sobre ele ter sido
+// compactado pelo armazenamento, ou nil se não houver registro, e então o
+// subcampo do cabeçalho decide. Se o arquivo foi compactado, o leitor o
+// descompacta e stored descreve o conteúdo original; caso contrário stored é
+// nil e file é lido sem alteração. A integridade dos dados compactados é
+// conferida pelo CRC do gzip ao final da leitura
+func openContent(file *os.File, compressed *bool) (io.Reader, *storedContent, error) {
+	if compressed != nil && !*compressed {
+		return file, nil, nil
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err == nil {
+		if stored, ok := parseCompressionExtra(zr.Header.Extra); ok {
+			return zr, stored, nil
+		}
+	}
+	if compressed != nil {
+		return nil, nil, fmt.Errorf("%w: cabeçalho de compressão inválido", ErrCorrupted)
+	}
+
+	// Não é um arquivo compactado pelo armazenamento, mesmo que seja um gzip
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, fmt.Errorf("erro ao posicionar arquivo: %w", err)
+	}
+	return file, nil, nil
+}
+
+// parseCompressionExtra lê o subcampo gravado por newCompressWriter e
+// completeCompressed no campo extra de um cabeçalho gzip
+func parseCompressionExtra(extra []byte) (*storedContent, bool) {
+	for len(extra) >= 4 {
+		id := string(extra[:2])
+		n := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+n {
+			return nil, false
+		}
+		data := extra[4 : 4+n]
+		extra = extra[4+n:]
+
+		if id != compressionMarker || n != compressionDataLen {
+			continue
+		}
+		return &storedContent{
+			Size:   int64(binary.LittleEndian.Uint64(data)),
+			SHA256: hex.EncodeToString(data[8:]),
+		}, true
+	}
+
+	return nil, false
+}
+
+// readCloser combina o leitor do conteúdo de um arquivo com o Close do arquivo
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,112 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hostileGzip retorna um .gz cujo cabeçalho tem o subcampo de
+// newCompressWriter, com o tamanho e o SHA-256 corretos do conteúdo, como um
+// usuário que imitasse um arquivo compactado pelo armazenamento
+func hostileGzip(t *testing.T, content string) []byte {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(content))
+	extra := make([]byte, 4+compressionDataLen)
+	copy(extra, compressionMarker)
+	binary.LittleEndian.PutUint16(extra[2:], compressionDataLen)
+	binary.LittleEndian.PutUint64(extra[4:], uint64(len(content)))
+	copy(extra[12:], sum[:])
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Header.Extra = extra
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestCompressionHostileGzip grava um .gz com o subcampo do armazenamento e
+// confere que ele é tratado como o arquivo enviado, e não descompactado, no
+// arquivo atual, nas versões e na lixeira, e que um arquivo compactado pelo
+// armazenamento continua sendo descompactado
+func TestCompressionHostileGzip(t *testing.T) {
+	dir := t.TempDir()
+	opts := LocalStorageOptions{
+		Versions:         VersionPolicy{MaxVersions: 5},
+		CompressionLevel: gzip.BestSpeed,
+		TrashRetention:   time.Hour,
+	}
+	ls, err := NewLocalStorage(dir, opts)
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	hostile := string(hostileGzip(t, "conteúdo escondido"))
+	plain := strings.Repeat("texto compressível ", 1000)
+
+	uploadString(t, ls, "a.txt", hostile)
+	checkDownload(t, ls, "a.txt", hostile)
+
+	// Um novo LocalStorage não tem as informações em cache e as lê do registro
+	reopened, err := NewLocalStorage(dir, opts)
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	info, err := reopened.StatFile("a.txt")
+	if err != nil || info.Size != int64(len(hostile)) || info.SHA256 != sumOf(hostile) {
+		t.Errorf("StatFile: %+v, %v; esperado tamanho %d e checksum %s", info, err, len(hostile), sumOf(hostile))
+	}
+	checkDownload(t, reopened, "a.txt", hostile)
+
+	// O .gz vai para as versões e volta por RestoreVersion
+	uploadString(t, ls, "a.txt", plain)
+	versions, err := ls.ListVersions("a.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("ListVersions: %v, %v", versions, err)
+	}
+	if versions[0].Size != int64(len(hostile)) || versions[0].SHA256 != sumOf(hostile) {
+		t.Errorf("ListVersions: %+v", versions[0])
+	}
+	rc, _, err := ls.DownloadVersion("a.txt", versions[0].ID)
+	if got, err := readAllClose(rc, err); err != nil || string(got) != hostile {
+		t.Errorf("DownloadVersion: %d bytes, %v; esperado o .gz enviado", len(got), err)
+	}
+
+	// O conteúdo compactado pelo armazenamento continua sendo descompactado
+	info, err = reopened.StatFile("a.txt")
+	if err != nil || info.Size != int64(len(plain)) || info.StoredSize >= info.Size {
+		t.Errorf("StatFile do conteúdo compactado: %+v, %v", info, err)
+	}
+	checkDownload(t, reopened, "a.txt", plain)
+
+	if err := ls.RestoreVersion("a.txt", versions[0].ID); err != nil {
+		t.Fatalf("RestoreVersion: %v", err)
+	}
+	checkDownload(t, reopened, "a.txt", hostile)
+
+	// Removido, o .gz vai para a lixeira
+	if err := ls.DeleteFile("a.txt"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	entries, err := ls.ListTrash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListTrash: %v, %v", entries, err)
+	}
+	if got, err := readAllClose(ls.DownloadTrash(entries[0].ID)); err != nil || string(got) != hostile {
+		t.Errorf("DownloadTrash: %d bytes, %v; esperado o .gz enviado", len(got), err)
+	}
+	if _, err := ls.RestoreTrash(entries[0].ID); err != nil {
+		t.Fatalf("RestoreTrash: %v", err)
+	}
+	checkDownload(t, reopened, "a.txt", hostile)
+}
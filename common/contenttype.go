@@ -0,0 +1,37 @@
+package common
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+)
+
+// sniffLen é o número de bytes iniciais usados para detectar o tipo do conteúdo
+const sniffLen = 512
+
+// DetectContentType determina o tipo MIME de um arquivo pela extensão do nome
+// ou, quando a extensão é desconhecida, pelos primeiros bytes do conteúdo
+func DetectContentType(name string, head []byte) string {
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		return ct
+	}
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	return http.DetectContentType(head)
+}
+
+// prefixWriter guarda os primeiros bytes escritos nele, até limit, e descarta
+// o restante; permite detectar o tipo do conteúdo durante uma cópia
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+// Write guarda o que couber de p no limite e sempre aceita p inteiro
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,685 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlobsDirName é o nome do diretório, dentro do diretório de dados, onde o
+// DedupStorage guarda o conteúdo dos arquivos
+const BlobsDirName = ".blobs"
+
+// refsSuffix identifica o arquivo com o contador de referências de um blob
+const refsSuffix = ".refs"
+
+// DedupStorage implementa FileService armazenando cada conteúdo uma única vez
+// O conteúdo fica em um blob nomeado pelo seu SHA-256 e cada arquivo é uma
+// pequena referência para o blob, então arquivos idênticos, com qualquer nome,
+// ocupam espaço em disco uma única vez. Cada blob tem um contador de
+// referências e é removido quando a última referência deixa de existir
+//
+// As referências formam uma árvore de diretórios gerenciada por um
+// LocalStorage, que fornece a validação de nomes, os locks, a listagem e as
+// operações sobre diretórios. Os contadores são sempre incrementados antes de
+// a referência ser gravada e decrementados depois de ela ser removida: uma
+// queda no meio de uma operação pode deixar um blob sem referências no disco,
+// mas nunca uma referência para um blob removido. Os contadores são
+// recontados a partir das referências ao abrir o armazenamento, e os blobs
+// sem referências são removidos
+type DedupStorage struct {
+	ns *LocalStorage
+}
+
+// dedupRef é o conteúdo do arquivo de referência de um DedupStorage
+type dedupRef struct {
+	SHA256      string            `json:"sha256"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"content_type"`
+	ExpiresAt   time.Time         `json:"expires_at,omitzero"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// NewDedupStorage cria uma nova instância de DedupStorage
+// Cria o diretório base se ele não existir e corrige os contadores de
+// referências deixados errados por quedas (ver recount)
+func NewDedupStorage(baseDir string) (*DedupStorage, error) {
+	ns, err := NewLocalStorage(baseDir, LocalStorageOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DedupStorage{ns: ns}
+	ns.readInfo = ds.refInfo
+	if err := ds.recount(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// ListFiles retorna as informações dos arquivos e diretórios de opts.Dir
+func (ds *DedupStorage) ListFiles(opts ListOptions) ([]FileInfo, string, error) {
+	return ds.ns.ListFiles(opts)
+}
+
+// UploadFile grava o conteúdo lido de r no arquivo especificado
+// O conteúdo é gravado em um arquivo temporário e, se já existir um blob com
+// o mesmo SHA-256, descartado; caso contrário o temporário se torna o blob.
+// A referência é então gravada no lugar do arquivo com um rename, então
+// leitores veem a versão anterior completa ou a nova versão completa
+func (ds *DedupStorage) UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+	if err := validateTags(opts.Tags); err != nil {
+		return FileInfo{}, err
+	}
+	root := ds.ns.root
+
+	tmp, tmpPath, err := createTemp(root, tempFilePrefix)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer tmp.Close()
+	if err := lockFile(tmp, true); err != nil {
+		root.Remove(tmpPath)
+		return FileInfo{}, fmt.Errorf("erro ao travar arquivo temporário: %w", err)
+	}
+
+	hash := sha256.New()
+	head := &prefixWriter{limit: sniffLen}
+	n, err := io.CopyBuffer(io.MultiWriter(tmp, hash, head), r, make([]byte, ChunkSize))
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		root.Remove(tmpPath)
+		return FileInfo{}, fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+	}
+
+	ref := dedupRef{
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		Size:        n,
+		ContentType: DetectContentType(name, head.buf),
+		ExpiresAt:   opts.expiresAt(time.Now()),
+		Tags:        cloneTags(opts.Tags),
+	}
+
+	unlock, err := ds.lockRefs(false)
+	if err != nil {
+		root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+	defer unlock()
+
+	// A partir daqui o blob tem uma referência a mais, que writeRef desfaz se a
+	// referência do arquivo não chegar a ser gravada
+	if err := ds.storeBlob(ref.SHA256, tmpPath); err != nil {
+		return FileInfo{}, err
+	}
+
+	return ds.writeRef(name, ref, opts)
+}
+
+// storeBlob guarda o conteúdo do arquivo temporário tmpPath como o blob sum,
+// ou o descarta se o blob já existir, e incrementa o contador de referências
+func (ds *DedupStorage) storeBlob(sum, tmpPath string) error {
+	root := ds.ns.root
+
+	unlock, err := ds.lockBlob(sum)
+	if err != nil {
+		root.Remove(tmpPath)
+		return err
+	}
+	defer unlock()
+
+	blobPath := ds.blobPath(sum)
+	if _, err := root.Lstat(blobPath); err == nil {
+		root.Remove(tmpPath)
+	} else {
+		if err := mkdirAll(root, filepath.Dir(blobPath)); err != nil {
+			root.Remove(tmpPath)
+			return fmt.Errorf("erro ao criar diretório de blobs: %w", err)
+		}
+		if err := renameInRoot(root, tmpPath, blobPath); err != nil {
+			root.Remove(tmpPath)
+			return fmt.Errorf("erro ao gravar blob %s: %w", sum, err)
+		}
+		if err := syncDir(root, filepath.Dir(blobPath)); err != nil {
+			return err
+		}
+	}
+
+	return ds.addRefs(sum, 1)
+}
+
+// writeRef grava a referência ref no arquivo name, depois de avaliar as
+// condições de opts, e libera o blob referenciado anteriormente por name
+// Se a referência não chegar a ser gravada, libera a referência ao blob de
+// ref obtida pelo chamador com storeBlob
+func (ds *DedupStorage) writeRef(name string, ref dedupRef, opts UploadOptions) (FileInfo, error) {
+	root := ds.ns.root
+
+	tmp, tmpPath, stat, err := ds.createRef(name, ref)
+	if err != nil {
+		ds.releaseBlob(ref.SHA256)
+		return FileInfo{}, err
+	}
+	defer tmp.Close()
+
+	// Desfaz o temporário e a referência ao blob quando o upload é abortado
+	abort := func(err error) (FileInfo, error) {
+		root.Remove(tmpPath)
+		ds.releaseBlob(ref.SHA256)
+		return FileInfo{}, err
+	}
+
+	unlock, err := ds.ns.lockPaths(true, name)
+	if err != nil {
+		return abort(err)
+	}
+	defer unlock()
+
+	filePath, err := ds.ns.prepareTarget(name)
+	if err != nil {
+		return abort(err)
+	}
+
+	if opts.conditional() {
+		if err := ds.ns.checkPreconditions(name, opts); err != nil {
+			return abort(err)
+		}
+	}
+
+	previous, err := ds.readRef(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return abort(fmt.Errorf("erro ao ler referência de %s: %w", name, err))
+	}
+	replaced := err == nil
+
+	if err := renameInRoot(root, tmpPath, filePath); err != nil {
+		return abort(fmt.Errorf("erro ao mover arquivo para %s: %w", filePath, err))
+	}
+
+	info := ref.fileInfo(name, stat.ModTime())
+
+	// Sincroniza o diretório para que o rename sobreviva a uma queda do sistema
+	if err := syncDir(root, filepath.Dir(filePath)); err != nil {
+		return info, err
+	}
+
+	// A referência anterior já não existe; seu blob perde uma referência
+	if replaced {
+		if err := ds.releaseBlob(previous.SHA256); err != nil {
+			return info, err
+		}
+	}
+
+	return info, nil
+}
+
+// createRef grava ref em um arquivo temporário e o retorna aberto, com seu
+// caminho e suas informações. Como nos uploads do LocalStorage, o flock sobre
+// o temporário, mantido até o chamador fechá-lo, impede que outro processo o
+// remova como resto de um upload interrompido
+func (ds *DedupStorage) createRef(name string, ref dedupRef) (*os.File, string, os.FileInfo, error) {
+	root := ds.ns.root
+
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("erro ao serializar referência de %s: %w", name, err)
+	}
+
+	tmp, tmpPath, err := createTemp(root, tempFilePrefix)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+
+	err = lockFile(tmp, true)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	var stat os.FileInfo
+	if err == nil {
+		stat, err = tmp.Stat()
+	}
+	if err != nil {
+		tmp.Close()
+		root.Remove(tmpPath)
+		return nil, "", nil, fmt.Errorf("erro ao escrever referência de %s: %w", name, err)
+	}
+
+	return tmp, tmpPath, stat, nil
+}
+
+// DownloadFile abre para leitura o trecho do conteúdo de um arquivo
+// selecionado por opts. O blob inteiro é conferido com o SHA-256 que o nomeia
+func (ds *DedupStorage) DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	// Com o lock da referência, o blob não pode ser removido antes de ser
+	// aberto; depois de aberto, a remoção não afeta a leitura
+	unlock, err := ds.ns.lockPaths(false, name)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	defer unlock()
+
+	filePath, err := ds.ns.resolve(name)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	if stat, err := ds.ns.root.Lstat(filePath); err == nil && stat.IsDir() {
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+
+	info, err := ds.refInfo(name)
+	if os.IsNotExist(err) {
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	offset, length, err := opts.Range(info.Size)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	blob, err := ds.ns.root.Open(ds.blobPath(info.SHA256))
+	if err != nil {
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir conteúdo de %s: %w", name, err)
+	}
+
+	if opts.Partial() {
+		if _, err := blob.Seek(offset, io.SeekStart); err != nil {
+			blob.Close()
+			return nil, FileInfo{}, fmt.Errorf("erro ao posicionar conteúdo de %s: %w", name, err)
+		}
+		return readCloser{io.LimitReader(blob, length), blob}, info, nil
+	}
+
+	return readCloser{verifyStored(blob, name, info.Size, info.SHA256), blob}, info, nil
+}
+
+// DeleteFile remove um arquivo ou um diretório vazio
+// O blob do arquivo é removido se esta era sua última referência
+func (ds *DedupStorage) DeleteFile(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	root := ds.ns.root
+
+	unlockRefs, err := ds.lockRefs(false)
+	if err != nil {
+		return err
+	}
+	defer unlockRefs()
+
+	unlock, err := ds.ns.lockPaths(true, name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := ds.ns.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	stat, err := root.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao consultar arquivo %s: %w", filePath, err)
+	}
+
+	var ref dedupRef
+	if stat.IsDir() {
+		empty, err := isEmptyDir(root, filePath)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, name)
+		}
+	} else if ref, err = ds.readRef(filePath); err != nil {
+		return fmt.Errorf("erro ao ler referência de %s: %w", name, err)
+	}
+
+	if err := root.Remove(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return fmt.Errorf("erro ao remover arquivo %s: %w", filePath, err)
+	}
+	if err := syncDir(root, filepath.Dir(filePath)); err != nil {
+		return err
+	}
+
+	if ref.SHA256 != "" {
+		return ds.releaseBlob(ref.SHA256)
+	}
+	return nil
+}
+
+// RenameFile renomeia ou move um arquivo ou diretório
+// Só as referências são movidas; os blobs e seus contadores não mudam
+func (ds *DedupStorage) RenameFile(oldName, newName string) error {
+	unlock, err := ds.lockRefs(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return ds.ns.RenameFile(oldName, newName)
+}
+
+// StatFile retorna as informações de um arquivo ou diretório
+func (ds *DedupStorage) StatFile(name string) (FileInfo, error) {
+	return ds.ns.StatFile(name)
+}
+
+// CreateDirectory cria um diretório e os diretórios intermediários que ainda
+// não existirem
+func (ds *DedupStorage) CreateDirectory(name string) error {
+	return ds.ns.CreateDirectory(name)
+}
+
+// refInfo monta as informações do arquivo name a partir da sua referência
+// É usada pelo LocalStorage das referências no lugar da leitura do conteúdo
+func (ds *DedupStorage) refInfo(name string) (FileInfo, error) {
+	file, err := ds.ns.root.Open(ds.ns.path(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+
+	ref, err := decodeRef(file)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao ler referência de %s: %w", name, err)
+	}
+
+	return ref.fileInfo(name, stat.ModTime()), nil
+}
+
+// readRef lê a referência gravada em filePath
+// Retorna o erro de os.Root sem alteração, para que os.IsNotExist funcione
+func (ds *DedupStorage) readRef(filePath string) (dedupRef, error) {
+	file, err := ds.ns.root.Open(filePath)
+	if err != nil {
+		return dedupRef{}, err
+	}
+	defer file.Close()
+
+	return decodeRef(file)
+}
+
+// readRefData lê o conteúdo do arquivo de referência filePath, sem
+// decodificá-lo
+func (ds *DedupStorage) readRefData(filePath string) ([]byte, error) {
+	file, err := ds.ns.root.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// releaseBlob decrementa o contador de referências do blob sum e o remove
+// quando o contador chega a zero
+func (ds *DedupStorage) releaseBlob(sum string) error {
+	unlock, err := ds.lockBlob(sum)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return ds.addRefs(sum, -1)
+}
+
+// addRefs soma delta ao contador de referências do blob sum, removendo o
+// blob e o contador quando não restarem referências
+// NOTA: Esta função assume que o chamador obteve o lock do blob com lockBlob
+func (ds *DedupStorage) addRefs(sum string, delta int64) error {
+	count, err := ds.refCount(ds.blobPath(sum) + refsSuffix)
+	if err != nil {
+		return err
+	}
+	return ds.setRefs(sum, count+delta)
+}
+
+// setRefs grava count como o contador de referências do blob sum, removendo
+// o blob e o contador se count não for positivo
+// NOTA: Esta função assume que o chamador obteve o lock do blob com lockBlob
+// ou o lock exclusivo das referências com lockRefs
+func (ds *DedupStorage) setRefs(sum string, count int64) error {
+	root := ds.ns.root
+	blobPath := ds.blobPath(sum)
+	refsPath := blobPath + refsSuffix
+
+	if count <= 0 {
+		if err := root.Remove(blobPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("erro ao remover blob %s: %w", sum, err)
+		}
+		if err := root.Remove(refsPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("erro ao remover contador do blob %s: %w", sum, err)
+		}
+		return nil
+	}
+
+	// O contador é substituído por rename para nunca ficar parcialmente gravado
+	tmp, tmpPath, err := createTemp(root, tempFilePrefix)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer tmp.Close()
+
+	err = lockFile(tmp, true)
+	if err == nil {
+		_, err = tmp.WriteString(strconv.FormatInt(count, 10))
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = renameInRoot(root, tmpPath, refsPath)
+	}
+	if err != nil {
+		root.Remove(tmpPath)
+		return fmt.Errorf("erro ao gravar contador do blob %s: %w", sum, err)
+	}
+
+	return syncDir(root, filepath.Dir(refsPath))
+}
+
+// refCount lê o contador de referências gravado em refsPath; um contador
+// inexistente vale zero
+func (ds *DedupStorage) refCount(refsPath string) (int64, error) {
+	file, err := ds.ns.root.Open(refsPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao abrir contador %s: %w", refsPath, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler contador %s: %w", refsPath, err)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("contador %s inválido: %w", refsPath, err)
+	}
+	return count, nil
+}
+
+// recount recalcula os contadores de referências a partir da árvore de
+// referências, corrigindo os que uma queda deixou errados, e remove os blobs
+// sem referências. Com o lock exclusivo das referências nenhuma operação que
+// as altera está em andamento, neste ou em outro processo. Um arquivo que não
+// pode ser decodificado como referência não aponta para nenhum blob
+func (ds *DedupStorage) recount() error {
+	root := ds.ns.root
+
+	unlock, err := ds.lockRefs(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	names, err := ds.ns.listDir("", true, nil)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int64)
+	for _, name := range names {
+		filePath := ds.ns.path(name)
+		stat, err := root.Lstat(filePath)
+		if err != nil {
+			return fmt.Errorf("erro ao consultar referência de %s: %w", name, err)
+		}
+		if !stat.Mode().IsRegular() {
+			continue
+		}
+
+		data, err := ds.readRefData(filePath)
+		if err != nil {
+			return fmt.Errorf("erro ao ler referência de %s: %w", name, err)
+		}
+		if ref, err := decodeRef(bytes.NewReader(data)); err == nil {
+			counts[ref.SHA256]++
+		}
+	}
+
+	dirs, err := readDir(root, BlobsDirName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler diretório de blobs: %w", err)
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := readDir(root, filepath.Join(BlobsDirName, dir.Name()))
+		if err != nil {
+			return fmt.Errorf("erro ao ler diretório de blobs %s: %w", dir.Name(), err)
+		}
+
+		// Cada blob aparece pelo conteúdo e pelo contador, ou só por um deles
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			sum := strings.TrimSuffix(entry.Name(), refsSuffix)
+			if seen[sum] {
+				continue
+			}
+			seen[sum] = true
+			if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size || sum[:2] != dir.Name() {
+				continue
+			}
+
+			// Um contador ilegível é regravado, e um blob sem contador, de um
+			// upload interrompido antes do incremento, é removido
+			stored, err := ds.refCount(ds.blobPath(sum) + refsSuffix)
+			if err != nil {
+				stored = -1
+			}
+			if stored == counts[sum] && stored > 0 {
+				continue
+			}
+			if err := ds.setRefs(sum, counts[sum]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lockRefs trava as referências e os contadores de todos os blobs, dentro
+// do processo e entre processos: as operações que os alteram usam o lock
+// compartilhado e recount o exclusivo. A chave é a do diretório de blobs,
+// que não coincide com o lock de um arquivo nem com o de um blob
+func (ds *DedupStorage) lockRefs(exclusive bool) (func(), error) {
+	return ds.ns.lock(BlobsDirName, exclusive)
+}
+
+// lockBlob trava o blob sum exclusivamente, dentro do processo e entre
+// processos. O prefixo da chave não é um nome válido de arquivo, então o lock
+// nunca coincide com o de um arquivo
+func (ds *DedupStorage) lockBlob(sum string) (func(), error) {
+	return ds.ns.lock(BlobsDirName+"/"+sum, true)
+}
+
+// blobPath retorna o caminho, relativo ao diretório base, do blob sum
+// Os blobs são distribuídos em subdiretórios pelos dois primeiros caracteres
+// do hash para que nenhum diretório fique grande demais
+func (ds *DedupStorage) blobPath(sum string) string {
+	return filepath.Join(BlobsDirName, sum[:2], sum)
+}
+
+// fileInfo monta as informações do arquivo name que tem a referência ref
+func (ref dedupRef) fileInfo(name string, modTime time.Time) FileInfo {
+	return FileInfo{
+		Name:        name,
+		Size:        ref.Size,
+		ModTime:     modTime,
+		SHA256:      ref.SHA256,
+		ContentType: ref.ContentType,
+		ETag:        ref.SHA256,
+		ExpiresAt:   ref.ExpiresAt,
+		Tags:        ref.Tags,
+	}
+}
+
+// decodeRef lê uma referência, recusando hashes fora do formato esperado,
+// que poderiam apontar para outros arquivos do diretório de blobs
+func decodeRef(r io.Reader) (dedupRef, error) {
+	var ref dedupRef
+	if err := json.NewDecoder(r).Decode(&ref); err != nil {
+		return dedupRef{}, err
+	}
+
+	if sum, err := hex.DecodeString(ref.SHA256); err != nil || len(sum) != sha256.Size {
+		return dedupRef{}, fmt.Errorf("hash inválido na referência: %q", ref.SHA256)
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,186 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// blobRefs retorna, para cada blob em BlobsDirName de dir, o seu contador de
+// referências; um blob sem contador vale zero e um contador sem blob, -1
+func blobRefs(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	refs := make(map[string]string)
+	err := filepath.WalkDir(filepath.Join(dir, BlobsDirName), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, isRefs := strings.CutSuffix(d.Name(), refsSuffix)
+		if !isRefs {
+			if _, ok := refs[sum]; !ok {
+				refs[sum] = "0"
+			}
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(strings.TrimSuffix(path, refsSuffix)); os.IsNotExist(err) {
+			refs[sum] = "-1"
+		} else {
+			refs[sum] = string(data)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("erro ao percorrer blobs: %v", err)
+	}
+	return refs
+}
+
+// sumOf retorna o SHA-256 em hexadecimal de content
+func sumOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkBlobRefs falha se os contadores dos blobs de dir não forem want
+func checkBlobRefs(t *testing.T, step, dir string, want map[string]string) {
+	t.Helper()
+
+	if got := blobRefs(t, dir); !maps.Equal(got, want) {
+		t.Fatalf("%s: blobs %v, esperado %v", step, got, want)
+	}
+}
+
+// newTestDedupStorage cria um DedupStorage em dir
+func newTestDedupStorage(t *testing.T, dir string) *DedupStorage {
+	t.Helper()
+
+	ds, err := NewDedupStorage(dir)
+	if err != nil {
+		t.Fatalf("NewDedupStorage: %v", err)
+	}
+	return ds
+}
+
+// uploadString grava content em name
+func uploadString(t *testing.T, storage FileService, name, content string) {
+	t.Helper()
+
+	if _, err := storage.UploadFile(name, strings.NewReader(content), UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile(%s): %v", name, err)
+	}
+}
+
+// checkDownload falha se o conteúdo de name não for want
+func checkDownload(t *testing.T, storage FileService, name, want string) {
+	t.Helper()
+
+	got, err := downloadBytes(storage, name, DownloadOptions{})
+	if err != nil || string(got) != want {
+		t.Errorf("DownloadFile(%s): %q, %v; esperado %q", name, got, err, want)
+	}
+}
+
+// TestDedupSharedBlob grava o mesmo conteúdo em dois nomes e confere que
+// eles compartilham um único blob
+func TestDedupSharedBlob(t *testing.T) {
+	dir := t.TempDir()
+	ds := newTestDedupStorage(t, dir)
+
+	uploadString(t, ds, "a.txt", "igual")
+	uploadString(t, ds, "docs/b.txt", "igual")
+
+	checkBlobRefs(t, "dois uploads iguais", dir, map[string]string{sumOf("igual"): "2"})
+	checkDownload(t, ds, "a.txt", "igual")
+	checkDownload(t, ds, "docs/b.txt", "igual")
+}
+
+// TestDedupReleaseBlob confere que sobrescritas e remoções decrementam os
+// contadores e removem o blob quando não restam referências, e que renames
+// não os alteram
+func TestDedupReleaseBlob(t *testing.T) {
+	dir := t.TempDir()
+	ds := newTestDedupStorage(t, dir)
+	same, other := sumOf("igual"), sumOf("outro")
+
+	uploadString(t, ds, "a.txt", "igual")
+	uploadString(t, ds, "b.txt", "igual")
+
+	uploadString(t, ds, "a.txt", "outro")
+	checkBlobRefs(t, "sobrescrita", dir, map[string]string{same: "1", other: "1"})
+
+	uploadString(t, ds, "a.txt", "outro")
+	checkBlobRefs(t, "sobrescrita com o mesmo conteúdo", dir, map[string]string{same: "1", other: "1"})
+
+	if err := ds.RenameFile("b.txt", "docs/b.txt"); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	checkBlobRefs(t, "rename", dir, map[string]string{same: "1", other: "1"})
+
+	if err := ds.DeleteFile("docs/b.txt"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	checkBlobRefs(t, "delete da última referência", dir, map[string]string{other: "1"})
+
+	uploadString(t, ds, "c.txt", "outro")
+	if err := ds.DeleteFile("a.txt"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	checkBlobRefs(t, "delete com referência restante", dir, map[string]string{other: "1"})
+	checkDownload(t, ds, "c.txt", "outro")
+
+	if err := ds.DeleteFile("c.txt"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	checkBlobRefs(t, "sem arquivos", dir, map[string]string{})
+	checkNoDebris(t, dir)
+}
+
+// TestDedupRecount corrompe e desfaz os contadores, como uma queda no meio
+// de uma operação, e confere que reabrir o armazenamento os reconta e
+// remove os blobs sem referências
+func TestDedupRecount(t *testing.T) {
+	dir := t.TempDir()
+	ds := newTestDedupStorage(t, dir)
+	same, single := sumOf("igual"), sumOf("único")
+
+	uploadString(t, ds, "a.txt", "igual")
+	uploadString(t, ds, "docs/b.txt", "igual")
+	uploadString(t, ds, "c.txt", "único")
+
+	blobPath := func(sum string) string { return filepath.Join(dir, ds.blobPath(sum)) }
+	writeFile := func(path, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	// Contador ilegível, contador errado e blobs sem referências, com e sem
+	// contador, como os de uploads interrompidos
+	orphan, unreferenced := sumOf("órfão"), sumOf("sem referência")
+	writeFile(blobPath(same)+refsSuffix, "lixo")
+	writeFile(blobPath(single)+refsSuffix, "7")
+	writeFile(blobPath(orphan), "órfão")
+	writeFile(blobPath(unreferenced), "sem referência")
+	writeFile(blobPath(unreferenced)+refsSuffix, "3")
+
+	reopened := newTestDedupStorage(t, dir)
+	checkBlobRefs(t, "recontado", dir, map[string]string{same: "2", single: "1"})
+	checkDownload(t, reopened, "a.txt", "igual")
+	checkDownload(t, reopened, "docs/b.txt", "igual")
+	checkDownload(t, reopened, "c.txt", "único")
+	checkNoDebris(t, dir)
+}
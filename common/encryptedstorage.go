@@ -0,0 +1,940 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeysDirName é o nome do diretório, dentro do diretório de dados, onde o
+// EncryptedStorage guarda as chaves de dados dos arquivos
+const KeysDirName = ".keys"
+
+const (
+	// keyCollectGrace é o tempo durante o qual uma chave recém-gravada é
+	// mantida por CollectKeys mesmo sem arquivo que a use, pois a chave é
+	// gravada antes do conteúdo e pode ser de um upload em andamento
+	keyCollectGrace = 24 * time.Hour
+
+	// keyCollectPageSize é o número de arquivos listados por vez por CollectKeys
+	keyCollectPageSize = 1000
+)
+
+// EncryptionOptions configura um EncryptedStorage
+type EncryptionOptions struct {
+	// Keys são as chaves mestras que cifram as chaves de dados
+	Keys *KeyRing
+
+	// KeysDir é o diretório onde ficam as chaves de dados cifradas
+	KeysDir string
+}
+
+// EncryptedStorage implementa FileService cifrando o conteúdo dos arquivos
+// antes de gravá-lo em outro FileService
+// Cada arquivo é cifrado com AES-256-GCM por uma chave de dados própria,
+// gerada no upload. As chaves de dados são guardadas fora do conteúdo, em
+// KeysDir, cifradas por uma chave mestra, e são encontradas pelo ID gravado no
+// cabeçalho do arquivo cifrado; assim uma rotação da chave mestra recifra só
+// as chaves de dados, sem reescrever os arquivos, e um rename não precisa
+// alterar as chaves. Downloads de arquivos alterados, truncados ou que não
+// estão cifrados falham com ErrDecryptionFailed
+//
+// O tamanho, o SHA-256 e o tipo do conteúdo original são guardados com a
+// chave, cifrados pela chave de dados. O ETag é o do arquivo cifrado, então
+// uploads condicionais são avaliados pelo armazenamento interno. As chaves
+// de arquivos removidos ou substituídos não são apagadas na operação, pois
+// versões anteriores, entradas da lixeira ou arquivos movidos durante ela
+// podem usá-las; CollectKeys apaga as que nada mais usa
+type EncryptedStorage struct {
+	inner FileService
+	keys  *KeyRing
+	store *keyStore
+
+	// infoCache guarda as informações do conteúdo original pelo ETag do
+	// arquivo cifrado, que muda a cada upload, evitando ler o cabeçalho do
+	// arquivo a cada listagem
+	infoCache sync.Map // ETag -> encryptedInfo
+}
+
+// encryptedInfo são as informações do conteúdo original de um arquivo cifrado
+type encryptedInfo struct {
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+}
+
+// versionedEncryptedStorage é o EncryptedStorage de um armazenamento que
+// mantém versões anteriores; as versões são decifradas como os arquivos
+type versionedEncryptedStorage struct {
+	*EncryptedStorage
+	versions Versioner
+}
+
+// trashEncryptedStorage é o versionedEncryptedStorage de um armazenamento
+// que também mantém uma lixeira; as entradas são decifradas como os arquivos
+type trashEncryptedStorage struct {
+	*versionedEncryptedStorage
+	trash Trasher
+}
+
+// NewEncryptedStorage cria o FileService que cifra os arquivos gravados em
+// inner. Se inner implementar Versioner ou Trasher, o FileService retornado
+// também implementa, decifrando as versões anteriores e a lixeira
+func NewEncryptedStorage(inner FileService, opts EncryptionOptions) (FileService, error) {
+	if opts.Keys == nil {
+		return nil, fmt.Errorf("nenhuma chave mestra configurada")
+	}
+
+	store, err := openKeyStore(opts.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+
+	es := &EncryptedStorage{
+		inner: inner,
+		keys:  opts.Keys,
+		store: store,
+	}
+
+	if versions, ok := inner.(Versioner); ok {
+		vs := &versionedEncryptedStorage{EncryptedStorage: es, versions: versions}
+		if trash, ok := inner.(Trasher); ok {
+			return &trashEncryptedStorage{versionedEncryptedStorage: vs, trash: trash}, nil
+		}
+		return vs, nil
+	}
+	return es, nil
+}
+
+// ListFiles retorna as informações dos arquivos e diretórios de opts.Dir
+// Arquivos que não podem ser decifrados aparecem com as informações do
+// armazenamento interno. A ordem e as páginas são as do armazenamento
+// interno: o tamanho cifrado cresce com o tamanho original, então a ordem
+// por tamanho é a mesma
+func (es *EncryptedStorage) ListFiles(opts ListOptions) ([]FileInfo, string, error) {
+	files, next, err := es.inner.ListFiles(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i, file := range files {
+		if files[i], err = es.fileInfo(file); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return files, next, nil
+}
+
+// UploadFile cifra o conteúdo lido de r e o grava no armazenamento interno
+// A chave de dados é guardada antes do conteúdo, então nenhum arquivo gravado
+// fica sem sua chave; se o upload falhar, a chave é descartada
+func (es *EncryptedStorage) UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error) {
+	fileID := make([]byte, encryptionIDLen)
+	dataKey := make([]byte, encryptionKeyLen)
+	if _, err := rand.Read(fileID); err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao gerar ID do arquivo: %w", err)
+	}
+	if _, err := rand.Read(dataKey); err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao gerar chave de dados: %w", err)
+	}
+
+	keyID, wrapped, err := es.keys.wrap(dataKey, fileID)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if err := es.store.putKey(fileID, keyRecord{KeyID: keyID, WrappedKey: wrapped}); err != nil {
+		return FileInfo{}, err
+	}
+
+	// O conteúdo original é medido enquanto é cifrado
+	hash := sha256.New()
+	head := &prefixWriter{limit: sniffLen}
+	counter := &countingWriter{}
+	enc, err := newEncryptReader(io.TeeReader(r, io.MultiWriter(hash, head, counter)), dataKey, fileID)
+	if err != nil {
+		es.store.remove(fileID)
+		return FileInfo{}, err
+	}
+
+	// Um erro com as informações do arquivo preenchidas ocorreu depois que o
+	// arquivo foi gravado; nesse caso a chave continua sendo necessária
+	stored, err := es.inner.UploadFile(name, enc, opts)
+	if err != nil && stored.Name == "" {
+		es.store.remove(fileID)
+		return FileInfo{}, err
+	}
+	uploadErr := err
+
+	info := encryptedInfo{
+		Size:        counter.n,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: DetectContentType(name, head.buf),
+	}
+	if stored.ETag != "" {
+		es.infoCache.Store(stored.ETag, info)
+	}
+
+	// Sem as informações guardadas, elas são recalculadas decifrando o arquivo
+	if err := es.store.putInfo(fileID, dataKey, info); err != nil && uploadErr == nil {
+		uploadErr = err
+	}
+
+	return info.fileInfo(stored), uploadErr
+}
+
+// DownloadFile abre para leitura o trecho de um arquivo selecionado por
+// opts, decifrando seu conteúdo. A leitura falha com ErrDecryptionFailed ao
+// encontrar um segmento alterado e o conteúdo inteiro é conferido com o
+// SHA-256 guardado com a chave. Os segmentos anteriores a um trecho são
+// lidos do armazenamento interno, mas não são decifrados
+func (es *EncryptedStorage) DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	file, stored, err := es.inner.DownloadFile(name, DownloadOptions{})
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	content, fileID, dataKey, err := es.decrypt(file)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir arquivo %s: %w", name, err)
+	}
+
+	info, err := es.openedInfo(stored, fileID, dataKey)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, err
+	}
+
+	if !opts.Partial() {
+		return readCloser{verifyStored(content, name, info.Size, info.SHA256), file}, info, nil
+	}
+
+	offset, length, err := opts.Range(info.Size)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, err
+	}
+	if length == 0 {
+		file.Close()
+		return io.NopCloser(strings.NewReader("")), info, nil
+	}
+
+	segment := uint64(offset / encryptionSegmentSize)
+	err = content.skip(segment)
+	if err == nil {
+		_, err = io.CopyN(io.Discard, content, offset-int64(segment)*encryptionSegmentSize)
+	}
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao posicionar arquivo %s: %w", name, err)
+	}
+
+	return readCloser{io.LimitReader(content, length), file}, info, nil
+}
+
+// DeleteFile remove um arquivo ou um diretório vazio
+func (es *EncryptedStorage) DeleteFile(name string) error {
+	return es.inner.DeleteFile(name)
+}
+
+// RenameFile renomeia ou move um arquivo ou diretório
+// As chaves são encontradas pelo ID gravado no arquivo, então não mudam
+func (es *EncryptedStorage) RenameFile(oldName, newName string) error {
+	return es.inner.RenameFile(oldName, newName)
+}
+
+// StatFile retorna as informações de um arquivo ou diretório
+func (es *EncryptedStorage) StatFile(name string) (FileInfo, error) {
+	file, err := es.inner.StatFile(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return es.fileInfo(file)
+}
+
+// CreateDirectory cria um diretório e os diretórios intermediários que ainda
+// não existirem
+func (es *EncryptedStorage) CreateDirectory(name string) error {
+	return es.inner.CreateDirectory(name)
+}
+
+// CollectKeys apaga as chaves de dados, e as informações guardadas com elas,
+// que nenhum arquivo, versão anterior ou entrada da lixeira do armazenamento
+// interno usa mais, e retorna quantas foram apagadas. Os arquivos são
+// percorridos duas vezes, para que um arquivo movido durante a primeira
+// passagem, entre diretórios ou para a lixeira e de volta, seja visto na
+// segunda. As chaves gravadas há menos de keyCollectGrace são mantidas
+// Todos os processos que usam KeysDir devem compartilhar o armazenamento
+// interno: as chaves de arquivos que só outro processo vê seriam apagadas
+func (es *EncryptedStorage) CollectKeys() (int, error) {
+	start := time.Now()
+
+	used := make(map[string]bool)
+	for range 2 {
+		if err := es.markKeys(used); err != nil {
+			return 0, fmt.Errorf("erro ao procurar chaves em uso: %w", err)
+		}
+	}
+
+	collected := 0
+	err := es.store.walk(func(fileID []byte) error {
+		if used[string(fileID)] {
+			return nil
+		}
+
+		stat, err := es.store.root.Stat(es.store.path(fileID, ".key"))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao consultar chave do arquivo %x: %w", fileID, err)
+		}
+		if start.Sub(stat.ModTime()) < keyCollectGrace {
+			return nil
+		}
+
+		es.store.remove(fileID)
+		collected++
+		return nil
+	})
+
+	return collected, err
+}
+
+// markKeys marca em used os IDs dos arquivos cifrados do armazenamento
+// interno: os arquivos atuais, suas versões anteriores e a lixeira
+func (es *EncryptedStorage) markKeys(used map[string]bool) error {
+	versions, _ := es.inner.(Versioner)
+	opts := ListOptions{Recursive: true, PageSize: keyCollectPageSize}
+	for {
+		files, next, err := es.inner.ListFiles(opts)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if file.IsDir {
+				continue
+			}
+
+			err := markKey(used, func() (io.ReadCloser, error) {
+				rc, _, err := es.inner.DownloadFile(file.Name, DownloadOptions{Length: int64(encryptionHeaderLen)})
+				return rc, err
+			})
+			if err != nil {
+				return err
+			}
+			if versions == nil {
+				continue
+			}
+
+			list, err := versions.ListVersions(file.Name)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			for _, version := range list {
+				err := markKey(used, func() (io.ReadCloser, error) {
+					rc, _, err := versions.DownloadVersion(file.Name, version.ID)
+					return rc, err
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+
+	trash, ok := es.inner.(Trasher)
+	if !ok {
+		return nil
+	}
+	entries, err := trash.ListTrash()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := markKey(used, func() (io.ReadCloser, error) {
+			return trash.DownloadTrash(entry.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markKey marca em used o ID do arquivo cifrado aberto por open. Arquivos
+// removidos durante a busca e arquivos que não estão cifrados são ignorados
+func markKey(used map[string]bool, open func() (io.ReadCloser, error)) error {
+	file, err := open()
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileID, err := readEncryptionHeader(file)
+	if errors.Is(err, ErrDecryptionFailed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	used[string(fileID)] = true
+	return nil
+}
+
+// fileInfo substitui as informações do arquivo cifrado stored pelas do
+// conteúdo original. Se o arquivo não puder ser decifrado, stored é mantido
+func (es *EncryptedStorage) fileInfo(stored FileInfo) (FileInfo, error) {
+	if stored.IsDir {
+		return stored, nil
+	}
+
+	if stored.ETag != "" {
+		if cached, ok := es.infoCache.Load(stored.ETag); ok {
+			return cached.(encryptedInfo).fileInfo(stored), nil
+		}
+	}
+
+	file, _, err := es.inner.DownloadFile(stored.Name, DownloadOptions{})
+	if errors.Is(err, ErrNotFound) {
+		// Arquivo removido depois da listagem; a listagem ainda o mostra
+		return stored, nil
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer file.Close()
+
+	info, err := es.contentInfo(stored.Name, file)
+	if errors.Is(err, ErrDecryptionFailed) {
+		return stored, nil
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	if stored.ETag != "" {
+		es.infoCache.Store(stored.ETag, info)
+	}
+	return info.fileInfo(stored), nil
+}
+
+// openedInfo retorna as informações do arquivo cifrado stored, já aberto
+// para download, com as do conteúdo original guardadas com a chave de dados
+// dataKey do arquivo fileID
+func (es *EncryptedStorage) openedInfo(stored FileInfo, fileID, dataKey []byte) (FileInfo, error) {
+	if stored.ETag != "" {
+		if cached, ok := es.infoCache.Load(stored.ETag); ok {
+			return cached.(encryptedInfo).fileInfo(stored), nil
+		}
+	}
+
+	info, err := es.store.getInfo(fileID, dataKey)
+	if os.IsNotExist(err) {
+		// Informações perdidas por uma queda durante o upload são
+		// recalculadas como em uma listagem
+		return es.fileInfo(stored)
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	if stored.ETag != "" {
+		es.infoCache.Store(stored.ETag, info)
+	}
+	return info.fileInfo(stored), nil
+}
+
+// contentInfo lê as informações do conteúdo original do arquivo cifrado
+// file, guardadas com sua chave. Se não tiverem sido guardadas, por uma queda
+// durante o upload, o arquivo é decifrado inteiro para recalculá-las
+func (es *EncryptedStorage) contentInfo(name string, file io.Reader) (encryptedInfo, error) {
+	content, fileID, dataKey, err := es.decrypt(file)
+	if err != nil {
+		return encryptedInfo{}, err
+	}
+
+	info, err := es.store.getInfo(fileID, dataKey)
+	if err == nil || !os.IsNotExist(err) {
+		return info, err
+	}
+
+	head := &prefixWriter{limit: sniffLen}
+	sum, n, err := Checksum(io.TeeReader(content, head))
+	if err != nil {
+		return encryptedInfo{}, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+	}
+
+	info = encryptedInfo{Size: n, SHA256: sum, ContentType: DetectContentType(name, head.buf)}
+	return info, es.store.putInfo(fileID, dataKey, info)
+}
+
+// decrypt lê o cabeçalho do arquivo cifrado lido de file e retorna o leitor
+// do conteúdo decifrado, o ID do arquivo e sua chave de dados
+func (es *EncryptedStorage) decrypt(file io.Reader) (*decryptReader, []byte, []byte, error) {
+	fileID, err := readEncryptionHeader(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dataKey, err := es.dataKey(fileID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	content, err := newDecryptReader(file, dataKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return content, fileID, dataKey, nil
+}
+
+// dataKey lê e decifra a chave de dados do arquivo fileID
+func (es *EncryptedStorage) dataKey(fileID []byte) ([]byte, error) {
+	record, err := es.store.getKey(fileID)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: chave do arquivo %x não encontrada", ErrDecryptionFailed, fileID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return es.keys.unwrap(record.KeyID, record.WrappedKey, fileID)
+}
+
+// fileInfo monta as informações de um arquivo a partir das do arquivo
+// cifrado stored, com o tamanho, o checksum e o tipo do conteúdo original
+func (info encryptedInfo) fileInfo(stored FileInfo) FileInfo {
+	storedSize := stored.StoredSize
+	if storedSize == 0 {
+		storedSize = stored.Size
+	}
+
+	return FileInfo{
+		Name:        stored.Name,
+		Size:        info.Size,
+		ModTime:     stored.ModTime,
+		SHA256:      info.SHA256,
+		ContentType: info.ContentType,
+		ETag:        stored.ETag,
+		StoredSize:  storedSize,
+		ExpiresAt:   stored.ExpiresAt,
+		Tags:        stored.Tags,
+	}
+}
+
+// ListVersions retorna as versões anteriores de um arquivo com o tamanho e o
+// checksum do conteúdo original
+func (vs *versionedEncryptedStorage) ListVersions(name string) ([]FileVersion, error) {
+	versions, err := vs.versions.ListVersions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, version := range versions {
+		file, _, err := vs.versions.DownloadVersion(name, version.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := vs.contentInfo(name, file)
+		file.Close()
+		if errors.Is(err, ErrDecryptionFailed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions[i].Size = info.Size
+		versions[i].SHA256 = info.SHA256
+	}
+
+	return versions, nil
+}
+
+// DownloadVersion abre uma versão anterior de um arquivo, decifrando seu
+// conteúdo, e retorna as informações da versão com o tamanho e o checksum
+// do conteúdo original, com os quais o conteúdo inteiro é conferido
+func (vs *versionedEncryptedStorage) DownloadVersion(name, versionID string) (io.ReadCloser, FileVersion, error) {
+	file, version, err := vs.versions.DownloadVersion(name, versionID)
+	if err != nil {
+		return nil, FileVersion{}, err
+	}
+
+	content, fileID, dataKey, err := vs.decrypt(file)
+	if err != nil {
+		file.Close()
+		return nil, FileVersion{}, fmt.Errorf("erro ao abrir versão %s de %s: %w", versionID, name, err)
+	}
+
+	info, err := vs.store.getInfo(fileID, dataKey)
+	if os.IsNotExist(err) {
+		// Informações perdidas por uma queda são recalculadas decifrando
+		// outra cópia da versão
+		info, err = vs.versionInfo(name, versionID)
+	}
+	if err != nil {
+		file.Close()
+		return nil, FileVersion{}, err
+	}
+
+	version.Size, version.SHA256 = info.Size, info.SHA256
+	return readCloser{verifyStored(content, name, info.Size, info.SHA256), file}, version, nil
+}
+
+// versionInfo lê as informações do conteúdo original de uma versão, como
+// contentInfo
+func (vs *versionedEncryptedStorage) versionInfo(name, versionID string) (encryptedInfo, error) {
+	file, _, err := vs.versions.DownloadVersion(name, versionID)
+	if err != nil {
+		return encryptedInfo{}, err
+	}
+	defer file.Close()
+
+	return vs.contentInfo(name, file)
+}
+
+// RestoreVersion torna uma versão anterior a versão atual do arquivo
+// A versão é copiada cifrada, com o ID da sua chave
+func (vs *versionedEncryptedStorage) RestoreVersion(name, versionID string) error {
+	return vs.versions.RestoreVersion(name, versionID)
+}
+
+// ListTrash retorna as entradas da lixeira com o tamanho, o checksum e o
+// tipo do conteúdo original
+func (ts *trashEncryptedStorage) ListTrash() ([]TrashEntry, error) {
+	entries, err := ts.trash.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		file, err := ts.trash.DownloadTrash(entry.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := ts.contentInfo(entry.Name, file)
+		file.Close()
+		if errors.Is(err, ErrDecryptionFailed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Size = info.Size
+		entries[i].SHA256 = info.SHA256
+		entries[i].ContentType = info.ContentType
+	}
+
+	return entries, nil
+}
+
+// DownloadTrash abre o conteúdo de uma entrada da lixeira, decifrando-o
+func (ts *trashEncryptedStorage) DownloadTrash(id string) (io.ReadCloser, error) {
+	file, err := ts.trash.DownloadTrash(id)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, _, err := ts.decrypt(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("erro ao abrir entrada %s da lixeira: %w", id, err)
+	}
+
+	return readCloser{content, file}, nil
+}
+
+// RestoreTrash grava uma entrada da lixeira de volta no seu nome
+// O conteúdo é copiado cifrado, com o ID da sua chave
+func (ts *trashEncryptedStorage) RestoreTrash(id string) (FileInfo, error) {
+	stored, err := ts.trash.RestoreTrash(id)
+	if err != nil {
+		return stored, err
+	}
+	return ts.fileInfo(stored)
+}
+
+// PurgeTrash remove definitivamente uma entrada da lixeira ou, com id vazio,
+// todas elas. As entradas retornadas descrevem o conteúdo cifrado
+func (ts *trashEncryptedStorage) PurgeTrash(id string) ([]TrashEntry, error) {
+	return ts.trash.PurgeTrash(id)
+}
+
+// PruneTrash remove as entradas além do prazo de retenção. As entradas
+// retornadas descrevem o conteúdo cifrado
+func (ts *trashEncryptedStorage) PruneTrash() ([]TrashEntry, error) {
+	return ts.trash.PruneTrash()
+}
+
+// RotateKeys recifra com a chave mestra atual de keys as chaves de dados em
+// keysDir cifradas por outras chaves mestras e retorna quantas foram
+// recifradas. O conteúdo dos arquivos não é lido nem alterado. Depois da
+// rotação, as chaves mestras antigas podem ser removidas do chaveiro
+func RotateKeys(keysDir string, keys *KeyRing) (int, error) {
+	store, err := openKeyStore(keysDir)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	err = store.walk(func(fileID []byte) error {
+		record, err := store.getKey(fileID)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.KeyID == keys.CurrentID() {
+			return nil
+		}
+
+		dataKey, err := keys.unwrap(record.KeyID, record.WrappedKey, fileID)
+		if err != nil {
+			return fmt.Errorf("chave do arquivo %x: %w", fileID, err)
+		}
+
+		keyID, wrapped, err := keys.wrap(dataKey, fileID)
+		if err != nil {
+			return err
+		}
+		if err := store.putKey(fileID, keyRecord{KeyID: keyID, WrappedKey: wrapped}); err != nil {
+			return err
+		}
+
+		rotated++
+		return nil
+	})
+
+	return rotated, err
+}
+
+// keyRecord é a chave de dados de um arquivo, cifrada pela chave mestra KeyID
+type keyRecord struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// keyStore guarda as chaves de dados e as informações do conteúdo dos
+// arquivos cifrados, em arquivos nomeados pelo ID de cada arquivo e
+// distribuídos em subdiretórios pelos dois primeiros caracteres do ID. A
+// chave e as informações ficam em arquivos separados porque são gravadas em
+// momentos diferentes: a rotação só reescreve a chave e nunca concorre com a
+// gravação das informações. Como no LocalStorage, o acesso passa por um os.Root
+type keyStore struct {
+	root *os.Root
+}
+
+// openKeyStore abre o diretório de chaves dir, criando-o se necessário
+func openKeyStore(dir string) (*keyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de chaves %s: %w", dir, err)
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir diretório de chaves %s: %w", dir, err)
+	}
+
+	return &keyStore{root: root}, nil
+}
+
+// getKey lê a chave de dados do arquivo fileID
+// Retorna o erro de os.Root sem alteração se a chave não existir, para que
+// os.IsNotExist funcione
+func (ks *keyStore) getKey(fileID []byte) (keyRecord, error) {
+	data, err := ks.read(ks.path(fileID, ".key"))
+	if err != nil {
+		return keyRecord{}, err
+	}
+
+	var record keyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return keyRecord{}, fmt.Errorf("%w: chave do arquivo %x inválida", ErrDecryptionFailed, fileID)
+	}
+	return record, nil
+}
+
+// putKey grava a chave de dados do arquivo fileID
+func (ks *keyStore) putKey(fileID []byte, record keyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar chave: %w", err)
+	}
+	return ks.write(ks.path(fileID, ".key"), data)
+}
+
+// getInfo lê as informações do conteúdo do arquivo fileID, decifrando-as com
+// sua chave de dados. Como getKey, retorna o erro de os.Root sem alteração
+func (ks *keyStore) getInfo(fileID, dataKey []byte) (encryptedInfo, error) {
+	sealed, err := ks.read(ks.path(fileID, ".info"))
+	if err != nil {
+		return encryptedInfo{}, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return encryptedInfo{}, err
+	}
+	data, err := aead.Open(nil, segmentNonce(0, nonceMetadata), sealed, fileID)
+	if err != nil {
+		return encryptedInfo{}, fmt.Errorf("%w: informações do arquivo %x alteradas", ErrDecryptionFailed, fileID)
+	}
+
+	var info encryptedInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return encryptedInfo{}, fmt.Errorf("%w: informações do arquivo %x inválidas", ErrDecryptionFailed, fileID)
+	}
+	return info, nil
+}
+
+// putInfo grava as informações do conteúdo do arquivo fileID, cifradas com
+// sua chave de dados para não expor o checksum do conteúdo original
+func (ks *keyStore) putInfo(fileID, dataKey []byte, info encryptedInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar informações: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	return ks.write(ks.path(fileID, ".info"), aead.Seal(nil, segmentNonce(0, nonceMetadata), data, fileID))
+}
+
+// remove apaga a chave e as informações do arquivo fileID
+func (ks *keyStore) remove(fileID []byte) {
+	ks.root.Remove(ks.path(fileID, ".key"))
+	ks.root.Remove(ks.path(fileID, ".info"))
+}
+
+// walk chama fn com o ID de cada arquivo que tem uma chave guardada
+func (ks *keyStore) walk(fn func(fileID []byte) error) error {
+	dirs, err := readDir(ks.root, ".")
+	if err != nil {
+		return fmt.Errorf("erro ao ler diretório de chaves: %w", err)
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		entries, err := readDir(ks.root, dir.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao ler diretório de chaves %s: %w", dir.Name(), err)
+		}
+
+		for _, entry := range entries {
+			encoded, ok := strings.CutSuffix(entry.Name(), ".key")
+			if !ok {
+				continue
+			}
+			fileID, err := hex.DecodeString(encoded)
+			if err != nil || len(fileID) != encryptionIDLen {
+				continue
+			}
+			if err := fn(fileID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// read lê o arquivo path do diretório de chaves
+func (ks *keyStore) read(path string) ([]byte, error) {
+	f, err := ks.root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// write grava data no arquivo path do diretório de chaves
+// Os dados são gravados em um arquivo temporário e renomeados, então o
+// arquivo nunca fica parcialmente gravado
+func (ks *keyStore) write(path string, data []byte) error {
+	if err := mkdirAll(ks.root, filepath.Dir(path)); err != nil {
+		return fmt.Errorf("erro ao criar diretório de chaves: %w", err)
+	}
+
+	tmp, tmpPath, err := createTemp(ks.root, filepath.Join(filepath.Dir(path), tempFilePrefix))
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer tmp.Close()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = renameInRoot(ks.root, tmpPath, path)
+	}
+	if err != nil {
+		ks.root.Remove(tmpPath)
+		return fmt.Errorf("erro ao gravar %s: %w", path, err)
+	}
+
+	return syncDir(ks.root, filepath.Dir(path))
+}
+
+// path retorna o caminho, relativo ao diretório de chaves, do arquivo com o
+// sufixo suffix do arquivo fileID
+func (ks *keyStore) path(fileID []byte, suffix string) string {
+	encoded := hex.EncodeToString(fileID)
+	return filepath.Join(encoded[:2], encoded+suffix)
+}
+
+// countingWriter conta os bytes escritos nele
+type countingWriter struct {
+	n int64
+}
+
+// Write conta os bytes de p e sempre aceita p inteiro
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,369 @@
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testKey retorna uma entrada "id:chave" de chaveiro com uma chave aleatória
+func testKey(t *testing.T, id string) string {
+	t.Helper()
+
+	key := make([]byte, encryptionKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+// testKeyRing cria um chaveiro com as entradas entries, a primeira a atual
+func testKeyRing(t *testing.T, entries ...string) *KeyRing {
+	t.Helper()
+
+	var text string
+	for _, entry := range entries {
+		text += entry + "\n"
+	}
+	kr, err := ParseKeyRing(text)
+	if err != nil {
+		t.Fatalf("ParseKeyRing: %v", err)
+	}
+	return kr
+}
+
+// newTestEncryptedStorage cria um EncryptedStorage sobre um LocalStorage em
+// dir, com as chaves de dados em KeysDirName, como fazem os servidores
+func newTestEncryptedStorage(t *testing.T, dir string, keys *KeyRing, opts LocalStorageOptions) FileService {
+	t.Helper()
+
+	inner, err := NewLocalStorage(dir, opts)
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	storage, err := NewEncryptedStorage(inner, EncryptionOptions{Keys: keys, KeysDir: filepath.Join(dir, KeysDirName)})
+	if err != nil {
+		t.Fatalf("NewEncryptedStorage: %v", err)
+	}
+	return storage
+}
+
+// randomContent retorna n bytes aleatórios
+func randomContent(t *testing.T, n int) []byte {
+	t.Helper()
+
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return data
+}
+
+// downloadBytes baixa o trecho opts de name e retorna o conteúdo lido
+func downloadBytes(storage FileService, name string, opts DownloadOptions) ([]byte, error) {
+	rc, _, err := storage.DownloadFile(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// TestEncryptionTampered cifra um conteúdo de vários segmentos e confere que
+// um segmento alterado, removido, truncado ou fora de ordem falha com
+// ErrDecryptionFailed, e que o conteúdo intacto é decifrado
+func TestEncryptionTampered(t *testing.T) {
+	dataKey := randomContent(t, encryptionKeyLen)
+	fileID := randomContent(t, encryptionIDLen)
+	plain := randomContent(t, 3*encryptionSegmentSize+123)
+
+	enc, err := newEncryptReader(bytes.NewReader(plain), dataKey, fileID)
+	if err != nil {
+		t.Fatalf("newEncryptReader: %v", err)
+	}
+	sealed, err := io.ReadAll(enc)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	segLen := encryptionSegmentSize + 16 // Segmento cifrado com a tag do GCM
+	header, body := sealed[:encryptionHeaderLen], sealed[encryptionHeaderLen:]
+	segment := func(i int) []byte { return body[i*segLen : (i+1)*segLen] }
+	join := func(parts ...[]byte) []byte { return bytes.Join(append([][]byte{header}, parts...), nil) }
+
+	decrypt := func(data []byte) ([]byte, error) {
+		r := bytes.NewReader(data)
+		if _, err := readEncryptionHeader(r); err != nil {
+			return nil, err
+		}
+		dec, err := newDecryptReader(r, dataKey)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(dec)
+	}
+
+	got, err := decrypt(sealed)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("conteúdo intacto: %d bytes, %v", len(got), err)
+	}
+
+	flipped := bytes.Clone(sealed)
+	flipped[encryptionHeaderLen+segLen+10] ^= 1
+
+	cases := map[string][]byte{
+		"alterado":             flipped,
+		"último removido":      sealed[:encryptionHeaderLen+3*segLen],
+		"truncado no meio":     sealed[:len(sealed)-50],
+		"reordenado":           join(segment(1), segment(0), segment(2), body[3*segLen:]),
+		"segmento repetido":    join(segment(0), segment(0), segment(2), body[3*segLen:]),
+		"sem cabeçalho":        body,
+		"primeiro removido":    join(segment(1), segment(2), body[3*segLen:]),
+		"vazio após cabeçalho": header,
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decrypt(data); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("decifrar: %v, esperado ErrDecryptionFailed", err)
+			}
+		})
+	}
+}
+
+// TestEncryptedStorageTamperedFile altera no disco um arquivo cifrado e
+// confere que o download falha com ErrDecryptionFailed
+func TestEncryptedStorageTamperedFile(t *testing.T) {
+	dir := t.TempDir()
+	storage := newTestEncryptedStorage(t, dir, testKeyRing(t, testKey(t, "k1")), LocalStorageOptions{})
+
+	if _, err := storage.UploadFile("a.dat", bytes.NewReader(randomContent(t, 2*ChunkSize)), UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	path := filepath.Join(dir, "a.dat")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := downloadBytes(storage, "a.dat", DownloadOptions{}); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("DownloadFile: %v, esperado ErrDecryptionFailed", err)
+	}
+}
+
+// TestEncryptedStorageRange baixa trechos que começam, terminam ou
+// atravessam os limites dos segmentos cifrados
+func TestEncryptedStorageRange(t *testing.T) {
+	storage := newTestEncryptedStorage(t, t.TempDir(), testKeyRing(t, testKey(t, "k1")), LocalStorageOptions{})
+
+	plain := randomContent(t, 3*encryptionSegmentSize+123)
+	if _, err := storage.UploadFile("a.dat", bytes.NewReader(plain), UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	seg := int64(encryptionSegmentSize)
+	size := int64(len(plain))
+	cases := []struct {
+		offset, length int64
+	}{
+		{seg - 10, 20},       // Atravessa o limite do primeiro segmento
+		{seg, seg},           // Exatamente o segundo segmento
+		{seg - 1, 2*seg + 2}, // Atravessa dois limites
+		{2*seg + 100, 0},     // Do meio do terceiro segmento até o fim
+		{3 * seg, 0},         // O último segmento, parcial
+		{size - 1, 10},       // O último byte
+		{-(seg + 50), 0},     // A partir do fim, atravessando um limite
+		{0, seg + 1},         // O primeiro segmento e um byte do segundo
+	}
+	for _, c := range cases {
+		got, err := downloadBytes(storage, "a.dat", DownloadOptions{Offset: c.offset, Length: c.length})
+		if err != nil {
+			t.Errorf("trecho %d+%d: %v", c.offset, c.length, err)
+			continue
+		}
+
+		start := c.offset
+		if start < 0 {
+			start += size
+		}
+		end := size
+		if c.length > 0 {
+			end = min(start+c.length, size)
+		}
+		if !bytes.Equal(got, plain[start:end]) {
+			t.Errorf("trecho %d+%d: %d bytes diferentes do original (%d esperados)", c.offset, c.length, len(got), end-start)
+		}
+	}
+}
+
+// TestRotateKeys recifra as chaves de dados com uma nova chave mestra e
+// confere que os arquivos cifrados não mudam e continuam legíveis só com a
+// nova chave
+func TestRotateKeys(t *testing.T) {
+	dir := t.TempDir()
+	keysDir := filepath.Join(dir, KeysDirName)
+	oldKey, newKey := testKey(t, "old"), testKey(t, "new")
+
+	storage := newTestEncryptedStorage(t, dir, testKeyRing(t, oldKey), LocalStorageOptions{})
+	contents := map[string][]byte{
+		"a.dat":      randomContent(t, ChunkSize+7),
+		"docs/b.dat": randomContent(t, 100),
+	}
+	sealed := make(map[string][]byte)
+	for name, data := range contents {
+		if _, err := storage.UploadFile(name, bytes.NewReader(data), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile(%s): %v", name, err)
+		}
+		stored, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		sealed[name] = stored
+	}
+
+	rotated, err := RotateKeys(keysDir, testKeyRing(t, newKey, oldKey))
+	if err != nil || rotated != len(contents) {
+		t.Fatalf("RotateKeys: %d, %v; esperado %d", rotated, err, len(contents))
+	}
+	if rotated, err := RotateKeys(keysDir, testKeyRing(t, newKey, oldKey)); err != nil || rotated != 0 {
+		t.Errorf("segunda RotateKeys: %d, %v; esperado 0", rotated, err)
+	}
+
+	// O conteúdo cifrado não foi reescrito
+	for name := range contents {
+		stored, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(stored, sealed[name]) {
+			t.Errorf("%s reescrito pela rotação: %v", name, err)
+		}
+	}
+
+	// Só com a chave nova os arquivos são decifrados; só com a antiga, não
+	rotatedStorage := newTestEncryptedStorage(t, dir, testKeyRing(t, newKey), LocalStorageOptions{})
+	oldStorage := newTestEncryptedStorage(t, dir, testKeyRing(t, oldKey), LocalStorageOptions{})
+	for name, data := range contents {
+		got, err := downloadBytes(rotatedStorage, name, DownloadOptions{})
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("DownloadFile(%s) com a chave nova: %d bytes, %v", name, len(got), err)
+		}
+		if _, err := downloadBytes(oldStorage, name, DownloadOptions{}); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("DownloadFile(%s) com a chave antiga: %v, esperado ErrDecryptionFailed", name, err)
+		}
+	}
+}
+
+// TestCollectKeys confere que CollectKeys apaga só as chaves que nenhum
+// arquivo, versão anterior ou entrada da lixeira usa, e mantém as recentes
+func TestCollectKeys(t *testing.T) {
+	dir := t.TempDir()
+	keysDir := filepath.Join(dir, KeysDirName)
+	storage := newTestEncryptedStorage(t, dir, testKeyRing(t, testKey(t, "k1")), LocalStorageOptions{
+		Versions:       VersionPolicy{MaxVersions: 5},
+		TrashRetention: time.Hour,
+	})
+	es := storage.(*trashEncryptedStorage)
+
+	upload := func(name string, data []byte) {
+		t.Helper()
+		if _, err := storage.UploadFile(name, bytes.NewReader(data), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile(%s): %v", name, err)
+		}
+	}
+	countKeys := func() int {
+		t.Helper()
+		n := 0
+		if err := es.store.walk(func([]byte) error { n++; return nil }); err != nil {
+			t.Fatalf("walk: %v", err)
+		}
+		return n
+	}
+
+	// Arquivo atual e sua versão anterior
+	upload("a.dat", []byte("a1"))
+	upload("a.dat", []byte("a2"))
+	// Arquivo na lixeira
+	upload("b.dat", []byte("b1"))
+	if err := storage.DeleteFile("b.dat"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	// Arquivos removidos definitivamente: as chaves não são mais usadas
+	purge := func(name string) {
+		t.Helper()
+		upload(name, []byte(name))
+		if err := storage.DeleteFile(name); err != nil {
+			t.Fatalf("DeleteFile(%s): %v", name, err)
+		}
+		entries, err := es.ListTrash()
+		if err != nil {
+			t.Fatalf("ListTrash: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Name == name {
+				if _, err := es.PurgeTrash(entry.ID); err != nil {
+					t.Fatalf("PurgeTrash: %v", err)
+				}
+			}
+		}
+	}
+	purge("c.dat")
+
+	// As chaves gravadas até aqui ficam mais antigas que o prazo de carência
+	old := time.Now().Add(-2 * keyCollectGrace)
+	err := es.store.walk(func(fileID []byte) error {
+		return os.Chtimes(filepath.Join(keysDir, es.store.path(fileID, ".key")), old, old)
+	})
+	if err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	// Uma chave recente sem arquivo, como a de um upload em andamento
+	purge("d.dat")
+
+	if n := countKeys(); n != 5 {
+		t.Fatalf("%d chaves antes da coleta, esperado 5", n)
+	}
+	if collected, err := es.CollectKeys(); err != nil || collected != 1 {
+		t.Fatalf("CollectKeys: %d, %v; esperado 1", collected, err)
+	}
+	if n := countKeys(); n != 4 {
+		t.Errorf("%d chaves depois da coleta, esperado 4", n)
+	}
+
+	// Tudo o que ainda existe continua legível
+	if got, err := downloadBytes(storage, "a.dat", DownloadOptions{}); err != nil || string(got) != "a2" {
+		t.Errorf("DownloadFile(a.dat): %q, %v", got, err)
+	}
+	versions, err := es.ListVersions("a.dat")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("ListVersions: %v, %v", versions, err)
+	}
+	rc, _, err := es.DownloadVersion("a.dat", versions[0].ID)
+	if got, err := readAllClose(rc, err); err != nil || string(got) != "a1" {
+		t.Errorf("DownloadVersion: %q, %v", got, err)
+	}
+	entries, err := es.ListTrash()
+	if err != nil || len(entries) != 1 || entries[0].Name != "b.dat" {
+		t.Fatalf("ListTrash: %v, %v", entries, err)
+	}
+	if got, err := readAllClose(es.DownloadTrash(entries[0].ID)); err != nil || string(got) != "b1" {
+		t.Errorf("DownloadTrash: %q, %v", got, err)
+	}
+}
+
+// readAllClose lê e fecha rc, aberto com o erro err
+func readAllClose(rc io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,353 @@
+package common
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrDecryptionFailed indica que um arquivo cifrado não pôde ser decifrado:
+// o conteúdo ou a chave foram alterados, a chave não existe ou o arquivo não
+// está cifrado
+var ErrDecryptionFailed = errors.New("não foi possível decifrar o arquivo")
+
+// Formato dos arquivos cifrados: um cabeçalho com encryptionMagic e o ID do
+// arquivo, seguido do conteúdo dividido em segmentos de encryptionSegmentSize
+// bytes, cada um cifrado com AES-256-GCM pela chave de dados do arquivo. O
+// nonce de cada segmento é o seu número e indica se ele é o último, então
+// segmentos reordenados, removidos ou um arquivo truncado falham na
+// verificação da tag como um segmento alterado
+const (
+	encryptionMagic       = "GFE1"
+	encryptionIDLen       = 16
+	encryptionHeaderLen   = len(encryptionMagic) + encryptionIDLen
+	encryptionSegmentSize = ChunkSize
+	encryptionKeyLen      = 32
+)
+
+// Usos do nonce, gravados no byte seguinte ao número do segmento
+const (
+	nonceSegment      = 0 // Segmento do conteúdo seguido de outros
+	nonceFinalSegment = 1 // Último segmento do conteúdo
+	nonceMetadata     = 2 // Informações do conteúdo guardadas com a chave
+)
+
+// keyIDPattern restringe os IDs das chaves mestras, que são gravados nos
+// registros das chaves de dados
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// KeyRing guarda as chaves mestras que cifram as chaves de dados dos arquivos
+// A chave atual cifra as chaves dos novos arquivos; as demais só decifram as
+// chaves de arquivos gravados antes de uma rotação
+type KeyRing struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeyRing lê as chaves mestras de text, no formato "id:chave", com a
+// chave de 32 bytes em base64. As entradas são separadas por quebras de linha
+// ou vírgulas e linhas iniciadas por "#" são ignoradas. A primeira entrada é a
+// chave atual
+func ParseKeyRing(text string) (*KeyRing, error) {
+	kr := &KeyRing{keys: make(map[string]cipher.AEAD)}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, entry := range strings.Split(line, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			id, encoded, ok := strings.Cut(entry, ":")
+			if !ok || !keyIDPattern.MatchString(id) {
+				return nil, fmt.Errorf("entrada de chave mestra inválida: use id:chave, com id de até 32 letras, números, - ou _")
+			}
+			if _, ok := kr.keys[id]; ok {
+				return nil, fmt.Errorf("chave mestra %s repetida", id)
+			}
+
+			key, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(key) != encryptionKeyLen {
+				return nil, fmt.Errorf("chave mestra %s inválida: use %d bytes em base64", id, encryptionKeyLen)
+			}
+
+			aead, err := newAEAD(key)
+			if err != nil {
+				return nil, err
+			}
+			kr.keys[id] = aead
+			if kr.current == "" {
+				kr.current = id
+			}
+		}
+	}
+
+	if kr.current == "" {
+		return nil, fmt.Errorf("nenhuma chave mestra encontrada")
+	}
+
+	return kr, nil
+}
+
+// LoadKeyRing lê as chaves mestras do arquivo path, no formato de ParseKeyRing
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de chaves %s: %w", path, err)
+	}
+
+	kr, err := ParseKeyRing(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("arquivo de chaves %s: %w", path, err)
+	}
+	return kr, nil
+}
+
+// CurrentID retorna o ID da chave mestra que cifra as chaves dos novos arquivos
+func (kr *KeyRing) CurrentID() string {
+	return kr.current
+}
+
+// wrap cifra a chave de dados do arquivo fileID com a chave mestra atual
+func (kr *KeyRing) wrap(dataKey, fileID []byte) (string, []byte, error) {
+	aead := kr.keys[kr.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("erro ao gerar nonce: %w", err)
+	}
+
+	return kr.current, aead.Seal(nonce, nonce, dataKey, fileID), nil
+}
+
+// unwrap decifra a chave de dados do arquivo fileID com a chave mestra keyID
+func (kr *KeyRing) unwrap(keyID string, wrapped, fileID []byte) ([]byte, error) {
+	aead, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: chave mestra %s não está no chaveiro", ErrDecryptionFailed, keyID)
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: chave de dados inválida", ErrDecryptionFailed)
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+
+	dataKey, err := aead.Open(nil, nonce, sealed, fileID)
+	if err != nil || len(dataKey) != encryptionKeyLen {
+		return nil, fmt.Errorf("%w: a chave de dados não confere com a chave mestra %s", ErrDecryptionFailed, keyID)
+	}
+	return dataKey, nil
+}
+
+// newAEAD cria o AES-256-GCM de uma chave
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar cifra: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// segmentNonce retorna o nonce do segmento counter com o uso kind
+func segmentNonce(counter uint64, kind byte) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, counter)
+	nonce[8] = kind
+	return nonce
+}
+
+// encryptReader cifra em streaming o conteúdo lido de src, produzindo o
+// cabeçalho e os segmentos de um arquivo cifrado
+type encryptReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte // Segmento em leitura e, após ele, um byte lido adiante
+	carry   int    // Bytes lidos adiante que iniciam o próximo segmento
+	sealed  []byte
+	pending []byte // Parte de sealed ainda não entregue
+	done    bool
+}
+
+// newEncryptReader cria o leitor que cifra src com dataKey para o arquivo fileID
+func newEncryptReader(src io.Reader, dataKey, fileID []byte) (*encryptReader, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, encryptionHeaderLen)
+	header = append(header, encryptionMagic...)
+	header = append(header, fileID...)
+
+	return &encryptReader{
+		src:     src,
+		aead:    aead,
+		plain:   make([]byte, encryptionSegmentSize+1),
+		sealed:  make([]byte, 0, encryptionSegmentSize+aead.Overhead()),
+		pending: header,
+	}, nil
+}
+
+// Read entrega o conteúdo cifrado, cifrando um segmento por vez
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// next cifra o próximo segmento. Um byte é lido além do segmento para saber
+// se ele é o último sem depender de um tamanho anunciado
+func (e *encryptReader) next() error {
+	n, err := io.ReadFull(e.src, e.plain[e.carry:])
+	total := e.carry + n
+
+	kind := byte(nonceSegment)
+	switch err {
+	case nil:
+		total = encryptionSegmentSize
+	case io.EOF, io.ErrUnexpectedEOF:
+		kind = nonceFinalSegment
+		e.done = true
+	default:
+		return err
+	}
+
+	e.sealed = e.aead.Seal(e.sealed[:0], segmentNonce(e.counter, kind), e.plain[:total], nil)
+	e.pending = e.sealed
+	e.counter++
+
+	e.carry = 0
+	if !e.done {
+		e.plain[0] = e.plain[encryptionSegmentSize]
+		e.carry = 1
+	}
+	return nil
+}
+
+// decryptReader decifra em streaming os segmentos de um arquivo cifrado,
+// falhando com ErrDecryptionFailed no primeiro segmento cuja tag não confere
+type decryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	sealed  []byte
+	plain   []byte
+	pending []byte
+	done    bool
+}
+
+// readEncryptionHeader lê o cabeçalho de um arquivo cifrado e retorna o ID do
+// arquivo; um arquivo sem o cabeçalho falha com ErrDecryptionFailed
+func readEncryptionHeader(r io.Reader) ([]byte, error) {
+	header := make([]byte, encryptionHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%w: o arquivo não está cifrado", ErrDecryptionFailed)
+		}
+		return nil, err
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, fmt.Errorf("%w: o arquivo não está cifrado", ErrDecryptionFailed)
+	}
+	return header[len(encryptionMagic):], nil
+}
+
+// newDecryptReader cria o leitor que decifra com dataKey os segmentos lidos
+// de src, posicionado logo após o cabeçalho
+func newDecryptReader(src io.Reader, dataKey []byte) (*decryptReader, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:    bufio.NewReaderSize(src, encryptionSegmentSize+aead.Overhead()+1),
+		aead:   aead,
+		sealed: make([]byte, encryptionSegmentSize+aead.Overhead()),
+		plain:  make([]byte, 0, encryptionSegmentSize),
+	}, nil
+}
+
+// Read entrega o conteúdo decifrado, decifrando um segmento por vez
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// skip descarta, sem decifrá-los, os próximos n segmentos, para que a
+// leitura comece no segmento seguinte
+func (d *decryptReader) skip(n uint64) error {
+	sealedLen := int64(len(d.sealed))
+	if _, err := io.CopyN(io.Discard, d.src, int64(n)*sealedLen); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("%w: o arquivo está incompleto", ErrDecryptionFailed)
+		}
+		return err
+	}
+
+	d.counter += n
+	return nil
+}
+
+// next lê e decifra o próximo segmento; ele é o último se nada vier depois
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.src, d.sealed)
+
+	kind := byte(nonceSegment)
+	switch err {
+	case nil:
+		if _, err := d.src.Peek(1); err == io.EOF {
+			kind = nonceFinalSegment
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		kind = nonceFinalSegment
+	default:
+		return err
+	}
+
+	plain, err := d.aead.Open(d.plain[:0], segmentNonce(d.counter, kind), d.sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: o segmento %d foi alterado ou o arquivo está incompleto", ErrDecryptionFailed, d.counter)
+	}
+
+	d.pending = plain
+	d.counter++
+	d.done = kind == nonceFinalSegment
+	return nil
+}
@@ -0,0 +1,79 @@
+package common
+
+import "errors"
+
+var (
+	// ErrNotFound indica que o arquivo não existe no armazenamento
+	ErrNotFound = errors.New("arquivo não encontrado")
+
+	// ErrAlreadyExists indica que já existe um arquivo com o nome de destino
+	ErrAlreadyExists = errors.New("arquivo já existe")
+
+	// ErrInvalidName indica que o nome do arquivo não é aceito pelo armazenamento
+	ErrInvalidName = errors.New("nome do arquivo inválido")
+
+	// ErrIsDirectory indica que a operação exige um arquivo, mas o nome é de um diretório
+	ErrIsDirectory = errors.New("é um diretório")
+
+	// ErrDirectoryNotEmpty indica que o diretório não pode ser removido por ter conteúdo
+	ErrDirectoryNotEmpty = errors.New("diretório não está vazio")
+
+	// ErrPreconditionFailed indica que uma condição de um upload condicional
+	// não foi atendida pela versão atual do arquivo
+	ErrPreconditionFailed = errors.New("condição do upload não atendida")
+
+	// ErrCorrupted indica que o conteúdo lido do armazenamento não confere com
+	// o tamanho ou o SHA-256 registrados no upload
+	ErrCorrupted = errors.New("conteúdo do arquivo está corrompido")
+
+	// ErrInvalidRange indica que o trecho pedido em um download parcial
+	// começa depois do fim do arquivo ou tem tamanho negativo
+	ErrInvalidRange = errors.New("trecho do arquivo inválido")
+
+	// ErrInvalidListOptions indica uma ordem, um padrão, um tamanho de
+	// página ou um token de página inválidos em uma listagem
+	ErrInvalidListOptions = errors.New("opções de listagem inválidas")
+
+	// ErrInvalidTags indica tags de upload com chaves ou valores fora do
+	// formato aceito, ou em número maior que MaxTags
+	ErrInvalidTags = errors.New("tags inválidas")
+
+	// ErrTooLarge indica um conteúdo maior que MaxMessageFileSize, que não
+	// cabe em uma única mensagem do RabbitMQ
+	ErrTooLarge = errors.New("conteúdo excede o limite de uma mensagem")
+)
+
+// Códigos de erro enviados em ResponseMessage.ErrorCode
+const (
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeAlreadyExists      = "already_exists"
+	ErrorCodeInvalidArgument    = "invalid_argument"
+	ErrorCodeFailedPrecondition = "failed_precondition"
+	ErrorCodeUnimplemented      = "unimplemented"
+	ErrorCodeOutOfRange         = "out_of_range"
+	ErrorCodeDataLoss           = "data_loss"
+	ErrorCodeResourceExhausted  = "resource_exhausted"
+	ErrorCodeInternal           = "internal"
+)
+
+// ErrorCode retorna o código de erro do protocolo AMQP correspondente a err
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrSessionNotFound):
+		return ErrorCodeNotFound
+	case errors.Is(err, ErrAlreadyExists):
+		return ErrorCodeAlreadyExists
+	case errors.Is(err, ErrInvalidName), errors.Is(err, ErrIsDirectory), errors.Is(err, ErrInvalidListOptions), errors.Is(err, ErrInvalidTags):
+		return ErrorCodeInvalidArgument
+	case errors.Is(err, ErrDirectoryNotEmpty), errors.Is(err, ErrPreconditionFailed):
+		return ErrorCodeFailedPrecondition
+	case errors.Is(err, ErrInvalidRange):
+		return ErrorCodeOutOfRange
+	case errors.Is(err, ErrDecryptionFailed), errors.Is(err, ErrCorrupted):
+		return ErrorCodeDataLoss
+	case errors.Is(err, ErrTooLarge):
+		return ErrorCodeResourceExhausted
+	default:
+		return ErrorCodeInternal
+	}
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+	"time"
+)
+
+// expiredPageSize é o número de entradas lidas por vez por RemoveExpired
+const expiredPageSize = 1000
+
+// UploadTTL resolve o TTL pedido por um cliente em um upload, em segundos:
+// um valor positivo é o TTL do arquivo, um negativo indica um arquivo que não
+// expira e zero usa defaultTTL, o TTL padrão do servidor
+func UploadTTL(seconds int64, defaultTTL time.Duration) time.Duration {
+	switch {
+	case seconds > 0:
+		return time.Duration(seconds) * time.Second
+	case seconds < 0:
+		return 0
+	default:
+		return defaultTTL
+	}
+}
+
+// RemoveExpired remove de storage os arquivos que expiraram até now e
+// retorna os nomes removidos. Cada arquivo é consultado de novo antes da
+// remoção, para não remover um arquivo substituído depois da listagem por
+// outro que ainda não expirou. Um erro em um arquivo não interrompe a
+// remoção dos demais; os erros são retornados juntos ao final
+func RemoveExpired(storage FileService, now time.Time) ([]string, error) {
+	var expired []string
+	opts := ListOptions{Recursive: true, PageSize: expiredPageSize}
+	for {
+		files, next, err := storage.ListFiles(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.Expired(now) {
+				expired = append(expired, file.Name)
+			}
+		}
+		if next == "" {
+			break
+		}
+		opts.PageToken = next
+	}
+
+	var removed []string
+	var errs []error
+	for _, name := range expired {
+		current, err := storage.StatFile(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !current.Expired(now) {
+			continue
+		}
+
+		if err := storage.DeleteFile(name); err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		removed = append(removed, name)
+	}
+
+	return removed, errors.Join(errs...)
+}
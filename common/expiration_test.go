@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRemoveExpired grava arquivos com TTLs diferentes e confere que
+// RemoveExpired, com o relógio avançado, remove só os que expiraram até o
+// instante pedido, inclusive as suas versões anteriores
+func TestRemoveExpired(t *testing.T) {
+	backends := map[string]func(t *testing.T) FileService{
+		"local": func(t *testing.T) FileService {
+			ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{Versions: VersionPolicy{MaxVersions: 5}})
+			if err != nil {
+				t.Fatalf("NewLocalStorage: %v", err)
+			}
+			return ls
+		},
+		"memory": func(t *testing.T) FileService {
+			return NewMemoryStorage(MemoryStorageOptions{Versions: VersionPolicy{MaxVersions: 5}})
+		},
+	}
+
+	for backend, open := range backends {
+		t.Run(backend, func(t *testing.T) {
+			testRemoveExpired(t, open(t))
+		})
+	}
+}
+
+// testRemoveExpired é a sequência de TestRemoveExpired
+func testRemoveExpired(t *testing.T, storage FileService) {
+	upload := func(name, content string, ttl time.Duration) {
+		t.Helper()
+		if _, err := storage.UploadFile(name, strings.NewReader(content), UploadOptions{TTL: ttl}); err != nil {
+			t.Fatalf("UploadFile(%s): %v", name, err)
+		}
+	}
+
+	start := time.Now()
+	upload("hour.txt", "v1", time.Hour)
+	upload("hour.txt", "v2", time.Hour)
+	upload("docs/two-hours.txt", "x", 2*time.Hour)
+	upload("forever.txt", "x", 0)
+	// Substituído por um upload sem TTL, o arquivo deixa de expirar
+	upload("replaced.txt", "x", time.Hour)
+	upload("replaced.txt", "y", 0)
+
+	remove := func(now time.Time, want ...string) {
+		t.Helper()
+		removed, err := RemoveExpired(storage, now)
+		if err != nil {
+			t.Fatalf("RemoveExpired: %v", err)
+		}
+		if !slices.Equal(removed, want) {
+			t.Fatalf("RemoveExpired(+%v): %v, esperado %v", now.Sub(start).Round(time.Minute), removed, want)
+		}
+	}
+
+	remove(start)
+	if info, err := storage.StatFile("hour.txt"); err != nil || info.ExpiresAt.Before(start.Add(time.Hour)) {
+		t.Errorf("StatFile: %+v, %v", info, err)
+	}
+
+	remove(start.Add(90*time.Minute), "hour.txt")
+	if _, err := storage.StatFile("hour.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("StatFile de arquivo expirado: %v, esperado ErrNotFound", err)
+	}
+	if versioner, ok := storage.(Versioner); ok {
+		if versions, err := versioner.ListVersions("hour.txt"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ListVersions de arquivo expirado: %v, %v; esperado ErrNotFound", versions, err)
+		}
+	}
+
+	remove(start.Add(3*time.Hour), "docs/two-hours.txt")
+	remove(start.Add(24 * time.Hour))
+	checkDownload(t, storage, "forever.txt", "x")
+	checkDownload(t, storage, "replaced.txt", "y")
+}
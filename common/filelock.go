@@ -0,0 +1,67 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocksDirName é o nome do diretório, dentro do diretório de dados, que guarda
+// os arquivos usados como locks entre processos
+const LocksDirName = ".locks"
+
+// fileLock é um lock consultivo (flock) mantido sobre um arquivo aberto
+// Protege operações entre processos diferentes que compartilham o mesmo
+// diretório, como o grpc-server e o rabbit-server no docker-compose
+type fileLock struct {
+	root *os.Root
+	path string
+	f    *os.File
+}
+
+// acquireFileLock abre (criando se necessário) o arquivo path de root e
+// trava-o, bloqueando até obter o lock. Locks exclusivos excluem todos os
+// outros; locks compartilhados excluem apenas os exclusivos
+func acquireFileLock(root *os.Root, path string, exclusive bool) (*fileLock, error) {
+	if err := mkdirAll(root, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de locks: %w", err)
+	}
+
+	for {
+		f, err := root.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir arquivo de lock %s: %w", path, err)
+		}
+
+		if err := lockFile(f, exclusive); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("erro ao travar %s: %w", path, err)
+		}
+
+		// Quem liberou o lock enquanto este processo esperava pode ter
+		// removido o arquivo (ver Release); o lock obtido sobre o arquivo
+		// removido não protege nada, então o arquivo é aberto de novo
+		held, err := f.Stat()
+		if err == nil {
+			var current os.FileInfo
+			if current, err = root.Stat(path); err == nil && os.SameFile(held, current) {
+				return &fileLock{root: root, path: path, f: f}, nil
+			}
+		}
+		f.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("erro ao consultar arquivo de lock %s: %w", path, err)
+		}
+	}
+}
+
+// Release libera o lock e fecha o arquivo. O arquivo é removido se ninguém
+// mais mantiver lock sobre ele, para que o diretório de locks não acumule um
+// arquivo por nome já usado
+func (l *fileLock) Release() error {
+	if locked, err := tryLockFile(l.f); err == nil && locked {
+		l.root.Remove(l.path)
+	}
+	unlockFile(l.f)
+	return l.f.Close()
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package common
+
+import "os"
+
+// Em plataformas sem flock os locks entre processos não têm efeito; a
+// consistência fica restrita aos locks internos de cada processo
+
+// lockFile não faz nada nesta plataforma
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// tryLockFile sempre informa sucesso nesta plataforma
+func tryLockFile(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile não faz nada nesta plataforma
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -1,14 +1,216 @@
 package common
 
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ChunkSize é o tamanho dos blocos usados para copiar arquivos em streaming
+const ChunkSize = 64 * 1024
+
 // FileService define a interface para operações de sistema de arquivos remoto
+// Upload e download trabalham com streams para que o uso de memória não
+// dependa do tamanho do arquivo. Os erros envolvem ErrNotFound,
+// ErrAlreadyExists, ErrInvalidName, ErrIsDirectory, ErrDirectoryNotEmpty,
+// ErrPreconditionFailed, ErrCorrupted, ErrInvalidRange,
+// ErrInvalidListOptions ou ErrInvalidTags (verificáveis com errors.Is) nas
+// situações correspondentes
+//
+// Os nomes são caminhos relativos separados por "/", como "docs/2024/a.txt",
+// em qualquer sistema operacional
 type FileService interface {
-	// ListFiles retorna uma lista de nomes de arquivos disponíveis
-	ListFiles() ([]string, error)
+	// ListFiles retorna as informações dos arquivos e diretórios de um
+	// diretório, incluindo o conteúdo dos subdiretórios se opts.Recursive,
+	// filtradas e ordenadas conforme opts. Com opts.PageSize, retorna no
+	// máximo uma página e o token da próxima, vazio na última
+	ListFiles(opts ListOptions) ([]FileInfo, string, error)
+
+	// UploadFile grava o conteúdo lido de r até io.EOF no arquivo especificado
+	// e retorna as informações do arquivo gravado. Os diretórios do caminho
+	// são criados se necessário. Se r retornar um erro ou as condições de opts
+	// não forem atendidas, o upload é abortado e nenhum arquivo parcial
+	// permanece no armazenamento
+	UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error)
+
+	// DownloadFile abre para leitura o trecho de um arquivo selecionado por
+	// opts e retorna as informações do arquivo inteiro, com o SHA-256
+	// registrado no upload, ou vazio se o armazenamento não o conhecer. Ao
+	// ler o arquivo inteiro, o conteúdo é conferido com esse registro e a
+	// leitura falha com ErrCorrupted no lugar de io.EOF se os dados lidos
+	// forem diferentes dos gravados; um trecho não é conferido
+	// O chamador é responsável por fechar o io.ReadCloser retornado
+	DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error)
+
+	// DeleteFile remove um arquivo ou um diretório vazio
+	DeleteFile(name string) error
+
+	// RenameFile renomeia ou move um arquivo ou diretório, falhando com
+	// ErrAlreadyExists se o nome de destino já estiver em uso
+	RenameFile(oldName, newName string) error
+
+	// StatFile retorna as informações de um arquivo ou diretório
+	StatFile(name string) (FileInfo, error)
+
+	// CreateDirectory cria um diretório e os diretórios intermediários que
+	// ainda não existirem, falhando com ErrAlreadyExists se o nome já estiver em uso
+	CreateDirectory(name string) error
+}
+
+// UploadOptions define condições para que um upload seja aceito, permitindo
+// que editores concorrentes não sobrescrevam as alterações uns dos outros,
+// por quanto tempo o arquivo gravado é mantido e as suas tags
+// Se uma condição falhar, o arquivo atual não é alterado e o erro envolve
+// ErrPreconditionFailed
+type UploadOptions struct {
+	// IfMatch exige que o arquivo exista e que seu ETag atual seja este
+	IfMatch string
+
+	// IfNoneMatch com "*" exige que o arquivo ainda não exista; com um ETag,
+	// exige que o conteúdo atual seja diferente dele
+	IfNoneMatch string
+
+	// TTL é o tempo, contado do fim do upload, depois do qual o arquivo
+	// expira e é removido por RemoveExpired; zero não expira
+	TTL time.Duration
+
+	// Tags são rótulos chave/valor gravados com o arquivo, como
+	// {"env": "prod"}, que substituem os do arquivo anterior e permitem
+	// selecioná-lo em ListFiles (ver ListOptions.Selector). Tags fora do
+	// formato aceito fazem o upload falhar com ErrInvalidTags
+	Tags map[string]string
+}
+
+// expiresAt retorna quando expira um arquivo gravado em modTime com opts, ou
+// zero se ele não expira
+func (opts UploadOptions) expiresAt(modTime time.Time) time.Time {
+	if opts.TTL <= 0 {
+		return time.Time{}
+	}
+	return modTime.Add(opts.TTL)
+}
+
+// conditional indica se opts tem alguma condição a avaliar
+func (opts UploadOptions) conditional() bool {
+	return opts.IfMatch != "" || opts.IfNoneMatch != ""
+}
+
+// check avalia as condições de opts contra a versão atual do arquivo name,
+// que é nil se o arquivo não existir
+func (opts UploadOptions) check(name string, current *FileInfo) error {
+	if opts.IfMatch != "" {
+		if current == nil {
+			return fmt.Errorf("%w: %s não existe", ErrPreconditionFailed, name)
+		}
+		if current.ETag != opts.IfMatch {
+			return fmt.Errorf("%w: ETag atual de %s é %s, esperado %s", ErrPreconditionFailed, name, current.ETag, opts.IfMatch)
+		}
+	}
+
+	if opts.IfNoneMatch != "" && current != nil {
+		if opts.IfNoneMatch == "*" {
+			return fmt.Errorf("%w: %s já existe", ErrPreconditionFailed, name)
+		}
+		if current.ETag == opts.IfNoneMatch {
+			return fmt.Errorf("%w: ETag atual de %s é %s", ErrPreconditionFailed, name, current.ETag)
+		}
+	}
 
-	// UploadFile faz upload de um arquivo com o nome e dados especificados
-	UploadFile(name string, data []byte) error
+	return nil
+}
+
+// DownloadOptions seleciona o trecho de um arquivo lido por DownloadFile
+// O valor zero lê o arquivo inteiro
+type DownloadOptions struct {
+	// Offset é a posição do primeiro byte lido. Um valor negativo conta a
+	// partir do fim do arquivo: -1024 lê os últimos 1024 bytes, ou o arquivo
+	// inteiro se ele for menor
+	Offset int64
+
+	// Length é o número máximo de bytes lidos; zero lê até o fim do arquivo
+	Length int64
+}
+
+// Partial indica se opts seleciona apenas um trecho do arquivo
+func (opts DownloadOptions) Partial() bool {
+	return opts.Offset != 0 || opts.Length != 0
+}
+
+// Range retorna a posição e o tamanho do trecho selecionado por opts em um
+// arquivo de size bytes. Um trecho que passa do fim do arquivo termina no
+// fim; um que começa depois do fim falha com ErrInvalidRange
+func (opts DownloadOptions) Range(size int64) (int64, int64, error) {
+	if opts.Length < 0 {
+		return 0, 0, fmt.Errorf("%w: tamanho negativo (%d)", ErrInvalidRange, opts.Length)
+	}
+
+	offset := opts.Offset
+	if offset < 0 {
+		offset = max(size+offset, 0)
+	}
+	if offset > size {
+		return 0, 0, fmt.Errorf("%w: posição %d depois do fim do arquivo (%d bytes)", ErrInvalidRange, offset, size)
+	}
+
+	length := size - offset
+	if opts.Length > 0 && opts.Length < length {
+		length = opts.Length
+	}
+	return offset, length, nil
+}
 
-	// DownloadFile faz download de um arquivo pelo nome e retorna seus dados
-	DownloadFile(name string) ([]byte, error)
+// ListOptions seleciona o que é retornado por ListFiles
+type ListOptions struct {
+	Dir       string // Diretório listado; vazio lista a raiz
+	Recursive bool   // Inclui o conteúdo dos subdiretórios
+
+	// Filtros: Prefix é o início do nome relativo a Dir, como "2024/" ou
+	// "rel"; Pattern é um padrão de path.Match para o último elemento do
+	// nome, como "*.txt". Os subdiretórios são percorridos mesmo que não
+	// passem pelos filtros
+	Prefix  string
+	Pattern string
+
+	// Selector seleciona só os arquivos cujas tags o atendem, como
+	// "env=prod" (ver ParseTagSelector); com um seletor, nenhum diretório é
+	// retornado
+	Selector string
+
+	Sort    ListSort // Ordem das entradas; vazio ordena por nome
+	Reverse bool     // Inverte a ordem
+
+	// PageSize é o número máximo de entradas retornadas; zero retorna todas
+	// PageToken é o token retornado com a página anterior, para continuar
+	// a listagem na mesma ordem
+	PageSize  int
+	PageToken string
+}
+
+// FileInfo descreve um arquivo armazenado
+type FileInfo struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	SHA256      string    `json:"sha256,omitempty"`       // Hash do conteúdo em hexadecimal
+	ContentType string    `json:"content_type,omitempty"` // Tipo MIME do conteúdo
+	IsDir       bool      `json:"is_dir,omitempty"`       // Indica um diretório; Size, SHA256, ContentType e ETag ficam vazios
+	ETag        string    `json:"etag,omitempty"`         // Identifica o conteúdo atual, para uploads condicionais
+	StoredSize  int64     `json:"stored_size,omitempty"`  // Bytes ocupados no armazenamento, menos que Size se compactado; zero se desconhecido
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`    // Quando o arquivo expira (ver UploadOptions.TTL); zero se não expira
+
+	Tags map[string]string `json:"tags,omitempty"` // Tags gravadas no upload (ver UploadOptions.Tags)
 }
 
+// Expired indica se o arquivo descrito por info já expirou em now
+func (info FileInfo) Expired(now time.Time) bool {
+	return !info.ExpiresAt.IsZero() && !now.Before(info.ExpiresAt)
+}
+
+// FileNames extrai os nomes de uma lista de arquivos
+func FileNames(files []FileInfo) []string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name
+	}
+	return names
+}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestFileServiceConformance executa a mesma sequência de operações sobre o
+// LocalStorage e o MemoryStorage, que deve substituí-lo em testes, e confere
+// que ambos retornam os mesmos resultados e os mesmos erros
+func TestFileServiceConformance(t *testing.T) {
+	backends := map[string]func(t *testing.T) FileService{
+		"local": func(t *testing.T) FileService {
+			ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{})
+			if err != nil {
+				t.Fatalf("NewLocalStorage: %v", err)
+			}
+			return ls
+		},
+		"memory": func(t *testing.T) FileService {
+			return NewMemoryStorage(MemoryStorageOptions{})
+		},
+	}
+
+	for backend, open := range backends {
+		t.Run(backend, func(t *testing.T) {
+			testFileServiceConformance(t, open(t))
+		})
+	}
+}
+
+// testFileServiceConformance é a sequência de TestFileServiceConformance
+func testFileServiceConformance(t *testing.T, storage FileService) {
+	upload := func(name, content string, opts UploadOptions) (FileInfo, error) {
+		return storage.UploadFile(name, strings.NewReader(content), opts)
+	}
+
+	info, err := upload("a.txt", "conteudo", UploadOptions{Tags: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if info.Name != "a.txt" || info.Size != int64(len("conteudo")) || info.SHA256 != sumOf("conteudo") || info.ETag == "" {
+		t.Errorf("UploadFile retornou %+v", info)
+	}
+	if _, err := upload("docs/sub/b.txt", "b", UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile em subdiretório: %v", err)
+	}
+	if err := storage.CreateDirectory("empty"); err != nil {
+		t.Fatalf("CreateDirectory: %v", err)
+	}
+
+	stat, err := storage.StatFile("a.txt")
+	if err != nil || stat.Size != info.Size || stat.SHA256 != info.SHA256 || stat.ETag != info.ETag || stat.Tags["k"] != "v" {
+		t.Errorf("StatFile: %+v, %v", stat, err)
+	}
+	if stat, err := storage.StatFile("docs"); err != nil || !stat.IsDir {
+		t.Errorf("StatFile de diretório: %+v, %v", stat, err)
+	}
+
+	got, err := downloadBytes(storage, "a.txt", DownloadOptions{Offset: 2, Length: 3})
+	if err != nil || string(got) != "nte" {
+		t.Errorf("DownloadFile de trecho: %q, %v", got, err)
+	}
+	if got, err := downloadBytes(storage, "a.txt", DownloadOptions{Offset: -3}); err != nil || string(got) != "udo" {
+		t.Errorf("DownloadFile do fim: %q, %v", got, err)
+	}
+
+	// Uploads condicionais
+	if _, err := upload("a.txt", "x", UploadOptions{IfNoneMatch: "*"}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("UploadFile com IfNoneMatch: %v, esperado ErrPreconditionFailed", err)
+	}
+	if _, err := upload("a.txt", "x", UploadOptions{IfMatch: "outro"}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("UploadFile com IfMatch diferente: %v, esperado ErrPreconditionFailed", err)
+	}
+	if _, err := upload("a.txt", "novo", UploadOptions{IfMatch: info.ETag}); err != nil {
+		t.Errorf("UploadFile com IfMatch igual: %v", err)
+	}
+
+	// Listagens
+	names := func(opts ListOptions) []string {
+		t.Helper()
+		files, _, err := storage.ListFiles(opts)
+		if err != nil {
+			t.Fatalf("ListFiles(%+v): %v", opts, err)
+		}
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		return names
+	}
+	if got := strings.Join(names(ListOptions{}), ","); got != "a.txt,docs,empty" {
+		t.Errorf("ListFiles: %s", got)
+	}
+	if got := strings.Join(names(ListOptions{Recursive: true}), ","); got != "a.txt,docs,docs/sub,docs/sub/b.txt,empty" {
+		t.Errorf("ListFiles recursivo: %s", got)
+	}
+	if got := strings.Join(names(ListOptions{Dir: "docs/sub"}), ","); got != "docs/sub/b.txt" {
+		t.Errorf("ListFiles de docs/sub: %s", got)
+	}
+
+	// Erros esperados de cada operação
+	errs := []struct {
+		what string
+		err  error
+		want error
+	}{
+		{"UploadFile com nome inválido", uploadErr(upload("../x", "x", UploadOptions{})), ErrInvalidName},
+		{"UploadFile sobre diretório", uploadErr(upload("docs", "x", UploadOptions{})), ErrIsDirectory},
+		{"DownloadFile inexistente", downloadErr(storage.DownloadFile("missing.txt", DownloadOptions{})), ErrNotFound},
+		{"DownloadFile de diretório", downloadErr(storage.DownloadFile("docs", DownloadOptions{})), ErrIsDirectory},
+		{"DownloadFile além do fim", downloadErr(storage.DownloadFile("a.txt", DownloadOptions{Offset: 100})), ErrInvalidRange},
+		{"StatFile inexistente", statErr(storage.StatFile("missing.txt")), ErrNotFound},
+		{"ListFiles de diretório inexistente", listErr(storage.ListFiles(ListOptions{Dir: "missing"})), ErrNotFound},
+		{"ListFiles de arquivo", listErr(storage.ListFiles(ListOptions{Dir: "a.txt"})), ErrInvalidName},
+		{"CreateDirectory existente", storage.CreateDirectory("empty"), ErrAlreadyExists},
+		{"CreateDirectory sobre arquivo", storage.CreateDirectory("a.txt"), ErrAlreadyExists},
+		{"DeleteFile inexistente", storage.DeleteFile("missing.txt"), ErrNotFound},
+		{"DeleteFile de diretório com conteúdo", storage.DeleteFile("docs"), ErrDirectoryNotEmpty},
+		{"RenameFile inexistente", storage.RenameFile("missing.txt", "x.txt"), ErrNotFound},
+		{"RenameFile sobre arquivo", storage.RenameFile("docs/sub/b.txt", "a.txt"), ErrAlreadyExists},
+		{"RenameFile sobre diretório", storage.RenameFile("a.txt", "empty"), ErrAlreadyExists},
+		{"RenameFile para dentro de si mesmo", storage.RenameFile("docs", "docs/sub/docs"), ErrInvalidName},
+	}
+	for _, e := range errs {
+		if !errors.Is(e.err, e.want) {
+			t.Errorf("%s: %v, esperado %v", e.what, e.err, e.want)
+		}
+	}
+
+	// Rename de diretório e remoções
+	if err := storage.RenameFile("docs", "moved"); err != nil {
+		t.Fatalf("RenameFile de diretório: %v", err)
+	}
+	if got := strings.Join(names(ListOptions{Recursive: true}), ","); got != "a.txt,empty,moved,moved/sub,moved/sub/b.txt" {
+		t.Errorf("ListFiles depois do rename: %s", got)
+	}
+	for _, name := range []string{"moved/sub/b.txt", "moved/sub", "moved", "empty", "a.txt"} {
+		if err := storage.DeleteFile(name); err != nil {
+			t.Errorf("DeleteFile(%s): %v", name, err)
+		}
+	}
+	if got := names(ListOptions{Recursive: true}); len(got) != 0 {
+		t.Errorf("ListFiles depois das remoções: %v", got)
+	}
+}
+
+// uploadErr, downloadErr, statErr e listErr descartam os resultados de uma
+// operação e retornam só o erro
+func uploadErr(_ FileInfo, err error) error { return err }
+
+func downloadErr(rc io.ReadCloser, _ FileInfo, err error) error {
+	if err == nil {
+		rc.Close()
+	}
+	return err
+}
+
+func statErr(_ FileInfo, err error) error { return err }
+
+func listErr(_ []FileInfo, _ string, err error) error { return err }
@@ -0,0 +1,578 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexDirName é o nome do diretório, dentro do diretório de dados, onde o
+// LocalStorage mantém o índice dos arquivos (ver LocalStorageOptions.Index)
+const IndexDirName = ".index"
+
+const (
+	// indexLockName é o arquivo travado com flock pelos processos que
+	// compartilham o índice: de forma exclusiva para gravar no journal e
+	// compartilhada para lê-lo
+	indexLockName = "lock"
+
+	// indexJournalName é o arquivo do índice. A primeira linha é um snapshot
+	// com todas as entradas; as seguintes são as transações gravadas depois
+	// dele, uma por linha
+	indexJournalName = "journal"
+
+	// indexCompactSize é o tamanho das transações no journal a partir do
+	// qual elas são incorporadas a um novo snapshot
+	indexCompactSize = 4 * 1024 * 1024
+
+	// indexStaleTx é o tempo depois do qual uma transação iniciada e não
+	// concluída é atribuída a um processo que caiu durante a operação
+	indexStaleTx = time.Minute
+)
+
+// indexHeader é a primeira linha do journal
+type indexHeader struct {
+	Entries []FileInfo `json:"entries"`
+}
+
+// indexRecord é uma linha do journal depois do snapshot. Uma operação do
+// LocalStorage grava um registro Begin, com os nomes que pode alterar, antes
+// de tocar no disco, e um registro Commit, com as alterações, depois. Um
+// Begin sem Commit indica uma operação interrompida por uma queda: os nomes
+// são lidos de novo do disco. Um registro truncado por uma queda não é
+// reconhecido e é ignorado, então cada Commit é aplicado por inteiro ou não
+// é aplicado
+type indexRecord struct {
+	Begin  string    `json:"begin,omitempty"`
+	Names  []string  `json:"names,omitempty"`
+	At     time.Time `json:"at,omitzero"` // Quando a transação começou
+	Commit string    `json:"commit,omitempty"`
+
+	// Alterações de um Commit, aplicadas nesta ordem. Delete remove os nomes
+	// e, se forem diretórios, todo o seu conteúdo; Move renomeia um nome e o
+	// seu conteúdo
+	Delete []string   `json:"delete,omitempty"`
+	Move   *indexMove `json:"move,omitempty"`
+	Put    []FileInfo `json:"put,omitempty"`
+}
+
+// indexMove é um rename registrado em um Commit
+type indexMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// indexPending é uma transação iniciada e ainda não concluída
+type indexPending struct {
+	names []string
+	since time.Time // Quando a transação começou
+}
+
+// fileIndex é um índice chave/valor dos arquivos de um LocalStorage, do nome
+// para as informações do arquivo, mantido em memória e persistido em
+// IndexDirName. Vários processos podem compartilhar o índice de um mesmo
+// diretório: cada um acompanha o journal, lendo as transações gravadas pelos
+// demais antes de cada consulta ou gravação
+type fileIndex struct {
+	root *os.Root
+
+	// scan lê do disco as informações de um nome e, se for um diretório, de
+	// todo o seu conteúdo; o nome vazio lê todo o diretório base
+	scan func(name string) ([]FileInfo, error)
+
+	mu       sync.Mutex
+	entries  map[string]FileInfo
+	children map[string]map[string]bool // Diretório -> nomes das entradas que contém
+	pending  map[string]indexPending    // ID da transação -> nomes alterados
+	journal  *os.File                   // Journal atual, aberto para leitura e escrita no fim
+	offset   int64                      // Bytes do journal já aplicados a entries
+	header   int64                      // Bytes do snapshot no início do journal
+}
+
+// openIndex abre o índice de root, construindo-o com scan se ele ainda não
+// existir. As transações sem Commit iniciadas há mais de indexStaleTx são
+// refeitas a partir do disco; as mais recentes podem ser de outro processo
+// ainda em andamento
+func openIndex(root *os.Root, scan func(name string) ([]FileInfo, error)) (*fileIndex, error) {
+	ix := &fileIndex{root: root, scan: scan}
+
+	unlock, err := ix.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Com o lock exclusivo nenhum outro processo está compactando o índice,
+	// então os temporários que existirem são restos de quedas
+	entries, err := readDir(root, IndexDirName)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler o índice: %w", err)
+	}
+	for _, entry := range entries {
+		if isTempFile(entry.Name()) {
+			root.Remove(filepath.Join(IndexDirName, entry.Name()))
+		}
+	}
+
+	if _, err := root.Stat(ix.journalPath()); os.IsNotExist(err) {
+		files, err := scan("")
+		if err != nil {
+			return nil, fmt.Errorf("erro ao construir o índice: %w", err)
+		}
+		if err := ix.compact(files); err != nil {
+			return nil, err
+		}
+		return ix, nil
+	}
+
+	if err := ix.catchUp(); err != nil {
+		return nil, err
+	}
+	if err := ix.recover(indexStaleTx); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// list retorna as entradas de dir e, se recursive, as de seus
+// subdiretórios, falhando com ErrNotFound se dir não estiver no índice
+func (ix *fileIndex) list(dir string, recursive bool) (map[string]FileInfo, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	unlock, err := ix.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	err = ix.catchUp()
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		info, ok := ix.entries[dir]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, dir)
+		}
+		if !info.IsDir {
+			return nil, fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, dir)
+		}
+	}
+
+	files := make(map[string]FileInfo)
+	if recursive {
+		for _, name := range ix.subtree(dir)[1:] {
+			files[name] = ix.entries[name]
+		}
+	} else {
+		for name := range ix.children[dir] {
+			files[name] = ix.entries[name]
+		}
+	}
+	return files, nil
+}
+
+// rebuild substitui todas as entradas do índice pelas lidas do disco e
+// retorna quantas foram indexadas
+func (ix *fileIndex) rebuild() (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	unlock, err := ix.lock(true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := ix.catchUp(); err != nil {
+		return 0, err
+	}
+	files, err := ix.scan("")
+	if err != nil {
+		return 0, fmt.Errorf("erro ao reconstruir o índice: %w", err)
+	}
+	if err := ix.compact(files); err != nil {
+		return 0, err
+	}
+	return len(files), nil
+}
+
+// indexTx é uma transação do índice aberta por begin
+// Os métodos de uma transação nil não fazem nada, para que o LocalStorage
+// sem índice use o mesmo código
+type indexTx struct {
+	ix    *fileIndex
+	id    string
+	names []string
+	done  bool
+}
+
+// begin grava no journal que uma operação vai alterar os nomes names,
+// sincronizando-o em disco antes de a operação começar. Com ix nil, retorna
+// uma transação nil
+func (ix *fileIndex) begin(names ...string) (*indexTx, error) {
+	if ix == nil {
+		return nil, nil
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.write(indexRecord{Begin: id, Names: names, At: time.Now()}); err != nil {
+		return nil, err
+	}
+	return &indexTx{ix: ix, id: id, names: names}, nil
+}
+
+// commit grava no journal as alterações feitas pela operação e encerra a
+// transação. Se a gravação falhar, a transação fica sem Commit e seus nomes
+// são lidos de novo do disco mais tarde
+func (tx *indexTx) commit(record indexRecord) error {
+	if tx == nil || tx.done {
+		return nil
+	}
+	tx.done = true
+
+	record.Commit = tx.id
+	return tx.ix.write(record)
+}
+
+// abort encerra uma transação que não chegou ao commit, por uma operação
+// que falhou, lendo de novo do disco os nomes que ela poderia ter alterado
+func (tx *indexTx) abort() {
+	if tx == nil || tx.done {
+		return
+	}
+	tx.done = true
+
+	tx.ix.mu.Lock()
+	defer tx.ix.mu.Unlock()
+
+	unlock, err := tx.ix.lock(true)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	if tx.ix.catchUp() == nil {
+		tx.ix.refresh(tx.id, tx.names)
+	}
+}
+
+// write grava record no fim do journal e o aplica às entradas
+func (ix *fileIndex) write(record indexRecord) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	unlock, err := ix.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := ix.catchUp(); err != nil {
+		return err
+	}
+	if err := ix.append(record); err != nil {
+		return err
+	}
+	if err := ix.recover(indexStaleTx); err != nil {
+		return err
+	}
+
+	if ix.offset-ix.header < indexCompactSize {
+		return nil
+	}
+	return ix.compact(slices.Collect(maps.Values(ix.entries)))
+}
+
+// append grava record no fim do journal, sincronizando-o em disco, e o
+// aplica às entradas
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo do índice
+// e aplicou o journal com catchUp
+func (ix *fileIndex) append(record indexRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar no índice: %w", err)
+	}
+	line = append(line, '\n')
+
+	// Depois de catchUp, só uma linha truncada por uma queda fica além de
+	// offset; o registro começa em uma nova linha para não emendar nela
+	stat, err := ix.journal.Stat()
+	if err != nil {
+		return fmt.Errorf("erro ao gravar no índice: %w", err)
+	}
+	if stat.Size() > ix.offset {
+		line = append([]byte("\n"), line...)
+		ix.offset = stat.Size()
+	}
+
+	if _, err := ix.journal.Write(line); err != nil {
+		return fmt.Errorf("erro ao gravar no índice: %w", err)
+	}
+	if err := ix.journal.Sync(); err != nil {
+		return fmt.Errorf("erro ao gravar no índice: %w", err)
+	}
+	ix.offset += int64(len(line))
+
+	ix.apply(record)
+	return nil
+}
+
+// recover lê de novo do disco os nomes das transações sem Commit vistas há
+// pelo menos age, que são de operações interrompidas, e grava um Commit
+// para cada uma
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo do índice
+// e aplicou o journal com catchUp
+func (ix *fileIndex) recover(age time.Duration) error {
+	for id, tx := range ix.pending {
+		if time.Since(tx.since) < age {
+			continue
+		}
+		if err := ix.refresh(id, tx.names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refresh grava o Commit da transação id com as informações dos nomes
+// names lidas do disco
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo do índice
+// e aplicou o journal com catchUp
+func (ix *fileIndex) refresh(id string, names []string) error {
+	record := indexRecord{Commit: id, Delete: names}
+	for _, name := range names {
+		files, err := ix.scan(name)
+		if err != nil {
+			return fmt.Errorf("erro ao reconstruir o índice de %s: %w", name, err)
+		}
+		record.Put = append(record.Put, files...)
+	}
+	return ix.append(record)
+}
+
+// compact grava um novo journal com files como snapshot, seguido do Begin
+// das transações ainda sem Commit, e o coloca no lugar do atual com um
+// rename. Os demais processos percebem a troca no próximo catchUp
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo do índice
+func (ix *fileIndex) compact(files []FileInfo) error {
+	slices.SortFunc(files, func(a, b FileInfo) int { return comparePaths(a.Name, b.Name) })
+
+	tmp, tmpPath, err := createTemp(ix.root, filepath.Join(IndexDirName, tempFilePrefix))
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	err = enc.Encode(indexHeader{Entries: files})
+	for id, tx := range ix.pending {
+		if err == nil {
+			err = enc.Encode(indexRecord{Begin: id, Names: tx.names, At: tx.since})
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = renameInRoot(ix.root, tmpPath, ix.journalPath())
+	}
+	if err != nil {
+		ix.root.Remove(tmpPath)
+		return fmt.Errorf("erro ao compactar o índice: %w", err)
+	}
+	if err := syncDir(ix.root, IndexDirName); err != nil {
+		return err
+	}
+
+	// Relê o journal gravado, como faria qualquer outro processo
+	ix.close()
+	return ix.catchUp()
+}
+
+// catchUp aplica às entradas as transações gravadas no journal desde a
+// última leitura, inclusive por outros processos. Se o journal foi
+// substituído por uma compactação, ele é lido de novo desde o snapshot
+// NOTA: Esta função assume que o chamador obteve o lock do índice
+func (ix *fileIndex) catchUp() error {
+	stat, err := ix.root.Stat(ix.journalPath())
+	if err != nil {
+		return fmt.Errorf("erro ao consultar o índice: %w", err)
+	}
+	if ix.journal != nil {
+		current, err := ix.journal.Stat()
+		if err != nil {
+			return fmt.Errorf("erro ao consultar o índice: %w", err)
+		}
+		if !os.SameFile(stat, current) {
+			ix.close()
+		}
+	}
+
+	if ix.journal == nil {
+		journal, err := ix.root.OpenFile(ix.journalPath(), os.O_RDWR|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("erro ao abrir o índice: %w", err)
+		}
+		ix.journal = journal
+		ix.entries = make(map[string]FileInfo)
+		ix.children = make(map[string]map[string]bool)
+		ix.pending = make(map[string]indexPending)
+	}
+
+	// Lê só o que foi gravado depois de offset, uma linha por vez
+	r := bufio.NewReader(io.NewSectionReader(ix.journal, ix.offset, 1<<62))
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Fim do journal ou linha truncada por uma queda durante a
+			// gravação, terminada pela próxima gravação (ver append)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao ler o índice: %w", err)
+		}
+
+		if ix.offset == 0 {
+			var header indexHeader
+			if err := json.Unmarshal(line, &header); err != nil {
+				return fmt.Errorf("erro ao ler o índice: snapshot inválido: %w", err)
+			}
+			for _, info := range header.Entries {
+				ix.put(info)
+			}
+			ix.header = int64(len(line))
+		} else {
+			var record indexRecord
+			if json.Unmarshal(line, &record) == nil {
+				ix.apply(record)
+			}
+		}
+		ix.offset += int64(len(line))
+	}
+}
+
+// apply aplica um registro do journal às entradas
+func (ix *fileIndex) apply(record indexRecord) {
+	if record.Begin != "" {
+		// Um Begin sem horário, de um journal anterior, conta a partir de
+		// quando foi lido
+		since := record.At
+		if since.IsZero() {
+			since = time.Now()
+		}
+		ix.pending[record.Begin] = indexPending{names: record.Names, since: since}
+		return
+	}
+	delete(ix.pending, record.Commit)
+
+	for _, name := range record.Delete {
+		ix.remove(name)
+	}
+	if move := record.Move; move != nil {
+		var moved []FileInfo
+		for _, name := range ix.subtree(move.From) {
+			if info, ok := ix.entries[name]; ok {
+				info.Name = move.To + strings.TrimPrefix(name, move.From)
+				moved = append(moved, info)
+			}
+		}
+		ix.remove(move.From)
+		for _, info := range moved {
+			ix.put(info)
+		}
+	}
+	for _, info := range record.Put {
+		ix.put(info)
+	}
+}
+
+// put grava info nas entradas, sob o diretório que a contém
+func (ix *fileIndex) put(info FileInfo) {
+	ix.entries[info.Name] = info
+
+	parent := indexParent(info.Name)
+	if ix.children[parent] == nil {
+		ix.children[parent] = make(map[string]bool)
+	}
+	ix.children[parent][info.Name] = true
+}
+
+// remove tira das entradas name e, se for um diretório, o seu conteúdo
+func (ix *fileIndex) remove(name string) {
+	for _, other := range ix.subtree(name) {
+		delete(ix.entries, other)
+		delete(ix.children, other)
+
+		parent := indexParent(other)
+		delete(ix.children[parent], other)
+		if len(ix.children[parent]) == 0 {
+			delete(ix.children, parent)
+		}
+	}
+}
+
+// subtree retorna name seguido dos nomes de todo o seu conteúdo no índice
+func (ix *fileIndex) subtree(name string) []string {
+	names := []string{name}
+	for i := 0; i < len(names); i++ {
+		for child := range ix.children[names[i]] {
+			names = append(names, child)
+		}
+	}
+	return names
+}
+
+// indexParent retorna o diretório que contém name, vazio para o diretório base
+func indexParent(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// close fecha o journal aberto, que será lido de novo desde o snapshot
+func (ix *fileIndex) close() {
+	if ix.journal != nil {
+		ix.journal.Close()
+		ix.journal = nil
+	}
+	ix.offset = 0
+	ix.header = 0
+}
+
+// lock trava o índice entre processos; os processos que só leem o journal
+// usam o lock compartilhado
+func (ix *fileIndex) lock(exclusive bool) (func(), error) {
+	fl, err := acquireFileLock(ix.root, filepath.Join(IndexDirName, indexLockName), exclusive)
+	if err != nil {
+		return nil, err
+	}
+	return func() { fl.Release() }, nil
+}
+
+// journalPath retorna o caminho, relativo ao diretório base, do journal
+func (ix *fileIndex) journalPath() string {
+	return filepath.Join(IndexDirName, indexJournalName)
+}
@@ -0,0 +1,287 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listSummary lista dir de storage e resume cada entrada pelo que o índice e
+// o disco devem concordar
+func listSummary(t *testing.T, storage FileService, dir string, recursive bool) map[string]string {
+	t.Helper()
+
+	files, _, err := storage.ListFiles(ListOptions{Dir: dir, Recursive: recursive})
+	if err != nil {
+		t.Fatalf("ListFiles(%q): %v", dir, err)
+	}
+	summary := make(map[string]string)
+	for _, file := range files {
+		summary[file.Name] = fmt.Sprintf("dir=%v size=%d sha256=%s tags=%v", file.IsDir, file.Size, file.SHA256, file.Tags)
+	}
+	return summary
+}
+
+// checkIndexMatchesDisk confere que indexed, com o índice ativo, lista o
+// mesmo que plain, que lê os diretórios do mesmo diretório de dados, no
+// diretório base e em cada um dos seus subdiretórios
+func checkIndexMatchesDisk(t *testing.T, step string, indexed, plain *LocalStorage) {
+	t.Helper()
+
+	dirs := []string{""}
+	files, _, err := plain.ListFiles(ListOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir {
+			dirs = append(dirs, file.Name)
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, recursive := range []bool{false, true} {
+			want := listSummary(t, plain, dir, recursive)
+			if got := listSummary(t, indexed, dir, recursive); !maps.Equal(got, want) {
+				t.Fatalf("%s: ListFiles(%q, recursive=%v) com índice\n%v\nsem índice\n%v", step, dir, recursive, got, want)
+			}
+		}
+	}
+}
+
+// openIndexedPair abre sobre o mesmo diretório um LocalStorage com índice e
+// outro sem
+func openIndexedPair(t *testing.T, dir string) (*LocalStorage, *LocalStorage) {
+	t.Helper()
+
+	indexed, err := NewLocalStorage(dir, LocalStorageOptions{Index: true})
+	if err != nil {
+		t.Fatalf("NewLocalStorage com índice: %v", err)
+	}
+	plain, err := NewLocalStorage(dir, LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return indexed, plain
+}
+
+// TestIndexMatchesDisk faz uploads, renames e remoções pelo LocalStorage com
+// índice e confere, a cada passo, que a listagem do índice é a do disco
+func TestIndexMatchesDisk(t *testing.T) {
+	dir := t.TempDir()
+	indexed, plain := openIndexedPair(t, dir)
+
+	steps := []struct {
+		name string
+		op   func() error
+	}{
+		{"upload", func() error {
+			_, err := indexed.UploadFile("a.txt", strings.NewReader("a"), UploadOptions{Tags: map[string]string{"k": "v"}})
+			return err
+		}},
+		{"upload em subdiretório", func() error {
+			_, err := indexed.UploadFile("docs/sub/b.txt", strings.NewReader("bb"), UploadOptions{})
+			return err
+		}},
+		{"mkdir", func() error { return indexed.CreateDirectory("docs/empty") }},
+		{"sobrescrita", func() error {
+			_, err := indexed.UploadFile("a.txt", strings.NewReader("aaa"), UploadOptions{})
+			return err
+		}},
+		{"rename de arquivo", func() error { return indexed.RenameFile("a.txt", "docs/a.txt") }},
+		{"rename de diretório", func() error { return indexed.RenameFile("docs/sub", "sub") }},
+		{"rename de volta", func() error { return indexed.RenameFile("sub", "docs/sub") }},
+		{"delete de arquivo", func() error { return indexed.DeleteFile("docs/sub/b.txt") }},
+		{"delete de diretório vazio", func() error { return indexed.DeleteFile("docs/empty") }},
+		{"rename falho", func() error {
+			if err := indexed.RenameFile("missing.txt", "x.txt"); err == nil {
+				return fmt.Errorf("rename de arquivo inexistente não falhou")
+			}
+			return nil
+		}},
+	}
+	for _, step := range steps {
+		if err := step.op(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkIndexMatchesDisk(t, step.name, indexed, plain)
+	}
+
+	// Um novo processo lê o mesmo índice do journal
+	reopened, _ := openIndexedPair(t, dir)
+	checkIndexMatchesDisk(t, "reaberto", reopened, plain)
+}
+
+// TestIndexRecoverStale grava Begins sem Commit, como os de operações
+// interrompidas por uma queda, e confere que só os mais antigos que
+// indexStaleTx são refeitos a partir do disco ao abrir o índice
+func TestIndexRecoverStale(t *testing.T) {
+	dir := t.TempDir()
+	openIndexedPair(t, dir)
+
+	// Os arquivos que as operações interrompidas gravaram no disco
+	for _, name := range []string{"stale.txt", "recent.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	staleAt := time.Now().Add(-2 * indexStaleTx)
+	appendJournal(t, dir,
+		indexRecord{Begin: "stale", Names: []string{"stale.txt"}, At: staleAt},
+		indexRecord{Begin: "recent", Names: []string{"recent.txt"}, At: time.Now()},
+	)
+
+	reopened, err := NewLocalStorage(dir, LocalStorageOptions{Index: true})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	files := listSummary(t, reopened, "", false)
+	if _, ok := files["stale.txt"]; !ok {
+		t.Errorf("stale.txt não foi lido do disco: %v", files)
+	}
+	if _, ok := files["recent.txt"]; ok {
+		t.Errorf("recent.txt lido do disco com a transação ainda recente: %v", files)
+	}
+
+	ix := reopened.index
+	if _, ok := ix.pending["recent"]; !ok || len(ix.pending) != 1 {
+		t.Fatalf("transações pendentes: %v, esperado só recent", ix.pending)
+	}
+
+	// Vencido o prazo, a próxima gravação recupera a transação recente
+	ix.mu.Lock()
+	tx := ix.pending["recent"]
+	tx.since = staleAt
+	ix.pending["recent"] = tx
+	ix.mu.Unlock()
+	if err := ix.write(indexRecord{Commit: "noop"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, ok := listSummary(t, reopened, "", false)["recent.txt"]; !ok {
+		t.Errorf("recent.txt não foi lido do disco depois do prazo")
+	}
+	if len(ix.pending) != 0 {
+		t.Errorf("transações pendentes restantes: %v", ix.pending)
+	}
+}
+
+// appendJournal grava records no fim do journal do índice de dir, como um
+// processo que caiu antes dos Commits
+func appendJournal(t *testing.T, dir string, records ...indexRecord) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Join(dir, IndexDirName, indexJournalName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+	}
+}
+
+// TestIndexCompaction grava no journal até passar de indexCompactSize e
+// confere que o snapshot compactado mantém todas as entradas e as
+// transações ainda pendentes
+func TestIndexCompaction(t *testing.T) {
+	dir := t.TempDir()
+	indexed, plain := openIndexedPair(t, dir)
+
+	for i := range 20 {
+		name := fmt.Sprintf("d%d/f%d.txt", i%4, i)
+		if _, err := indexed.UploadFile(name, strings.NewReader(name), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile: %v", err)
+		}
+	}
+
+	ix := indexed.index
+	beganAt := time.Now().Truncate(time.Second)
+	if err := ix.write(indexRecord{Begin: "open", Names: []string{"d0"}, At: beganAt}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// Regrava as entradas com tags grandes até o journal ser compactado
+	big := strings.Repeat("x", 64*1024)
+	for compacted := false; !compacted; {
+		var put []FileInfo
+		for _, info := range ix.entries {
+			if !info.IsDir {
+				info.Tags = map[string]string{"big": big}
+			}
+			put = append(put, info)
+		}
+		before := ix.offset
+		if err := ix.write(indexRecord{Commit: "rewrite", Put: put}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		compacted = ix.offset < before
+	}
+	reopened, err := NewLocalStorage(dir, LocalStorageOptions{Index: true})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	if !reflect.DeepEqual(reopened.index.entries, ix.entries) {
+		t.Errorf("entradas diferentes depois da compactação: %d, esperado %d", len(reopened.index.entries), len(ix.entries))
+	}
+	if len(ix.entries) != 24 {
+		t.Errorf("%d entradas no índice, esperado 24", len(ix.entries))
+	}
+	pending, ok := reopened.index.pending["open"]
+	if !ok || !pending.since.Equal(beganAt) || !reflect.DeepEqual(pending.names, []string{"d0"}) {
+		t.Errorf("transação pendente perdida na compactação: %v", reopened.index.pending)
+	}
+
+	// Sem as tags regravadas, a listagem volta a ser a do disco
+	if _, err := reopened.Reindex(); err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	checkIndexMatchesDisk(t, "compactado", reopened, plain)
+}
+
+// TestReindex altera o diretório de dados por fora do LocalStorage e
+// confere que Reindex reconstrói o índice a partir do disco
+func TestReindex(t *testing.T) {
+	dir := t.TempDir()
+	indexed, plain := openIndexedPair(t, dir)
+
+	for _, name := range []string{"a.txt", "docs/b.txt", "docs/sub/c.txt"} {
+		if _, err := indexed.UploadFile(name, strings.NewReader(name), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile: %v", err)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dir, "docs", "b.txt")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "sub", "new.txt"), []byte("novo"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	files := listSummary(t, indexed, "", true)
+	if _, ok := files["docs/b.txt"]; !ok {
+		t.Fatalf("índice já viu a remoção feita por fora: %v", files)
+	}
+
+	n, err := indexed.Reindex()
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if want := len(listSummary(t, plain, "", true)); n != want {
+		t.Errorf("Reindex indexou %d entradas, esperado %d", n, want)
+	}
+	checkIndexMatchesDisk(t, "reindexado", indexed, plain)
+
+	if _, err := plain.Reindex(); err == nil {
+		t.Errorf("Reindex sem índice não falhou")
+	}
+}
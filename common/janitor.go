@@ -0,0 +1,71 @@
+package common
+
+import (
+	"log"
+	"time"
+)
+
+// KeyCollector é implementado pelos armazenamentos que removem as chaves de
+// dados sem uso, como o EncryptedStorage
+type KeyCollector interface {
+	CollectKeys() (int, error)
+}
+
+// RunKeyCollector remove as chaves de dados sem uso de collector a cada
+// interval e registra em logger quantas foram removidas. Não retorna; os
+// servidores a executam em uma goroutine
+func RunKeyCollector(collector KeyCollector, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		collected, err := collector.CollectKeys()
+		if collected > 0 {
+			logger.Printf("%d chave(s) de dados sem uso removida(s)", collected)
+		}
+		if err != nil {
+			logger.Printf("Erro ao remover chaves sem uso: %v", err)
+		}
+	}
+}
+
+// RunJanitor remove os arquivos expirados de storage, as entradas da
+// lixeira além do prazo de retenção e, se sessionMaxAge for positivo, as
+// sessões de upload sem atividade há mais de sessionMaxAge a cada interval,
+// começando imediatamente, e registra em logger o que foi removido. Não
+// retorna; os servidores a executam em uma goroutine
+func RunJanitor(storage FileService, sessions *UploadSessions, interval, sessionMaxAge time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := RemoveExpired(storage, time.Now())
+		for _, name := range removed {
+			logger.Printf("Arquivo expirado removido: %s", name)
+		}
+		if err != nil {
+			logger.Printf("Erro ao remover arquivos expirados: %v", err)
+		}
+
+		if trash, ok := storage.(Trasher); ok {
+			purged, err := trash.PruneTrash()
+			for _, entry := range purged {
+				logger.Printf("Entrada %s da lixeira removida: %s", entry.ID, entry.Name)
+			}
+			if err != nil {
+				logger.Printf("Erro ao esvaziar a lixeira: %v", err)
+			}
+		}
+
+		if sessionMaxAge > 0 {
+			stale, err := sessions.RemoveStale(sessionMaxAge)
+			for _, id := range stale {
+				logger.Printf("Sessão de upload abandonada removida: %s", id)
+			}
+			if err != nil {
+				logger.Printf("Erro ao remover sessões de upload abandonadas: %v", err)
+			}
+		}
+		<-ticker.C
+	}
+}
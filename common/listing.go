@@ -0,0 +1,199 @@
+package common
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ListSort é a ordem das entradas retornadas por ListFiles
+type ListSort string
+
+const (
+	SortByName    ListSort = "name"     // Ordem alfabética, com o conteúdo de cada subdiretório logo após ele
+	SortBySize    ListSort = "size"     // Do menor para o maior; diretórios têm tamanho zero
+	SortByModTime ListSort = "mod_time" // Do mais antigo para o mais recente
+)
+
+// ParseListSort converte o nome de uma ordem, como usado no protocolo AMQP e
+// nos clientes, em ListSort. Um nome vazio é a ordem por nome
+func ParseListSort(s string) (ListSort, error) {
+	switch sort := ListSort(s); sort {
+	case "", SortByName:
+		return SortByName, nil
+	case SortBySize, SortByModTime:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: ordem desconhecida %q (use name, size ou mod_time)", ErrInvalidListOptions, s)
+	}
+}
+
+// listCursor é o conteúdo de um token de página: a ordem da listagem e a
+// última entrada da página anterior. A próxima página começa na primeira
+// entrada depois dela, então entradas criadas ou removidas entre as páginas
+// não fazem a listagem repetir nem pular as demais
+type listCursor struct {
+	Sort    ListSort  `json:"sort"`
+	Reverse bool      `json:"reverse,omitempty"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// check confere as opções de listagem que não dependem do armazenamento
+func (opts ListOptions) check() error {
+	if _, err := ParseListSort(string(opts.Sort)); err != nil {
+		return err
+	}
+	if opts.PageSize < 0 {
+		return fmt.Errorf("%w: tamanho de página negativo (%d)", ErrInvalidListOptions, opts.PageSize)
+	}
+	if _, err := path.Match(opts.Pattern, ""); err != nil {
+		return fmt.Errorf("%w: padrão %q inválido", ErrInvalidListOptions, opts.Pattern)
+	}
+	if _, err := ParseTagSelector(opts.Selector); err != nil {
+		return err
+	}
+	_, err := opts.cursor()
+	return err
+}
+
+// cursor decodifica opts.PageToken, retornando nil na primeira página
+func (opts ListOptions) cursor() (*listCursor, error) {
+	if opts.PageToken == "" {
+		return nil, nil
+	}
+
+	invalid := fmt.Errorf("%w: token de página inválido", ErrInvalidListOptions)
+	data, err := base64.RawURLEncoding.DecodeString(opts.PageToken)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalid
+	}
+
+	sort, _ := ParseListSort(string(opts.Sort))
+	if cursor.Sort != sort || cursor.Reverse != opts.Reverse {
+		return nil, fmt.Errorf("%w: token de página de uma listagem em outra ordem", ErrInvalidListOptions)
+	}
+	return &cursor, nil
+}
+
+// matches indica se o nome name, listado em opts.Dir, passa pelos filtros
+// opts.Prefix e opts.Pattern
+func (opts ListOptions) matches(name string) bool {
+	rel := name
+	if opts.Dir != "" {
+		rel = strings.TrimPrefix(name, opts.Dir+"/")
+	}
+	if !strings.HasPrefix(rel, opts.Prefix) {
+		return false
+	}
+	if opts.Pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(opts.Pattern, path.Base(name))
+	return ok
+}
+
+// compare ordena duas entradas na ordem de opts. O nome desempata as demais
+// ordens, então duas entradas diferentes nunca são iguais
+func (opts ListOptions) compare(a, b FileInfo) int {
+	c := 0
+	switch opts.Sort {
+	case SortBySize:
+		c = cmp.Compare(a.Size, b.Size)
+	case SortByModTime:
+		c = a.ModTime.Compare(b.ModTime)
+	}
+	if c == 0 {
+		c = comparePaths(a.Name, b.Name)
+	}
+	if opts.Reverse {
+		return -c
+	}
+	return c
+}
+
+// listPage monta a página de ListFiles selecionada por opts a partir dos
+// nomes listados, obtendo as informações de cada um com info. Na ordem por
+// nome só são consultados os nomes até o fim da página; nas demais, todos.
+// O seletor de tags é aplicado às informações, então com ele os nomes são
+// consultados até a página ter entradas suficientes que o atendam.
+// Nomes que info não encontra mais (ErrNotFound) foram removidos durante a
+// listagem e são ignorados. Retorna as entradas e o token da próxima página,
+// vazio na última
+func listPage(names []string, opts ListOptions, info func(name string) (FileInfo, error)) ([]FileInfo, string, error) {
+	opts.Sort, _ = ParseListSort(string(opts.Sort))
+	cursor, err := opts.cursor()
+	if err != nil {
+		return nil, "", err
+	}
+	selector, err := ParseTagSelector(opts.Selector)
+	if err != nil {
+		return nil, "", err
+	}
+
+	names = slices.DeleteFunc(names, func(name string) bool { return !opts.matches(name) })
+	slices.SortFunc(names, comparePaths)
+	if opts.Reverse {
+		slices.Reverse(names)
+	}
+
+	var files []FileInfo
+	for _, name := range names {
+		if opts.Sort == SortByName && cursor != nil && opts.compare(FileInfo{Name: name}, FileInfo{Name: cursor.Name}) <= 0 {
+			continue
+		}
+
+		file, err := info(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if selector != nil && (file.IsDir || !selector.Matches(file.Tags)) {
+			continue
+		}
+		files = append(files, file)
+
+		// Uma entrada além da página indica que há uma próxima
+		if opts.Sort == SortByName && opts.PageSize > 0 && len(files) > opts.PageSize {
+			break
+		}
+	}
+
+	if opts.Sort != SortByName {
+		slices.SortFunc(files, opts.compare)
+		if cursor != nil {
+			last := FileInfo{Name: cursor.Name, Size: cursor.Size, ModTime: cursor.ModTime}
+			files = slices.DeleteFunc(files, func(file FileInfo) bool { return opts.compare(file, last) <= 0 })
+		}
+	}
+
+	if opts.PageSize == 0 || len(files) <= opts.PageSize {
+		return files, "", nil
+	}
+	files = files[:opts.PageSize]
+	last := files[len(files)-1]
+	token, err := json.Marshal(listCursor{
+		Sort:    opts.Sort,
+		Reverse: opts.Reverse,
+		Name:    last.Name,
+		Size:    last.Size,
+		ModTime: last.ModTime,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao gerar token de página: %w", err)
+	}
+	return files, base64.RawURLEncoding.EncodeToString(token), nil
+}
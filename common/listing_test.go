@@ -0,0 +1,180 @@
+package common
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// listPages percorre as páginas de opts até a última, chamando between entre
+// uma página e a seguinte, e retorna as entradas de todas as páginas
+func listPages(t *testing.T, storage FileService, opts ListOptions, between func(page int)) []FileInfo {
+	t.Helper()
+
+	var files []FileInfo
+	for page := 0; ; page++ {
+		entries, token, err := storage.ListFiles(opts)
+		if err != nil {
+			t.Fatalf("ListFiles(%+v): %v", opts, err)
+		}
+		if len(entries) > opts.PageSize {
+			t.Fatalf("página %d com %d entradas, esperado até %d", page, len(entries), opts.PageSize)
+		}
+		files = append(files, entries...)
+		if token == "" {
+			return files
+		}
+		if page > 100 {
+			t.Fatalf("listagem não terminou")
+		}
+		between(page)
+		opts.PageToken = token
+	}
+}
+
+// TestListPagination pagina diretórios em cada ordem enquanto arquivos são
+// criados e removidos entre as páginas e confere que nenhuma entrada é
+// repetida, que as entradas vêm na ordem pedida e que nenhuma das que
+// existiram durante toda a listagem é pulada
+func TestListPagination(t *testing.T) {
+	backends := map[string]func(t *testing.T) FileService{
+		"local": func(t *testing.T) FileService {
+			ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{})
+			if err != nil {
+				t.Fatalf("NewLocalStorage: %v", err)
+			}
+			return ls
+		},
+		"index": func(t *testing.T) FileService {
+			ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{Index: true})
+			if err != nil {
+				t.Fatalf("NewLocalStorage: %v", err)
+			}
+			return ls
+		},
+		"memory": func(t *testing.T) FileService {
+			return NewMemoryStorage(MemoryStorageOptions{})
+		},
+	}
+
+	orders := map[string]ListOptions{
+		"name":          {Sort: SortByName},
+		"name-reverse":  {Sort: SortByName, Reverse: true},
+		"size":          {Sort: SortBySize},
+		"mtime-reverse": {Sort: SortByModTime, Reverse: true},
+		"recursive":     {Sort: SortByName, Recursive: true},
+		"pattern":       {Sort: SortBySize, Pattern: "*.txt"},
+		"prefix":        {Sort: SortByName, Prefix: "f1"},
+	}
+
+	for backend, open := range backends {
+		for order, opts := range orders {
+			t.Run(backend+"/"+order, func(t *testing.T) {
+				testListPagination(t, open(t), opts)
+			})
+		}
+	}
+}
+
+// testListPagination é a listagem de TestListPagination em uma ordem
+func testListPagination(t *testing.T, storage FileService, opts ListOptions) {
+	// Tamanhos repetidos para que o nome desempate a ordem por tamanho
+	var initial []string
+	for i := range 20 {
+		name := fmt.Sprintf("docs/f%02d.txt", i)
+		if i%5 == 0 {
+			name = fmt.Sprintf("docs/f%02d.dat", i)
+		}
+		if i%7 == 0 {
+			name = fmt.Sprintf("docs/sub%02d/g.txt", i)
+		}
+		uploadString(t, storage, name, strings.Repeat("x", i%4))
+		initial = append(initial, name)
+	}
+
+	// Os arquivos removidos entre as páginas e os criados, antes e depois
+	// da posição da listagem em cada ordem
+	removed := map[string]bool{"docs/f03.txt": true, "docs/f17.txt": true}
+	between := func(page int) {
+		for name := range removed {
+			storage.DeleteFile(name)
+		}
+		uploadString(t, storage, fmt.Sprintf("docs/f%02da.txt", page), "")
+		uploadString(t, storage, fmt.Sprintf("docs/f%02db.txt", 19-page), "xxxx")
+	}
+
+	opts.Dir = "docs"
+	opts.PageSize = 3
+	files := listPages(t, storage, opts, between)
+
+	seen := make(map[string]bool)
+	for i, file := range files {
+		if seen[file.Name] {
+			t.Errorf("entrada repetida: %s", file.Name)
+		}
+		seen[file.Name] = true
+		if i > 0 && opts.compare(files[i-1], file) >= 0 {
+			t.Errorf("%s listada depois de %s", file.Name, files[i-1].Name)
+		}
+	}
+
+	// Toda entrada criada antes da listagem e não removida deve aparecer; um
+	// arquivo em um subdiretório aparece como o subdiretório se a listagem
+	// não for recursiva
+	want := 0
+	for _, name := range initial {
+		if !opts.Recursive && strings.Count(name, "/") > 1 {
+			name = name[:strings.LastIndex(name, "/")]
+		}
+		if removed[name] || !opts.matches(name) {
+			continue
+		}
+		want++
+		if !seen[name] {
+			t.Errorf("entrada pulada: %s", name)
+		}
+	}
+	// Na listagem recursiva os subdiretórios também são entradas
+	if opts.Recursive {
+		for _, name := range initial {
+			if dir := name[:strings.LastIndex(name, "/")]; dir != "docs" && !seen[dir] {
+				t.Errorf("diretório pulado: %s", dir)
+			}
+		}
+	}
+	if want == 0 {
+		t.Fatalf("nenhuma entrada esperada com %+v", opts)
+	}
+}
+
+// TestListInvalidPageToken confere que tokens de página corrompidos ou de
+// uma listagem em outra ordem são rejeitados com ErrInvalidListOptions
+func TestListInvalidPageToken(t *testing.T) {
+	ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	for i := range 5 {
+		uploadString(t, ls, fmt.Sprintf("f%d.txt", i), "x")
+	}
+
+	_, token, err := ls.ListFiles(ListOptions{PageSize: 2})
+	if err != nil || token == "" {
+		t.Fatalf("ListFiles: %q, %v", token, err)
+	}
+
+	invalid := []ListOptions{
+		{PageSize: 2, PageToken: "não é base64!"},
+		{PageSize: 2, PageToken: base64.RawURLEncoding.EncodeToString([]byte("não é json"))},
+		{PageSize: 2, PageToken: token[:len(token)/2]},
+		{PageSize: 2, PageToken: token, Sort: SortBySize},
+		{PageSize: 2, PageToken: token, Reverse: true},
+	}
+	for _, opts := range invalid {
+		if _, _, err := ls.ListFiles(opts); !errors.Is(err, ErrInvalidListOptions) {
+			t.Errorf("ListFiles(%+v): %v, esperado ErrInvalidListOptions", opts, err)
+		}
+	}
+}
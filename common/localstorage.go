@@ -1,23 +1,115 @@
 package common
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 // LocalStorage implementa FileService usando armazenamento local em disco
+// Os nomes com "/" são mapeados para subdiretórios do diretório base. Todo
+// acesso ao disco passa por um os.Root aberto sobre o diretório base, então
+// nenhuma operação alcança arquivos fora dele, mesmo que alguém crie links
+// simbólicos no volume. Cada nome tem seu próprio lock, então operações sobre
+// arquivos diferentes não bloqueiam umas às outras
 type LocalStorage struct {
-	baseDir string
-	mu      sync.RWMutex
+	baseDir       string
+	root          *os.Root
+	locks         *lockTable
+	infoCache     sync.Map // nome -> localCachedInfo calculado por describe
+	versionPolicy VersionPolicy
+
+	// trashRetention é o tempo que os arquivos removidos e substituídos
+	// ficam na lixeira; zero desativa a lixeira
+	trashRetention time.Duration
+
+	// compressionLevel é o nível do gzip usado nos uploads; zero desativa
+	compressionLevel int
+
+	// index, se não for nil, guarda as informações dos arquivos para as
+	// listagens (ver LocalStorageOptions.Index)
+	index *fileIndex
+
+	// readInfo, se definida, substitui a leitura do conteúdo em fileInfo;
+	// usada pelo DedupStorage, cujos arquivos são referências para o conteúdo
+	readInfo func(name string) (FileInfo, error)
+}
+
+// localCachedInfo é uma entrada do infoCache do LocalStorage
+type localCachedInfo struct {
+	info     FileInfo
+	stat     os.FileInfo // Arquivo descrito, para distingui-lo de outro com o mesmo tamanho e data
+	recorded bool        // O checksum está registrado em ChecksumsDirName
+
+	compressed bool // O arquivo foi compactado pelo armazenamento
+}
+
+// LocalStorageOptions configura um LocalStorage
+type LocalStorageOptions struct {
+	// Versions define se e por quanto tempo as versões substituídas por novos
+	// uploads são mantidas; o valor zero desativa o versionamento
+	Versions VersionPolicy
+
+	// CompressionLevel compacta com gzip, neste nível (de 1, mais rápido, a
+	// MaxCompressionLevel, menor), o conteúdo dos uploads cujo tipo ainda não
+	// é compactado; o valor zero desativa a compressão. Arquivos compactados
+	// são descompactados no download qualquer que seja esta opção
+	CompressionLevel int
+
+	// TrashRetention guarda na lixeira, por este tempo, os arquivos removidos
+	// e os substituídos por uploads sem versionamento ativo, que podem ser
+	// restaurados até lá; o valor zero desativa a lixeira
+	TrashRetention time.Duration
+
+	// Index mantém em IndexDirName um índice com as informações dos arquivos
+	// (tamanho, checksum, data de modificação, expiração e tags), atualizado
+	// junto com cada operação, e faz ListFiles consultá-lo em vez de ler os
+	// diretórios. Alterações feitas no diretório por fora do LocalStorage só
+	// aparecem no índice depois de Reindex
+	Index bool
+}
+
+// tempFilePrefix identifica arquivos temporários de uploads em andamento
+const tempFilePrefix = ".tmp-"
+
+// reservedNames são entradas do diretório base usadas internamente
+var reservedNames = map[string]bool{
+	UploadsDirName:   true,
+	LocksDirName:     true,
+	VersionsDirName:  true,
+	ChecksumsDirName: true,
+	BlobsDirName:     true,
+	KeysDirName:      true,
+	TrashDirName:     true,
+	IndexDirName:     true,
 }
 
 // NewLocalStorage cria uma nova instância de LocalStorage
 // Cria o diretório base se ele não existir
-func NewLocalStorage(baseDir string) (*LocalStorage, error) {
+func NewLocalStorage(baseDir string, opts LocalStorageOptions) (*LocalStorage, error) {
+	if opts.CompressionLevel < 0 || opts.CompressionLevel > MaxCompressionLevel {
+		return nil, fmt.Errorf("nível de compressão inválido: %d (use 0 a %d)", opts.CompressionLevel, MaxCompressionLevel)
+	}
+
 	ls := &LocalStorage{
-		baseDir: baseDir,
+		baseDir:          baseDir,
+		locks:            newLockTable(),
+		versionPolicy:    opts.Versions,
+		trashRetention:   opts.TrashRetention,
+		compressionLevel: opts.CompressionLevel,
 	}
 
 	// Garante que o diretório existe
@@ -25,13 +117,28 @@ func NewLocalStorage(baseDir string) (*LocalStorage, error) {
 		return nil, fmt.Errorf("falha ao criar diretório base: %w", err)
 	}
 
+	root, err := os.OpenRoot(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao abrir diretório base: %w", err)
+	}
+	ls.root = root
+
+	// Remove restos de uploads interrompidos por quedas anteriores
+	if err := ls.cleanupTempFiles(); err != nil {
+		return nil, fmt.Errorf("falha ao limpar arquivos temporários: %w", err)
+	}
+
+	if opts.Index {
+		if ls.index, err = openIndex(root, ls.scanTree); err != nil {
+			return nil, fmt.Errorf("falha ao abrir o índice: %w", err)
+		}
+	}
+
 	return ls, nil
 }
 
 // ensureDir cria o diretório base se ele não existir
-// NOTA: Esta função assume que o mutex já está travado pelo chamador
 func (ls *LocalStorage) ensureDir() error {
-	// Não trava o mutex aqui porque o chamador já deve ter travado
 	if err := os.MkdirAll(ls.baseDir, 0755); err != nil {
 		return fmt.Errorf("erro ao criar diretório %s: %w", ls.baseDir, err)
 	}
@@ -39,83 +146,1042 @@ func (ls *LocalStorage) ensureDir() error {
 	return nil
 }
 
-// ListFiles retorna uma lista de nomes de arquivos disponíveis
-func (ls *LocalStorage) ListFiles() ([]string, error) {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+// ListFiles retorna as informações dos arquivos e diretórios de opts.Dir
+// Não trava nenhum arquivo: a leitura do diretório é segura porque os
+// arquivos só aparecem ou são substituídos por rename. Os diretórios são
+// lidos por inteiro, mas na ordem por nome só as entradas da página são
+// consultadas. Com o índice ativo, as entradas vêm dele e o disco não é lido
+func (ls *LocalStorage) ListFiles(opts ListOptions) ([]FileInfo, string, error) {
+	if err := opts.check(); err != nil {
+		return nil, "", err
+	}
+	if opts.Dir != "" {
+		if err := validateName(opts.Dir); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if ls.index != nil {
+		files, err := ls.index.list(opts.Dir, opts.Recursive)
+		if err != nil {
+			return nil, "", err
+		}
+		return listPage(slices.Collect(maps.Keys(files)), opts, func(name string) (FileInfo, error) {
+			info := files[name]
+			info.Tags = cloneTags(info.Tags)
+			return info, nil
+		})
+	}
 
-	entries, err := os.ReadDir(ls.baseDir)
+	if opts.Dir != "" {
+		dirPath, err := ls.resolve(opts.Dir)
+		if err != nil {
+			return nil, "", err
+		}
+		stat, err := ls.root.Stat(dirPath)
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("%w: %s", ErrNotFound, opts.Dir)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("erro ao consultar diretório %s: %w", opts.Dir, err)
+		}
+		if !stat.IsDir() {
+			return nil, "", fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, opts.Dir)
+		}
+	}
+
+	names, err := ls.listDir(opts.Dir, opts.Recursive, nil)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao ler diretório %s: %w", ls.baseDir, err)
+		return nil, "", err
 	}
+	return listPage(names, opts, ls.listedInfo)
+}
 
-	var files []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			files = append(files, entry.Name())
+// Reindex reconstrói o índice lendo todo o diretório base, para incluir
+// alterações feitas por fora do LocalStorage, e retorna o número de entradas
+// indexadas. Falha se o índice não estiver ativo
+func (ls *LocalStorage) Reindex() (int, error) {
+	if ls.index == nil {
+		return 0, errors.New("o índice não está ativo")
+	}
+	return ls.index.rebuild()
+}
+
+// scanTree lê do disco, para o índice, as informações de name, dos
+// diretórios que o contêm e, se name for um diretório, de todo o seu
+// conteúdo; o nome vazio lê todo o diretório base
+func (ls *LocalStorage) scanTree(name string) ([]FileInfo, error) {
+	files, err := ls.indexDirs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{name}
+	if name == "" || slices.ContainsFunc(files, func(file FileInfo) bool { return file.Name == name }) {
+		if names, err = ls.listDir(name, true, nil); err != nil {
+			return nil, err
 		}
 	}
 
+	for _, name := range names {
+		info, err := ls.listedInfo(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, info)
+	}
 	return files, nil
 }
 
-// UploadFile faz upload de um arquivo com o nome e dados especificados
-func (ls *LocalStorage) UploadFile(name string, data []byte) error {
-	// Validação básica do nome do arquivo
-	if name == "" {
-		return fmt.Errorf("nome do arquivo não pode ser vazio")
+// indexDirs retorna, para o índice, as informações dos diretórios que
+// contêm os nomes e dos próprios nomes que forem diretórios, cujas datas de
+// modificação mudam quando uma operação altera o seu conteúdo
+func (ls *LocalStorage) indexDirs(names ...string) ([]FileInfo, error) {
+	var dirs []FileInfo
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for dir := name; dir != "." && dir != "" && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+
+			stat, err := ls.root.Lstat(ls.path(dir))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("erro ao consultar %s: %w", dir, err)
+			}
+			if stat.IsDir() {
+				dirs = append(dirs, FileInfo{Name: dir, ModTime: stat.ModTime(), IsDir: true})
+			}
+		}
 	}
+	return dirs, nil
+}
 
-	// Previne path traversal attacks
-	if filepath.Base(name) != name {
-		return fmt.Errorf("nome do arquivo inválido: não pode conter caminhos relativos")
+// listDir acrescenta a names os nomes das entradas do diretório dir e, se
+// recursive, os de seus subdiretórios. Links simbólicos, arquivos
+// temporários e as áreas internas do diretório base não são listados
+func (ls *LocalStorage) listDir(dir string, recursive bool, names []string) ([]string, error) {
+	entries, err := readDir(ls.root, ls.path(dir))
+	if os.IsNotExist(err) {
+		// Diretório removido durante a listagem
+		return names, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler diretório %s: %w", dir, err)
 	}
 
-	ls.mu.Lock()
-	defer ls.mu.Unlock()
+	for _, entry := range entries {
+		if isTempFile(entry.Name()) || (dir == "" && reservedNames[entry.Name()]) {
+			continue
+		}
+		name := path.Join(dir, entry.Name())
 
-	// Garante que o diretório existe antes de escrever
-	if err := ls.ensureDir(); err != nil {
-		return fmt.Errorf("erro ao garantir diretório: %w", err)
+		if entry.IsDir() {
+			names = append(names, name)
+			if recursive {
+				if names, err = ls.listDir(name, true, names); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if entry.Type().IsRegular() {
+			names = append(names, name)
+		}
 	}
 
-	filePath := filepath.Join(ls.baseDir, name)
+	return names, nil
+}
 
-	// Escreve o arquivo
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("erro ao escrever arquivo %s: %w", filePath, err)
+// listedInfo retorna as informações de uma entrada encontrada por listDir,
+// ou ErrNotFound se ela foi removida depois da leitura do diretório
+func (ls *LocalStorage) listedInfo(name string) (FileInfo, error) {
+	stat, err := ls.root.Lstat(ls.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return FileInfo{}, fmt.Errorf("erro ao consultar %s: %w", name, err)
+	}
+	if err == nil && stat.IsDir() {
+		return FileInfo{Name: name, ModTime: stat.ModTime(), IsDir: true}, nil
 	}
 
-	return nil
+	// Um link simbólico ou outro tipo de arquivo no lugar da entrada
+	// também não é listado
+	if err == nil && stat.Mode().IsRegular() {
+		var info FileInfo
+		if info, err = ls.fileInfo(name, false); !os.IsNotExist(err) {
+			return info, err
+		}
+	}
+	return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
 }
 
-// DownloadFile faz download de um arquivo pelo nome e retorna seus dados
-func (ls *LocalStorage) DownloadFile(name string) ([]byte, error) {
-	// Validação básica do nome do arquivo
+// UploadFile grava o conteúdo lido de r no arquivo especificado
+// Os dados são gravados em um arquivo temporário, sincronizados em disco e só
+// então renomeados para o nome final. Leitores nunca veem um arquivo parcial:
+// ou abrem a versão anterior completa ou a nova versão completa. As condições
+// de opts são avaliadas com o lock do arquivo, imediatamente antes do rename
+func (ls *LocalStorage) UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+	if err := validateTags(opts.Tags); err != nil {
+		return FileInfo{}, err
+	}
+
+	// O arquivo temporário fica no diretório base, no mesmo sistema de
+	// arquivos do destino, para que o rename seja atômico
+	tmp, tmpPath, err := createTemp(ls.root, tempFilePrefix)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+
+	// O flock sobre o temporário indica a outros processos que o upload está
+	// em andamento; ele é liberado só depois do rename, ao fechar o arquivo
+	defer tmp.Close()
+	if err := lockFile(tmp, true); err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, fmt.Errorf("erro ao travar arquivo temporário: %w", err)
+	}
+
+	// O início do conteúdo é lido antes da cópia para detectar seu tipo, que
+	// decide se o conteúdo é compactado. Conteúdos que cabem inteiros nesse
+	// início não são compactados: o cabeçalho do gzip ocuparia mais do que a
+	// compressão economizaria
+	head := make([]byte, sniffLen)
+	headLen, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+	}
+	complete := err != nil
+	head = head[:headLen]
+	contentType := DetectContentType(name, head)
+
+	var zw *gzip.Writer
+	var dst io.Writer = tmp
+	if ls.compressionLevel > 0 && !complete && compressible(contentType) {
+		if zw, err = newCompressWriter(tmp, ls.compressionLevel); err != nil {
+			ls.root.Remove(tmpPath)
+			return FileInfo{}, fmt.Errorf("erro ao iniciar compressão: %w", err)
+		}
+		dst = zw
+	}
+
+	// Copia os dados em blocos, calculando o checksum do conteúdo original;
+	// em caso de erro descarta o temporário
+	hash := sha256.New()
+	n, err := io.CopyBuffer(io.MultiWriter(dst, hash), io.MultiReader(bytes.NewReader(head), r), make([]byte, ChunkSize))
+	if err == nil && zw != nil {
+		err = zw.Close()
+		if err == nil {
+			err = completeCompressed(tmp, n, hash.Sum(nil))
+		}
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	var stat os.FileInfo
+	if err == nil {
+		stat, err = tmp.Stat()
+	}
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	info := FileInfo{
+		Name:        name,
+		Size:        n,
+		ModTime:     stat.ModTime(),
+		SHA256:      sum,
+		ContentType: contentType,
+		ETag:        sum,
+		StoredSize:  stat.Size(),
+		ExpiresAt:   opts.expiresAt(stat.ModTime()),
+		Tags:        cloneTags(opts.Tags),
+	}
+
+	unlock, err := ls.lockPaths(true, name)
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+	defer unlock()
+
+	tx, err := ls.index.begin(name)
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+	defer tx.abort()
+
+	filePath, err := ls.prepareTarget(name)
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+
+	if opts.conditional() {
+		if err := ls.checkPreconditions(name, opts); err != nil {
+			ls.root.Remove(tmpPath)
+			return FileInfo{}, err
+		}
+	}
+
+	// Com versionamento, a versão atual vai para o histórico antes de ser
+	// substituída; sem ele, vai para a lixeira, se ativa
+	versionPath, err := ls.archiveVersion(name, filePath)
+	if err == nil && versionPath == "" {
+		versionPath, err = ls.trashFile(name, filePath, true)
+	}
+	if err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+
+	// O registro da versão atual é removido antes do rename e o da nova
+	// versão só é gravado depois, então uma queda no meio do caminho deixa o
+	// arquivo sem registro, e nunca com o registro de outro conteúdo
+	if err := ls.removeChecksums(name); err != nil {
+		ls.root.Remove(tmpPath)
+		return FileInfo{}, err
+	}
+
+	if err := renameInRoot(ls.root, tmpPath, filePath); err != nil {
+		ls.root.Remove(tmpPath)
+		if versionPath != "" {
+			renameInRoot(ls.root, versionPath, filePath)
+		}
+		return FileInfo{}, fmt.Errorf("erro ao mover arquivo para %s: %w", filePath, err)
+	}
+
+	// O rename preserva a data de modificação do temporário, que identifica
+	// o arquivo no registro
+	compressed := zw != nil
+	err = ls.writeChecksum(name, checksumRecord{
+		SHA256:     sum,
+		Size:       n,
+		StoredSize: stat.Size(),
+		ModTime:    stat.ModTime(),
+		ExpiresAt:  info.ExpiresAt,
+		Tags:       info.Tags,
+		Compressed: &compressed,
+	})
+	ls.infoCache.Store(name, localCachedInfo{info: info, stat: stat, recorded: err == nil, compressed: compressed})
+
+	// O arquivo já está no lugar, então entra no índice mesmo sem o registro
+	if indexErr := ls.commitIndex(tx, indexRecord{Put: []FileInfo{info}}, name); err == nil {
+		err = indexErr
+	}
+	if err != nil {
+		return info, err
+	}
+
+	// Sincroniza o diretório para que o rename sobreviva a uma queda do sistema
+	if err := syncDir(ls.root, filepath.Dir(filePath)); err != nil {
+		return info, err
+	}
+
+	if versionPath != "" {
+		if err := ls.pruneVersions(name); err != nil {
+			return info, err
+		}
+	}
+
+	return info, nil
+}
+
+// checkPreconditions avalia as condições de opts contra a versão atual de name
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) checkPreconditions(name string, opts UploadOptions) error {
+	current, err := ls.fileInfo(name, true)
+	if os.IsNotExist(err) {
+		return opts.check(name, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return opts.check(name, &current)
+}
+
+// DownloadFile abre para leitura o trecho de um arquivo selecionado por opts
+// Como os uploads substituem arquivos via rename, o arquivo aberto continua
+// apontando para uma versão completa mesmo que outro upload termine durante a
+// leitura. O conteúdo inteiro é conferido com o checksum registrado no
+// upload; um arquivo gravado antes dos registros existirem é registrado
+// agora, e só os próximos downloads são conferidos. Um trecho de um arquivo
+// sem compressão é lido a partir da sua posição, sem ler o início do arquivo
+func (ls *LocalStorage) DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	unlock, err := ls.lockPaths(false, name)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	defer unlock()
+
+	filePath, err := ls.resolve(name)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	file, err := ls.root.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir arquivo %s: %w", filePath, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao consultar arquivo %s: %w", filePath, err)
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+
+	info, compressed, err := ls.describe(name, file, stat, true)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, err
+	}
+	offset, length, err := opts.Range(info.Size)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao posicionar arquivo %s: %w", filePath, err)
+	}
+	content, stored, err := openContent(file, &compressed)
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+	}
+
+	if !opts.Partial() {
+		return readCloser{verifyStored(content, name, info.Size, info.SHA256), file}, info, nil
+	}
+
+	// Um arquivo compactado é descompactado e descartado até o trecho
+	if stored == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, content, offset)
+	}
+	if err != nil {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("erro ao posicionar arquivo %s: %w", filePath, err)
+	}
+
+	return readCloser{io.LimitReader(content, length), file}, info, nil
+}
+
+// DeleteFile remove um arquivo ou um diretório vazio
+// Com a lixeira ativa, o arquivo é movido para ela, de onde pode ser restaurado
+func (ls *LocalStorage) DeleteFile(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	// O lock exclusivo sobre um diretório aguarda as operações em andamento
+	// dentro dele, que mantêm locks compartilhados sobre seus diretórios
+	unlock, err := ls.lockPaths(true, name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath, err := ls.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ls.index.begin(name)
+	if err != nil {
+		return err
+	}
+	defer tx.abort()
+
+	stat, err := ls.root.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao consultar arquivo %s: %w", filePath, err)
+	}
+	if stat.IsDir() {
+		empty, err := isEmptyDir(ls.root, filePath)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, name)
+		}
+	}
+
+	// Com a lixeira ativa, o arquivo é movido para uma entrada da lixeira em
+	// vez de removido. A entrada é criada antes de o registro ser removido,
+	// para guardar as informações registradas no upload
+	trashDir, err := ls.newTrashEntry(name, filePath, false)
+	if err != nil {
+		return err
+	}
+
+	// O registro é removido antes do arquivo para que uma queda entre as duas
+	// remoções não deixe um registro sem arquivo
+	if err := ls.removeChecksums(name); err != nil {
+		if trashDir != "" {
+			removeAll(ls.root, trashDir)
+		}
+		return err
+	}
+
+	if trashDir != "" {
+		err = renameInRoot(ls.root, filePath, filepath.Join(trashDir, trashContentName))
+	} else {
+		err = ls.root.Remove(filePath)
+	}
+	if err != nil {
+		if trashDir != "" {
+			removeAll(ls.root, trashDir)
+		}
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return fmt.Errorf("erro ao remover arquivo %s: %w", filePath, err)
+	}
+	ls.infoCache.Delete(name)
+	if err := ls.commitIndex(tx, indexRecord{Delete: []string{name}}, name); err != nil {
+		return err
+	}
+
+	// O histórico de versões é removido junto com o arquivo
+	if err := removeAll(ls.root, ls.versionsDir(name)); err != nil {
+		return fmt.Errorf("erro ao remover versões de %s: %w", name, err)
+	}
+
+	return syncDir(ls.root, filepath.Dir(filePath))
+}
+
+// RenameFile renomeia ou move um arquivo ou diretório
+// Falha com ErrAlreadyExists se já existir um arquivo ou diretório com o
+// nome de destino. Os diretórios do caminho de destino são criados se
+// necessário
+func (ls *LocalStorage) RenameFile(oldName, newName string) error {
+	if err := validateName(oldName); err != nil {
+		return err
+	}
+	if err := validateName(newName); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+	if strings.HasPrefix(newName, oldName+"/") {
+		return fmt.Errorf("%w: %s não pode ser movido para dentro de si mesmo", ErrInvalidName, oldName)
+	}
+
+	unlock, err := ls.lockPaths(true, oldName, newName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	oldPath, err := ls.resolve(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err := ls.root.Lstat(oldPath); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, oldName)
+	}
+
+	// Um diretório no destino também é um nome em uso, e não ErrIsDirectory
+	// como em prepareTarget
+	newPath, err := ls.resolve(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := ls.root.Lstat(newPath); err == nil {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+
+	tx, err := ls.index.begin(oldName, newName)
+	if err != nil {
+		return err
+	}
+	defer tx.abort()
+
+	if _, err := ls.prepareTarget(newName); err != nil {
+		return err
+	}
+
+	if err := renameInRoot(ls.root, oldPath, newPath); err != nil {
+		return fmt.Errorf("erro ao renomear %s para %s: %w", oldName, newName, err)
+	}
+	if err := ls.commitIndex(tx, indexRecord{Move: &indexMove{From: oldName, To: newName}}, oldName, newName); err != nil {
+		return err
+	}
+
+	// O histórico de versões e os checksums acompanham o arquivo ou diretório
+	if err := ls.moveVersions(oldName, newName); err != nil {
+		return err
+	}
+	if err := ls.moveChecksums(oldName, newName); err != nil {
+		return err
+	}
+
+	// Descarta o cache do arquivo ou de todo o conteúdo do diretório movido
+	ls.infoCache.Range(func(key, _ any) bool {
+		if k := key.(string); k == oldName || strings.HasPrefix(k, oldName+"/") {
+			ls.infoCache.Delete(k)
+		}
+		return true
+	})
+
+	if err := syncDir(ls.root, filepath.Dir(oldPath)); err != nil {
+		return err
+	}
+	return syncDir(ls.root, filepath.Dir(newPath))
+}
+
+// StatFile retorna as informações de um arquivo ou diretório
+func (ls *LocalStorage) StatFile(name string) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+
+	unlock, err := ls.lockPaths(false, name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer unlock()
+
+	filePath, err := ls.resolve(name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	stat, err := ls.root.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+	if stat.IsDir() {
+		return FileInfo{Name: name, ModTime: stat.ModTime(), IsDir: true}, nil
+	}
+
+	info, err := ls.fileInfo(name, true)
+	if os.IsNotExist(err) {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return info, err
+}
+
+// CreateDirectory cria um diretório e os diretórios intermediários que ainda
+// não existirem
+func (ls *LocalStorage) CreateDirectory(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	unlock, err := ls.lockPaths(true, name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dirPath, err := ls.resolve(name)
+	if err != nil {
+		return err
+	}
+	if _, err := ls.root.Lstat(dirPath); err == nil {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, name)
+	}
+
+	tx, err := ls.index.begin(name)
+	if err != nil {
+		return err
+	}
+	defer tx.abort()
+
+	if err := mkdirAll(ls.root, dirPath); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", name, err)
+	}
+	if err := ls.commitIndex(tx, indexRecord{}, name); err != nil {
+		return err
+	}
+
+	return syncDir(ls.root, filepath.Dir(dirPath))
+}
+
+// fileInfo monta as informações de um arquivo com describe
+// NOTA: record exige que o chamador tenha obtido o lock de name com lockPaths
+func (ls *LocalStorage) fileInfo(name string, record bool) (FileInfo, error) {
+	info, _, err := ls.storedInfo(name, record)
+	return info, err
+}
+
+// storedInfo é fileInfo, indicando também se o arquivo foi compactado pelo
+// armazenamento, para quem o move para as versões ou a lixeira
+// NOTA: record exige que o chamador tenha obtido o lock de name com lockPaths
+func (ls *LocalStorage) storedInfo(name string, record bool) (FileInfo, bool, error) {
+	if ls.readInfo != nil {
+		info, err := ls.readInfo(name)
+		return info, false, err
+	}
+
+	// Stat e leitura usam o mesmo descritor para descreverem a mesma versão
+	// do arquivo, mesmo que um upload o substitua no meio da consulta
+	file, err := ls.root.Open(ls.path(name))
+	if err != nil {
+		return FileInfo{}, false, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return FileInfo{}, false, fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+
+	return ls.describe(name, file, stat, record)
+}
+
+// describe monta as informações do arquivo name, aberto em file e descrito
+// por stat. O checksum vem do registro gravado no upload ou, na falta dele,
+// do cabeçalho de um arquivo compactado; um arquivo sem nenhum dos dois é
+// lido inteiro para calculá-lo. A expiração e as tags só vêm do registro. O
+// tipo do conteúdo exige ler seu início, então as informações ficam em cache
+// e só são montadas de novo quando o tamanho ou a data de modificação mudam.
+// Se record, um checksum ainda não registrado é gravado para conferir os
+// próximos downloads
+// Também indica se o arquivo foi compactado pelo armazenamento, o que vem
+// do registro sempre que ele existir (ver openContent)
+// NOTA: record exige que o chamador tenha obtido o lock de name com lockPaths
+func (ls *LocalStorage) describe(name string, file *os.File, stat os.FileInfo, record bool) (FileInfo, bool, error) {
+	info := FileInfo{
+		Name:       name,
+		Size:       stat.Size(),
+		ModTime:    stat.ModTime(),
+		StoredSize: stat.Size(),
+	}
+
+	recorded, compressed := false, false
+	if cached, ok := ls.infoCache.Load(name); ok {
+		c := cached.(localCachedInfo)
+		// A data de modificação tem a resolução do relógio do sistema de
+		// arquivos, então outro processo pode ter gravado, no mesmo
+		// instante, um conteúdo do mesmo tamanho; o arquivo é comparado
+		if c.info.StoredSize == info.StoredSize && c.info.ModTime.Equal(info.ModTime) && os.SameFile(c.stat, stat) {
+			if c.recorded || !record {
+				return c.info, c.compressed, nil
+			}
+			// Falta apenas registrar o checksum calculado antes
+			info, compressed = c.info, c.compressed
+		}
+	}
+
+	if info.SHA256 == "" {
+		// Sem registro, checksum.Compressed é nil e o cabeçalho decide
+		checksum, ok := ls.readChecksum(name, stat)
+		content, stored, err := openContent(file, checksum.Compressed)
+		if err != nil {
+			return FileInfo{}, false, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+		}
+		recorded, compressed = ok, stored != nil
+
+		head := make([]byte, sniffLen)
+		n, err := io.ReadFull(content, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return FileInfo{}, false, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+		}
+		head = head[:n]
+
+		var sum string
+		if recorded {
+			info.Size = checksum.Size
+			info.ExpiresAt = checksum.ExpiresAt
+			info.Tags = checksum.Tags
+			sum = checksum.SHA256
+		} else if stored != nil {
+			info.Size = stored.Size
+			sum = stored.SHA256
+		} else if sum, _, err = Checksum(io.MultiReader(bytes.NewReader(head), content)); err != nil {
+			return FileInfo{}, false, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+		}
+
+		info.SHA256 = sum
+		info.ETag = sum
+		info.ContentType = DetectContentType(name, head)
+	}
+
+	if record && !recorded {
+		err := ls.writeChecksum(name, checksumRecord{
+			SHA256:     info.SHA256,
+			Size:       info.Size,
+			StoredSize: stat.Size(),
+			ModTime:    stat.ModTime(),
+			ExpiresAt:  info.ExpiresAt,
+			Tags:       info.Tags,
+			Compressed: &compressed,
+		})
+		if err != nil {
+			return FileInfo{}, false, err
+		}
+		recorded = true
+	}
+
+	ls.infoCache.Store(name, localCachedInfo{info: info, stat: stat, recorded: recorded, compressed: compressed})
+	return info, compressed, nil
+}
+
+// lockPaths trava os nomes em names, de forma exclusiva ou compartilhada, e os
+// diretórios que os contêm de forma compartilhada, impedindo que sejam
+// removidos ou movidos durante a operação. Os locks são obtidos sempre em
+// ordem alfabética para evitar deadlock entre operações concorrentes
+func (ls *LocalStorage) lockPaths(exclusive bool, names ...string) (func(), error) {
+	keys, modes := lockOrder(exclusive, names)
+
+	releases := make([]func(), 0, len(keys))
+	releaseAll := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+
+	for _, key := range keys {
+		release, err := ls.lock(key, modes[key])
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+
+	return releaseAll, nil
+}
+
+// lock trava o nome name dentro do processo, pela tabela de locks, e entre
+// processos, por flock. Os arquivos de lock ficam em LocksDirName e são
+// removidos por quem os libera sem outros à espera (ver fileLock.Release). Eles
+// são nomeados pelo hash do nome, o que mantém o diretório de locks plano
+// qualquer que seja a profundidade do caminho
+func (ls *LocalStorage) lock(name string, exclusive bool) (func(), error) {
+	acquire := ls.locks.RLock
+	if exclusive {
+		acquire = ls.locks.Lock
+	}
+	release := acquire(name)
+
+	sum := sha256.Sum256([]byte(name))
+	fl, err := acquireFileLock(ls.root, filepath.Join(LocksDirName, hex.EncodeToString(sum[:])+".lock"), exclusive)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return func() {
+		fl.Release()
+		release()
+	}, nil
+}
+
+// path retorna o caminho relativo ao diretório base correspondente a name,
+// sem verificações
+func (ls *LocalStorage) path(name string) string {
 	if name == "" {
-		return nil, fmt.Errorf("nome do arquivo não pode ser vazio")
+		return "."
 	}
+	return filepath.FromSlash(name)
+}
 
-	// Previne path traversal attacks
-	if filepath.Base(name) != name {
-		return nil, fmt.Errorf("nome do arquivo inválido: não pode conter caminhos relativos")
+// resolve retorna o caminho relativo ao diretório base correspondente a name,
+// recusando nomes cujo caminho passe por um link simbólico ou por um arquivo
+// comum. Os componentes que ainda não existem são aceitos. A root já impede
+// que um link simbólico leve para fora do diretório base; recusá-los também
+// dentro dele evita que o mesmo arquivo seja acessado por nomes diferentes,
+// cada um com seu próprio lock
+// NOTA: name deve ter sido validado com validateName
+func (ls *LocalStorage) resolve(name string) (string, error) {
+	parts := strings.Split(name, "/")
+	current := ""
+
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+
+		stat, err := ls.root.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("erro ao consultar %s: %w", name, err)
+		}
+		if stat.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s passa por um link simbólico", ErrInvalidName, name)
+		}
+		if i < len(parts)-1 && !stat.IsDir() {
+			return "", fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, strings.Join(parts[:i+1], "/"))
+		}
 	}
 
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+	return ls.path(name), nil
+}
 
-	filePath := filepath.Join(ls.baseDir, name)
+// commitIndex conclui a transação do índice de uma operação sobre os nomes
+// names, acrescentando a record as informações atuais dos seus diretórios
+// (ver indexDirs)
+func (ls *LocalStorage) commitIndex(tx *indexTx, record indexRecord, names ...string) error {
+	if tx == nil {
+		return nil
+	}
 
-	// Verifica se o arquivo existe
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("arquivo não encontrado: %s", name)
+	dirs, err := ls.indexDirs(names...)
+	if err != nil {
+		return err
 	}
+	record.Put = append(dirs, record.Put...)
+	return tx.commit(record)
+}
 
-	// Lê o arquivo
-	data, err := os.ReadFile(filePath)
+// prepareTarget resolve name e cria os diretórios que devem contê-lo
+// Falha com ErrIsDirectory se name já for um diretório
+// NOTA: Esta função assume que o chamador obteve o lock com lockPaths
+func (ls *LocalStorage) prepareTarget(name string) (string, error) {
+	target, err := ls.resolve(name)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+		return "", err
+	}
+
+	if stat, err := ls.root.Lstat(target); err == nil && stat.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrIsDirectory, name)
 	}
 
-	return data, nil
+	if err := mkdirAll(ls.root, filepath.Dir(target)); err != nil {
+		return "", fmt.Errorf("erro ao criar diretórios de %s: %w", name, err)
+	}
+
+	return target, nil
+}
+
+// cleanupTempFiles remove arquivos temporários deixados por uploads
+// interrompidos por uma queda do processo
+func (ls *LocalStorage) cleanupTempFiles() error {
+	entries, err := readDir(ls.root, ".")
+	if err != nil {
+		return fmt.Errorf("erro ao ler diretório %s: %w", ls.baseDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isTempFile(entry.Name()) {
+			continue
+		}
+
+		if err := removeIfUnlocked(ls.root, entry.Name()); err != nil {
+			return err
+		}
+	}
+
+	// Os registros de checksum também são gravados por meio de temporários
+	entries, err = readDir(ls.root, ChecksumsDirName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler diretório %s: %w", ChecksumsDirName, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isTempFile(entry.Name()) {
+			continue
+		}
+
+		if err := removeIfUnlocked(ls.root, filepath.Join(ChecksumsDirName, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeIfUnlocked remove path se nenhum processo mantiver flock sobre ele
+// Um upload em andamento, inclusive em outro servidor que compartilha o
+// diretório, mantém o lock até concluir; um processo que caiu já o liberou
+func removeIfUnlocked(root *os.Root, path string) error {
+	f, err := root.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo temporário %s: %w", path, err)
+	}
+	defer f.Close()
+
+	locked, err := tryLockFile(f)
+	if err != nil {
+		return fmt.Errorf("erro ao travar arquivo temporário %s: %w", path, err)
+	}
+	if !locked {
+		return nil
+	}
+
+	if err := root.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("erro ao remover arquivo temporário %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// validateName valida o nome de um arquivo recebido pelas operações
+func validateName(name string) error {
+	// Validação básica do nome do arquivo
+	if name == "" {
+		return fmt.Errorf("%w: nome do arquivo não pode ser vazio", ErrInvalidName)
+	}
+
+	// Previne path traversal attacks: os nomes são caminhos relativos com
+	// componentes separados por "/", sem ".", ".." ou componentes vazios
+	if strings.Contains(name, `\`) || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("%w: não pode conter caminhos relativos", ErrInvalidName)
+	}
+	parts := strings.Split(name, "/")
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w: não pode conter caminhos relativos", ErrInvalidName)
+		}
+
+		// Impede que arquivos sejam confundidos com uploads em andamento
+		if isTempFile(part) {
+			return fmt.Errorf("%w: %s é reservado", ErrInvalidName, part)
+		}
+	}
+
+	// Impede que arquivos sobrescrevam as áreas internas do diretório de dados
+	if reservedNames[parts[0]] {
+		return fmt.Errorf("%w: %s é reservado", ErrInvalidName, parts[0])
+	}
+
+	return nil
+}
+
+// isTempFile indica se name é um arquivo temporário de upload
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, tempFilePrefix)
 }
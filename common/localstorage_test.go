@@ -0,0 +1,238 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testContentSize é o tamanho dos conteúdos gravados nos testes de
+// concorrência, grande o bastante para que uma leitura parcial ou misturada
+// atravesse vários blocos de ChunkSize
+const testContentSize = 3*ChunkSize + 123
+
+// testContent retorna o conteúdo da versão v de um arquivo: todos os bytes
+// iguais a v, para que uma mistura de versões seja detectável
+func testContent(v byte) []byte {
+	return bytes.Repeat([]byte{v}, testContentSize)
+}
+
+// checkContent confere que data é uma versão completa gravada por testContent
+func checkContent(name string, data []byte) error {
+	if len(data) != testContentSize {
+		return fmt.Errorf("%s: %d bytes lidos, esperado %d", name, len(data), testContentSize)
+	}
+	if i := bytes.IndexFunc(data, func(r rune) bool { return byte(r) != data[0] }); i >= 0 {
+		return fmt.Errorf("%s: conteúdo misturado na posição %d", name, i)
+	}
+	return nil
+}
+
+// TestLocalStorageTwoInstances simula dois servidores sobre o mesmo diretório
+// de dados: cada leitura deve ver uma versão completa de um arquivo, e nenhum
+// temporário ou arquivo de lock deve sobrar
+func TestLocalStorageTwoInstances(t *testing.T) {
+	dir := t.TempDir()
+	var instances [2]*LocalStorage
+	for i := range instances {
+		ls, err := NewLocalStorage(dir, LocalStorageOptions{})
+		if err != nil {
+			t.Fatalf("NewLocalStorage: %v", err)
+		}
+		instances[i] = ls
+	}
+
+	names := []string{"a.dat", "b.dat", "docs/c.dat"}
+	const workers, ops = 8, 60
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*ops)
+	for w := range workers {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ls := instances[w%len(instances)]
+			r := rand.New(rand.NewSource(int64(w)))
+
+			for range ops {
+				name := names[r.Intn(len(names))]
+				var err error
+				switch r.Intn(5) {
+				case 0, 1:
+					v := byte('a' + r.Intn(26))
+					_, err = ls.UploadFile(name, bytes.NewReader(testContent(v)), UploadOptions{})
+				case 2:
+					err = readContent(ls, name)
+				case 3:
+					err = ls.DeleteFile(name)
+				case 4:
+					err = ls.RenameFile(name, names[r.Intn(len(names))])
+				}
+				if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAlreadyExists) {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Os arquivos que sobraram também são versões completas
+	for _, name := range names {
+		if err := readContent(instances[0], name); err != nil && !errors.Is(err, ErrNotFound) {
+			t.Error(err)
+		}
+	}
+
+	checkNoDebris(t, dir)
+}
+
+// readContent baixa name de ls e confere o conteúdo com checkContent
+func readContent(ls *LocalStorage, name string) error {
+	rc, _, err := ls.DownloadFile(name, DownloadOptions{})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return checkContent(name, data)
+}
+
+// checkNoDebris falha se restarem em dir temporários de upload ou arquivos de
+// lock depois de todas as operações terminarem
+func checkNoDebris(t *testing.T, dir string) {
+	t.Helper()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if strings.HasPrefix(d.Name(), tempFilePrefix) {
+			t.Errorf("temporário restante: %s", rel)
+		}
+		if !d.IsDir() && filepath.Dir(rel) == LocksDirName {
+			t.Errorf("arquivo de lock restante: %s", rel)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("erro ao percorrer %s: %v", dir, err)
+	}
+}
+
+// plantOutside cria, fora do diretório de dados, um diretório com o arquivo
+// secret.txt, que nenhuma operação do armazenamento deve alcançar
+func plantOutside(t *testing.T) (string, string) {
+	t.Helper()
+
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("segredo"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return outside, secret
+}
+
+// checkOutside falha se o conteúdo de fora do diretório de dados foi lido
+// pelo chamador, alterado ou removido, ou se algum arquivo foi criado nele
+func checkOutside(t *testing.T, outside, secret string) {
+	t.Helper()
+
+	data, err := os.ReadFile(secret)
+	if err != nil || string(data) != "segredo" {
+		t.Fatalf("arquivo de fora alterado: %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("diretório de fora alterado: %v, %v", entries, err)
+	}
+}
+
+// TestLocalStorageSymlinks planta links simbólicos para fora do diretório de
+// dados, como folha e como diretório intermediário, e confere que nenhuma
+// operação os segue
+func TestLocalStorageSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	outside, secret := plantOutside(t)
+	if err := os.Symlink(secret, filepath.Join(dir, "leak.txt")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "docs")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	ls, err := NewLocalStorage(dir, LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	if _, err := ls.UploadFile("mine.txt", strings.NewReader("meu"), UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	for _, name := range []string{"leak.txt", "docs/secret.txt"} {
+		t.Run(name, func(t *testing.T) {
+			if rc, _, err := ls.DownloadFile(name, DownloadOptions{}); !errors.Is(err, ErrInvalidName) {
+				if err == nil {
+					rc.Close()
+				}
+				t.Errorf("DownloadFile: %v, esperado ErrInvalidName", err)
+			}
+			if _, err := ls.StatFile(name); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("StatFile: %v, esperado ErrInvalidName", err)
+			}
+			if _, err := ls.UploadFile(name, strings.NewReader("sobrescrito"), UploadOptions{}); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("UploadFile: %v, esperado ErrInvalidName", err)
+			}
+			if err := ls.DeleteFile(name); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("DeleteFile: %v, esperado ErrInvalidName", err)
+			}
+			if err := ls.RenameFile(name, "stolen.txt"); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("RenameFile de %s: %v, esperado ErrInvalidName", name, err)
+			}
+			checkOutside(t, outside, secret)
+		})
+	}
+
+	if _, err := ls.UploadFile("docs/new.txt", strings.NewReader("novo"), UploadOptions{}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("UploadFile em docs: %v, esperado ErrInvalidName", err)
+	}
+	if err := ls.CreateDirectory("docs/sub"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("CreateDirectory em docs: %v, esperado ErrInvalidName", err)
+	}
+	if err := ls.RenameFile("mine.txt", "docs/mine.txt"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("RenameFile para docs: %v, esperado ErrInvalidName", err)
+	}
+	if _, _, err := ls.ListFiles(ListOptions{Dir: "docs"}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("ListFiles de docs: %v, esperado ErrInvalidName", err)
+	}
+
+	// A listagem do diretório base não mostra os links
+	files, _, err := ls.ListFiles(ListOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "mine.txt" {
+		t.Errorf("ListFiles listou %v, esperado só mine.txt", files)
+	}
+
+	checkOutside(t, outside, secret)
+	if _, err := os.Lstat(filepath.Join(dir, "leak.txt")); err != nil {
+		t.Errorf("link leak.txt removido: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"path"
+	"sort"
+	"sync"
+)
+
+// lockTable mantém um RWMutex por nome, criado sob demanda e descartado
+// quando o último usuário o libera. Operações sobre nomes diferentes nunca
+// disputam o mesmo lock
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]*refLock
+}
+
+// refLock é um RWMutex com contagem de usuários que o obtiveram da tabela
+type refLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// newLockTable cria uma tabela de locks vazia
+func newLockTable() *lockTable {
+	return &lockTable{
+		locks: make(map[string]*refLock),
+	}
+}
+
+// Lock trava name exclusivamente e retorna a função que libera o lock
+func (t *lockTable) Lock(name string) func() {
+	l := t.get(name)
+	l.Lock()
+	return func() {
+		l.Unlock()
+		t.put(name, l)
+	}
+}
+
+// RLock trava name para leitura e retorna a função que libera o lock
+func (t *lockTable) RLock(name string) func() {
+	l := t.get(name)
+	l.RLock()
+	return func() {
+		l.RUnlock()
+		t.put(name, l)
+	}
+}
+
+// get obtém o lock de name, criando-o se necessário, e registra um usuário
+func (t *lockTable) get(name string) *refLock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.locks[name]
+	if !ok {
+		l = &refLock{}
+		t.locks[name] = l
+	}
+	l.refs++
+	return l
+}
+
+// put remove um usuário do lock de name e o descarta se não houver outros
+func (t *lockTable) put(name string, l *refLock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(t.locks, name)
+	}
+}
+
+// LockPaths trava os nomes em names, de forma exclusiva ou compartilhada, e os
+// diretórios que os contêm de forma compartilhada, na ordem de lockOrder, e
+// retorna a função que libera todos os locks
+func (t *lockTable) LockPaths(exclusive bool, names ...string) func() {
+	keys, modes := lockOrder(exclusive, names)
+
+	releases := make([]func(), 0, len(keys))
+	for _, key := range keys {
+		if modes[key] {
+			releases = append(releases, t.Lock(key))
+		} else {
+			releases = append(releases, t.RLock(key))
+		}
+	}
+
+	return func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+}
+
+// lockOrder retorna os nomes que devem ser travados para operar sobre names,
+// em ordem alfabética para evitar deadlock entre operações concorrentes, e o
+// modo de cada um: names no modo pedido e os diretórios que os contêm em
+// modo compartilhado
+func lockOrder(exclusive bool, names []string) ([]string, map[string]bool) {
+	modes := make(map[string]bool)
+	for _, name := range names {
+		modes[name] = exclusive
+	}
+	for _, name := range names {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := modes[dir]; !ok {
+				modes[dir] = false
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(modes))
+	for key := range modes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, modes
+}
@@ -0,0 +1,170 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDeadline é o prazo para operações que não devem esperar por locks de
+// outros nomes
+const testDeadline = 5 * time.Second
+
+// blockingReader entrega um bloco de dados e então bloqueia até release ser
+// fechado, simulando um cliente lento no meio de um upload
+type blockingReader struct {
+	started chan struct{}
+	release chan struct{}
+	sent    bool
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		close(r.started)
+		return copy(p, bytes.Repeat([]byte{'x'}, min(len(p), ChunkSize))), nil
+	}
+	<-r.release
+	return 0, io.EOF
+}
+
+// withDeadline executa fn e falha se ela não terminar dentro de testDeadline
+func withDeadline(t *testing.T, what string, fn func() error) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+	case <-time.After(testDeadline):
+		t.Fatalf("%s não terminou em %v: bloqueado por outro nome", what, testDeadline)
+	}
+}
+
+// TestUnrelatedNamesDoNotBlock confere que um upload lento e o lock
+// exclusivo de a.dat não atrasam operações sobre b.dat
+func TestUnrelatedNamesDoNotBlock(t *testing.T) {
+	ls, err := NewLocalStorage(t.TempDir(), LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	if _, err := ls.UploadFile("a.dat", bytes.NewReader(testContent('a')), UploadOptions{}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	// Um upload de a.dat parado no meio da leitura do cliente
+	slow := &blockingReader{started: make(chan struct{}), release: make(chan struct{})}
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := ls.UploadFile("a.dat", slow, UploadOptions{})
+		slowDone <- err
+	}()
+	<-slow.started
+
+	// E o lock exclusivo de a.dat, como o mantém um upload durante o rename
+	unlock, err := ls.lockPaths(true, "a.dat")
+	if err != nil {
+		t.Fatalf("lockPaths: %v", err)
+	}
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- readContent(ls, "a.dat") }()
+
+	withDeadline(t, "UploadFile(b.dat)", func() error {
+		_, err := ls.UploadFile("b.dat", bytes.NewReader(testContent('b')), UploadOptions{})
+		return err
+	})
+	withDeadline(t, "DownloadFile(b.dat)", func() error { return readContent(ls, "b.dat") })
+	withDeadline(t, "StatFile(b.dat)", func() error {
+		_, err := ls.StatFile("b.dat")
+		return err
+	})
+
+	// Já o download de a.dat espera o lock
+	select {
+	case err := <-blocked:
+		t.Fatalf("DownloadFile(a.dat) terminou com o lock exclusivo mantido: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	close(slow.release)
+	withDeadline(t, "DownloadFile(a.dat)", func() error { return <-blocked })
+	withDeadline(t, "UploadFile(a.dat)", func() error { return <-slowDone })
+}
+
+// TestLockPathsStress trava conjuntos sobrepostos de nomes, com diretórios e
+// seus conteúdos, de várias goroutines: todas devem terminar, e a tabela deve
+// ficar vazia depois
+func TestLockPathsStress(t *testing.T) {
+	table := newLockTable()
+	pool := []string{"d", "d/x", "d/x/y", "d/x/z", "d/w", "e", "e/x", "f"}
+	const workers, rounds = 16, 500
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		holders  = make(map[string]int) // nome -> número de portadores exclusivos
+		conflict error
+	)
+	for w := range workers {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(w)))
+
+			for range rounds {
+				var names []string
+				for range 1 + r.Intn(3) {
+					if name := pool[r.Intn(len(pool))]; !slices.Contains(names, name) {
+						names = append(names, name)
+					}
+				}
+				exclusive := r.Intn(2) == 0
+				unlock := table.LockPaths(exclusive, names...)
+
+				if exclusive {
+					mu.Lock()
+					for _, name := range names {
+						holders[name]++
+						if holders[name] > 1 && conflict == nil {
+							conflict = errors.New("dois locks exclusivos simultâneos sobre " + name)
+						}
+					}
+					mu.Unlock()
+				}
+				if r.Intn(4) == 0 {
+					time.Sleep(time.Microsecond)
+				}
+				if exclusive {
+					mu.Lock()
+					for _, name := range names {
+						holders[name]--
+					}
+					mu.Unlock()
+				}
+
+				unlock()
+			}
+		}(w)
+	}
+
+	withDeadline(t, "LockPaths", func() error {
+		wg.Wait()
+		return conflict
+	})
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if len(table.locks) != 0 {
+		t.Fatalf("%d locks restantes na tabela depois de liberados", len(table.locks))
+	}
+}
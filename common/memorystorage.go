@@ -0,0 +1,523 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage implementa FileService mantendo os arquivos em memória
+// Aplica as mesmas validações e retorna os mesmos erros que o LocalStorage,
+// então pode substituí-lo em testes herméticos e em servidores efêmeros, como
+// nos benchmarks que medem só o custo dos protocolos. O conteúdo é perdido
+// quando o processo termina
+type MemoryStorage struct {
+	mu            sync.RWMutex
+	nodes         map[string]*memoryNode // nome -> arquivo ou diretório
+	versionPolicy VersionPolicy
+}
+
+// MemoryStorageOptions configura um MemoryStorage
+type MemoryStorageOptions struct {
+	// Versions define se e por quanto tempo as versões substituídas por novos
+	// uploads são mantidas; o valor zero desativa o versionamento
+	Versions VersionPolicy
+}
+
+// memoryNode é um arquivo ou diretório de um MemoryStorage
+// O conteúdo de um arquivo nunca é alterado depois de gravado: um upload
+// troca o slice inteiro, então leitores em andamento continuam com a versão
+// que abriram
+type memoryNode struct {
+	info     FileInfo
+	data     []byte
+	versions []memoryVersion // Da mais antiga para a mais recente
+}
+
+// fileInfo retorna uma cópia das informações do nó, com um mapa de tags
+// próprio, para que o chamador não altere o estado do armazenamento
+func (n *memoryNode) fileInfo() FileInfo {
+	info := n.info
+	info.Tags = cloneTags(n.info.Tags)
+	return info
+}
+
+// memoryVersion é uma versão anterior de um arquivo em memória
+type memoryVersion struct {
+	FileVersion
+	data []byte
+}
+
+// NewMemoryStorage cria uma nova instância de MemoryStorage, vazia
+func NewMemoryStorage(opts MemoryStorageOptions) *MemoryStorage {
+	return &MemoryStorage{
+		nodes:         make(map[string]*memoryNode),
+		versionPolicy: opts.Versions,
+	}
+}
+
+// ListFiles retorna as informações dos arquivos e diretórios de opts.Dir, na
+// mesma ordem do LocalStorage: alfabética, com o conteúdo de cada
+// subdiretório logo após ele quando opts.Recursive
+func (ms *MemoryStorage) ListFiles(opts ListOptions) ([]FileInfo, string, error) {
+	if err := opts.check(); err != nil {
+		return nil, "", err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if opts.Dir != "" {
+		if err := validateName(opts.Dir); err != nil {
+			return nil, "", err
+		}
+		if err := ms.resolve(opts.Dir); err != nil {
+			return nil, "", err
+		}
+
+		node, ok := ms.nodes[opts.Dir]
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %s", ErrNotFound, opts.Dir)
+		}
+		if !node.info.IsDir {
+			return nil, "", fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, opts.Dir)
+		}
+	}
+
+	var names []string
+	for name := range ms.nodes {
+		parent := memoryParent(name)
+		if parent == opts.Dir || (opts.Recursive && (opts.Dir == "" || strings.HasPrefix(parent, opts.Dir+"/"))) {
+			names = append(names, name)
+		}
+	}
+
+	return listPage(names, opts, func(name string) (FileInfo, error) {
+		return ms.nodes[name].fileInfo(), nil
+	})
+}
+
+// UploadFile grava o conteúdo lido de r no arquivo especificado
+// Os dados são lidos por completo antes de o arquivo ser substituído, então
+// leitores nunca veem um arquivo parcial e um erro de r não altera nada
+func (ms *MemoryStorage) UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+	if err := validateTags(opts.Tags); err != nil {
+		return FileInfo{}, err
+	}
+
+	// A leitura acontece fora do lock para não bloquear as demais operações
+	var buf bytes.Buffer
+	hash := sha256.New()
+	if _, err := io.CopyBuffer(io.MultiWriter(&buf, hash), r, make([]byte, ChunkSize)); err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+	}
+	data := buf.Bytes()
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	info := FileInfo{
+		Name:        name,
+		Size:        int64(len(data)),
+		ModTime:     time.Now(),
+		SHA256:      sum,
+		ContentType: DetectContentType(name, data[:min(len(data), sniffLen)]),
+		ETag:        sum,
+		Tags:        cloneTags(opts.Tags),
+	}
+	info.ExpiresAt = opts.expiresAt(info.ModTime)
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.resolve(name); err != nil {
+		return FileInfo{}, err
+	}
+
+	current, exists := ms.nodes[name]
+	if exists && current.info.IsDir {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+
+	if opts.conditional() {
+		var currentInfo *FileInfo
+		if exists {
+			currentInfo = &current.info
+		}
+		if err := opts.check(name, currentInfo); err != nil {
+			return FileInfo{}, err
+		}
+	}
+
+	node := &memoryNode{info: info, data: data}
+	if exists {
+		// Com versionamento, a versão atual vai para o histórico antes de ser substituída
+		node.versions = current.versions
+		if ms.versionPolicy.Enabled() {
+			node.versions = append(node.versions, memoryVersion{
+				FileVersion: FileVersion{
+					ID:         nextVersionID(node.versions, info.ModTime),
+					Size:       current.info.Size,
+					SHA256:     current.info.SHA256,
+					ModTime:    current.info.ModTime,
+					ReplacedAt: info.ModTime,
+				},
+				data: current.data,
+			})
+		}
+	}
+
+	ms.mkdirAll(memoryParent(name), info.ModTime)
+	if !exists {
+		ms.touch(memoryParent(name), info.ModTime)
+	}
+	ms.nodes[name] = node
+	ms.pruneVersions(node)
+
+	return info, nil
+}
+
+// DownloadFile abre para leitura o trecho de um arquivo selecionado por opts
+// O conteúdo de um upload nunca é alterado, então o leitor continua com a
+// mesma versão mesmo que outro upload substitua o arquivo durante a leitura
+func (ms *MemoryStorage) DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if err := ms.resolve(name); err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	node, ok := ms.nodes[name]
+	if !ok {
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if node.info.IsDir {
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+
+	if opts.Partial() {
+		offset, length, err := opts.Range(int64(len(node.data)))
+		if err != nil {
+			return nil, FileInfo{}, err
+		}
+		return io.NopCloser(bytes.NewReader(node.data[offset : offset+length])), node.fileInfo(), nil
+	}
+
+	content := verifyStored(bytes.NewReader(node.data), name, node.info.Size, node.info.SHA256)
+	return io.NopCloser(content), node.fileInfo(), nil
+}
+
+// DeleteFile remove um arquivo ou um diretório vazio, junto com seu histórico
+func (ms *MemoryStorage) DeleteFile(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.resolve(name); err != nil {
+		return err
+	}
+
+	node, ok := ms.nodes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if node.info.IsDir && ms.hasChildren(name) {
+		return fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, name)
+	}
+
+	delete(ms.nodes, name)
+	ms.touch(memoryParent(name), time.Now())
+
+	return nil
+}
+
+// RenameFile renomeia ou move um arquivo ou diretório, com todo o seu conteúdo
+// Falha com ErrAlreadyExists se já existir um arquivo ou diretório com o
+// nome de destino. Os diretórios do caminho de destino são criados se
+// necessário
+func (ms *MemoryStorage) RenameFile(oldName, newName string) error {
+	if err := validateName(oldName); err != nil {
+		return err
+	}
+	if err := validateName(newName); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+	if strings.HasPrefix(newName, oldName+"/") {
+		return fmt.Errorf("%w: %s não pode ser movido para dentro de si mesmo", ErrInvalidName, oldName)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.resolve(oldName); err != nil {
+		return err
+	}
+	if _, ok := ms.nodes[oldName]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, oldName)
+	}
+
+	if err := ms.resolve(newName); err != nil {
+		return err
+	}
+	if _, ok := ms.nodes[newName]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+
+	now := time.Now()
+	ms.mkdirAll(memoryParent(newName), now)
+
+	// Move o próprio nome e, se for um diretório, todo o seu conteúdo
+	moved := make(map[string]*memoryNode)
+	for name, node := range ms.nodes {
+		if name == oldName || strings.HasPrefix(name, oldName+"/") {
+			moved[newName+strings.TrimPrefix(name, oldName)] = node
+			delete(ms.nodes, name)
+		}
+	}
+	for name, node := range moved {
+		node.info.Name = name
+		ms.nodes[name] = node
+	}
+
+	ms.touch(memoryParent(oldName), now)
+	ms.touch(memoryParent(newName), now)
+
+	return nil
+}
+
+// StatFile retorna as informações de um arquivo ou diretório
+func (ms *MemoryStorage) StatFile(name string) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if err := ms.resolve(name); err != nil {
+		return FileInfo{}, err
+	}
+
+	node, ok := ms.nodes[name]
+	if !ok {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	return node.fileInfo(), nil
+}
+
+// CreateDirectory cria um diretório e os diretórios intermediários que ainda
+// não existirem
+func (ms *MemoryStorage) CreateDirectory(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.resolve(name); err != nil {
+		return err
+	}
+	if _, ok := ms.nodes[name]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, name)
+	}
+
+	ms.mkdirAll(name, time.Now())
+	return nil
+}
+
+// ListVersions retorna as versões anteriores de um arquivo, da mais recente
+// para a mais antiga. Versões expiradas pela política são removidas antes
+func (ms *MemoryStorage) ListVersions(name string) ([]FileVersion, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	// Exclusivo porque a limpeza das versões expiradas altera o histórico
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if err := ms.resolve(name); err != nil {
+		return nil, err
+	}
+
+	node, ok := ms.nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	ms.pruneVersions(node)
+
+	var versions []FileVersion
+	for i := len(node.versions) - 1; i >= 0; i-- {
+		versions = append(versions, node.versions[i].FileVersion)
+	}
+
+	return versions, nil
+}
+
+// DownloadVersion abre uma versão anterior de um arquivo para leitura
+func (ms *MemoryStorage) DownloadVersion(name, versionID string) (io.ReadCloser, FileVersion, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileVersion{}, err
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if err := ms.resolve(name); err != nil {
+		return nil, FileVersion{}, err
+	}
+
+	if node, ok := ms.nodes[name]; ok {
+		for _, version := range node.versions {
+			if version.ID == versionID {
+				content := verifyStored(bytes.NewReader(version.data), name, version.Size, version.SHA256)
+				return io.NopCloser(content), version.FileVersion, nil
+			}
+		}
+	}
+
+	return nil, FileVersion{}, fmt.Errorf("%w: versão %s de %s", ErrNotFound, versionID, name)
+}
+
+// RestoreVersion torna uma versão anterior a versão atual do arquivo
+// O conteúdo da versão é gravado como um novo upload e a versão atual entra
+// no histórico
+func (ms *MemoryStorage) RestoreVersion(name, versionID string) error {
+	version, _, err := ms.DownloadVersion(name, versionID)
+	if err != nil {
+		return err
+	}
+	defer version.Close()
+
+	if _, err := ms.UploadFile(name, version, UploadOptions{}); err != nil {
+		return fmt.Errorf("erro ao restaurar versão %s de %s: %w", versionID, name, err)
+	}
+
+	return nil
+}
+
+// resolve recusa nomes cujo caminho passe por um arquivo comum, como o
+// LocalStorage. Os componentes que ainda não existem são aceitos
+// NOTA: Esta função assume que o chamador obteve ms.mu
+func (ms *MemoryStorage) resolve(name string) error {
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		node, ok := ms.nodes[dir]
+		if !ok {
+			break
+		}
+		if !node.info.IsDir {
+			return fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, dir)
+		}
+	}
+	return nil
+}
+
+// mkdirAll cria dir e os diretórios que o contêm que ainda não existirem
+// NOTA: Esta função assume que o chamador obteve ms.mu de forma exclusiva e
+// verificou o caminho com resolve
+func (ms *MemoryStorage) mkdirAll(dir string, now time.Time) {
+	for ; dir != ""; dir = memoryParent(dir) {
+		if _, ok := ms.nodes[dir]; ok {
+			return
+		}
+		ms.nodes[dir] = &memoryNode{info: FileInfo{Name: dir, ModTime: now, IsDir: true}}
+		ms.touch(memoryParent(dir), now)
+	}
+}
+
+// touch atualiza a data de modificação do diretório dir, como o sistema de
+// arquivos faz quando uma entrada é criada ou removida
+// NOTA: Esta função assume que o chamador obteve ms.mu de forma exclusiva
+func (ms *MemoryStorage) touch(dir string, now time.Time) {
+	if node, ok := ms.nodes[dir]; ok {
+		node.info.ModTime = now
+	}
+}
+
+// hasChildren indica se o diretório dir tem alguma entrada
+// NOTA: Esta função assume que o chamador obteve ms.mu
+func (ms *MemoryStorage) hasChildren(dir string) bool {
+	for name := range ms.nodes {
+		if memoryParent(name) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneVersions remove as versões de node que excedem a política de retenção
+// NOTA: Esta função assume que o chamador obteve ms.mu de forma exclusiva
+func (ms *MemoryStorage) pruneVersions(node *memoryNode) {
+	// Sem versionamento ativo o histórico existente é preservado
+	policy := ms.versionPolicy
+	if !policy.Enabled() {
+		return
+	}
+
+	kept := node.versions[:0]
+	for i, version := range node.versions {
+		newer := len(node.versions) - 1 - i
+		expired := (policy.MaxVersions > 0 && newer >= policy.MaxVersions) ||
+			(policy.MaxAge > 0 && time.Since(version.ReplacedAt) > policy.MaxAge)
+		if !expired {
+			kept = append(kept, version)
+		}
+	}
+	node.versions = kept
+}
+
+// nextVersionID gera o ID de uma versão substituída em replacedAt, no mesmo
+// formato do LocalStorage, mantendo os IDs de versions em ordem crescente
+func nextVersionID(versions []memoryVersion, replacedAt time.Time) string {
+	t := replacedAt.UTC()
+	if len(versions) > 0 {
+		last, _ := time.Parse(versionIDLayout, versions[len(versions)-1].ID)
+		if !t.After(last) {
+			t = last.Add(time.Nanosecond)
+		}
+	}
+	return t.Format(versionIDLayout)
+}
+
+// memoryParent retorna o diretório que contém name, ou "" para a raiz
+func memoryParent(name string) string {
+	dir := path.Dir(name)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// comparePaths compara dois nomes componente a componente, de modo que o
+// conteúdo de um diretório fique logo após ele, como em uma listagem recursiva
+func comparePaths(a, b string) int {
+	pa, pb := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if c := strings.Compare(pa[i], pb[i]); c != 0 {
+			return c
+		}
+	}
+	return len(pa) - len(pb)
+}
@@ -1,18 +1,72 @@
 package common
 
+// MaxMessageFileSize é o maior conteúdo, em bytes, transportado em FileData
+// numa única mensagem do RabbitMQ. O conteúdo vai inteiro na mensagem, em
+// base64, e é mantido em memória pelo servidor e pelo cliente; arquivos
+// maiores são enviados com upload retomável e baixados em trechos
+const MaxMessageFileSize = 50 * 1024 * 1024
+
 // RequestMessage representa uma mensagem de requisição do cliente
 type RequestMessage struct {
-	Operation string `json:"operation"` // "list", "upload", "download"
-	FileName  string `json:"file_name,omitempty"`
-	FileData  []byte `json:"file_data,omitempty"` // Base64 encoded para JSON
+	Operation string `json:"operation"`            // "list", "upload", "download", "delete", "rename", "stat", "mkdir", "versions", "*_version", "*_trash", "session_*"
+	FileName  string `json:"file_name,omitempty"`  // Na operação "list", o diretório listado
+	FileData  []byte `json:"file_data,omitempty"`  // Base64 encoded para JSON
+	NewName   string `json:"new_name,omitempty"`   // Para operação "rename"
+	Recursive bool   `json:"recursive,omitempty"`  // Para operação "list"
+	VersionID string `json:"version_id,omitempty"` // Para "download_version" e "restore_version"
+	TrashID   string `json:"trash_id,omitempty"`   // Para "restore_trash" e "purge_trash"
+	All       bool   `json:"all,omitempty"`        // Para "purge_trash" sem trash_id: esvazia a lixeira
+
+	// Condições da operação "upload" (ver UploadOptions)
+	IfMatch     string `json:"if_match,omitempty"`
+	IfNoneMatch string `json:"if_none_match,omitempty"`
+
+	// TTL das operações "upload" e "session_create", em segundos: positivo
+	// expira o arquivo, negativo o mantém sem expiração e zero usa o TTL
+	// padrão do servidor (ver UploadTTL)
+	TTL int64 `json:"ttl_seconds,omitempty"`
+
+	// Tags das operações "upload" e "session_create" (ver UploadOptions.Tags)
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Trecho da operação "download" (ver DownloadOptions)
+	Length int64 `json:"length,omitempty"`
+
+	// Filtros, ordem e paginação da operação "list" (ver ListOptions)
+	Prefix    string `json:"prefix,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Sort      string `json:"sort,omitempty"` // "name" (padrão), "size" ou "mod_time"
+	Reverse   bool   `json:"reverse,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+	PageToken string `json:"page_token,omitempty"`
+	Selector  string `json:"selector,omitempty"` // Seletor de tags, como "env=prod" (ver ParseTagSelector)
+
+	// Campos das operações de upload retomável
+	SessionID string `json:"session_id,omitempty"` // "session_status", "session_append", "session_commit", "session_abort"
+	Offset    int64  `json:"offset,omitempty"`     // Para "session_append" e, no trecho de "download", o início
+	Size      int64  `json:"size,omitempty"`       // Para "session_create"
+	Checksum  string `json:"checksum,omitempty"`   // Para "session_create"
 }
 
 // ResponseMessage representa uma mensagem de resposta do servidor
 type ResponseMessage struct {
-	Success   bool     `json:"success"`
-	Message   string   `json:"message,omitempty"`
-	Files     []string `json:"files,omitempty"`     // Para operação "list"
-	FileData  []byte   `json:"file_data,omitempty"` // Base64 encoded para JSON
-	FileName  string   `json:"file_name,omitempty"` // Para operação "download"
-}
+	Success       bool          `json:"success"`
+	Message       string        `json:"message,omitempty"`
+	ErrorCode     string        `json:"error_code,omitempty"`      // Um dos ErrorCode* quando Success é false
+	Files         []string      `json:"files,omitempty"`           // Para operação "list"
+	FileInfos     []FileInfo    `json:"file_infos,omitempty"`      // Para operação "list"
+	NextPageToken string        `json:"next_page_token,omitempty"` // Para operação "list": token da próxima página
+	FileData      []byte        `json:"file_data,omitempty"`       // Base64 encoded para JSON
+	FileName      string        `json:"file_name,omitempty"`       // Para operação "download"
+	Checksum      string        `json:"checksum,omitempty"`        // Para operação "download": SHA-256 do conteúdo enviado
+	File          *FileInfo     `json:"file,omitempty"`            // Para operações "stat" e "upload"
+	Versions      []FileVersion `json:"versions,omitempty"`        // Para operação "versions"
+	Trash         []TrashEntry  `json:"trash,omitempty"`           // Para "list_trash" e, em "purge_trash", as entradas removidas
 
+	// Estado da sessão nas operações de upload retomável. Na operação
+	// "download", Offset é o início do trecho enviado e Size o tamanho do
+	// arquivo inteiro
+	SessionID string `json:"session_id,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+}
@@ -0,0 +1,181 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Funções auxiliares para operar sobre um os.Root. O os.Root recusa qualquer
+// caminho que saia do diretório raiz, inclusive por links simbólicos, mas na
+// versão 1.24 do Go não oferece MkdirAll, Rename nem CreateTemp; essas
+// operações são montadas aqui a partir das primitivas disponíveis
+// Os caminhos recebidos são relativos à raiz e usam o separador do sistema
+
+// mkdirAll cria o diretório dir dentro de root e os diretórios intermediários
+// que ainda não existirem
+func mkdirAll(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+
+	current := ""
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+
+		err := root.Mkdir(current, 0755)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+
+		// O componente já existe: só é aceito se for um diretório
+		stat, err := root.Stat(current)
+		if err != nil {
+			return err
+		}
+		if !stat.IsDir() {
+			return fmt.Errorf("%s não é um diretório", current)
+		}
+	}
+
+	return nil
+}
+
+// createTemp cria em root um arquivo novo com nome aleatório iniciado por
+// prefix e retorna o arquivo aberto para escrita e seu nome
+func createTemp(root *os.Root, prefix string) (*os.File, string, error) {
+	buf := make([]byte, 8)
+	for range 10 {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		name := prefix + hex.EncodeToString(buf)
+
+		f, err := root.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, name, err
+	}
+
+	return nil, "", fmt.Errorf("não foi possível gerar um nome único com prefixo %s", prefix)
+}
+
+// readDir retorna as entradas do diretório dir de root em ordem alfabética
+// NOTA: o método Info das entradas retornadas não deve ser usado, pois
+// consulta o caminho relativo ao diretório atual do processo, e não a root
+func readDir(root *os.Root, dir string) ([]fs.DirEntry, error) {
+	d, err := root.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	entries, err := d.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// isEmptyDir indica se o diretório dir de root não tem nenhuma entrada
+func isEmptyDir(root *os.Root, dir string) (bool, error) {
+	d, err := root.Open(dir)
+	if err != nil {
+		return false, fmt.Errorf("erro ao abrir diretório %s: %w", dir, err)
+	}
+	defer d.Close()
+
+	entries, err := d.ReadDir(1)
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("erro ao ler diretório %s: %w", dir, err)
+	}
+
+	return len(entries) == 0, nil
+}
+
+// openRegular abre o arquivo path de root como root.OpenFile, mas recusa
+// links simbólicos mesmo que apontem para dentro de root, como se path não
+// existisse. O arquivo aberto é comparado com a entrada do diretório, então
+// um link criado entre a consulta e a abertura também é recusado
+func openRegular(root *os.Root, path string, flag int, perm os.FileMode) (*os.File, error) {
+	if stat, err := root.Lstat(path); err == nil && !stat.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+
+	f, err := root.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	opened, err := f.Stat()
+	if err == nil {
+		var entry os.FileInfo
+		if entry, err = root.Lstat(path); err == nil && !os.SameFile(opened, entry) {
+			err = &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// syncDir sincroniza em disco as entradas do diretório dir de root
+func syncDir(root *os.Root, dir string) error {
+	d, err := root.Open(dir)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir diretório %s: %w", dir, err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("erro ao sincronizar diretório %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// removeAll remove path de root e, se for um diretório, todo o seu conteúdo
+// Links simbólicos são removidos sem que o destino seja visitado
+func removeAll(root *os.Root, path string) error {
+	stat, err := root.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if stat.IsDir() {
+		entries, err := readDir(root, path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := removeAll(root, filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := root.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// renameInRoot renomeia oldPath para newPath, ambos relativos a root
+// Sem renameat, o rename usa os caminhos completos: os nomes foram
+// verificados pelo chamador, mas um link simbólico criado entre a verificação
+// e o rename não é detectado nesta plataforma
+func renameInRoot(root *os.Root, oldPath, newPath string) error {
+	return os.Rename(filepath.Join(root.Name(), oldPath), filepath.Join(root.Name(), newPath))
+}
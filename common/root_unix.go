@@ -0,0 +1,35 @@
+//go:build unix
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameInRoot renomeia oldPath para newPath, ambos relativos a root
+// Os diretórios de origem e destino são abertos pela root, que garante que
+// estão dentro dela, e o rename usa os descritores desses diretórios: nenhum
+// link simbólico no caminho é seguido depois da verificação
+func renameInRoot(root *os.Root, oldPath, newPath string) error {
+	oldDir, err := root.Open(filepath.Dir(oldPath))
+	if err != nil {
+		return err
+	}
+	defer oldDir.Close()
+
+	newDir, err := root.Open(filepath.Dir(newPath))
+	if err != nil {
+		return err
+	}
+	defer newDir.Close()
+
+	err = unix.Renameat(int(oldDir.Fd()), filepath.Base(oldPath), int(newDir.Fd()), filepath.Base(newPath))
+	if err != nil {
+		return &os.LinkError{Op: "renameat", Old: oldPath, New: newPath, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,1139 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// DefaultS3PartSize é o tamanho padrão das partes dos uploads multipart
+// Arquivos menores que uma parte são enviados com uma única requisição
+const DefaultS3PartSize = 16 * 1024 * 1024
+
+const (
+	// minS3PartSize é o menor tamanho de parte aceito pelo S3, exceto na última
+	minS3PartSize = 5 * 1024 * 1024
+
+	// maxS3Parts é o número máximo de partes de um upload multipart
+	maxS3Parts = 10000
+
+	// maxS3CopySize é o maior objeto que CopyObject copia em uma única requisição
+	maxS3CopySize = 5 * 1024 * 1024 * 1024
+
+	// s3ChecksumMetadata é a chave dos metadados do objeto com o SHA-256 do conteúdo
+	s3ChecksumMetadata = "sha256"
+
+	// s3ExpiresMetadata é a chave dos metadados do objeto com a expiração
+	// definida no upload, no formato RFC 3339
+	s3ExpiresMetadata = "expires-at"
+
+	// s3TagsMetadata é a chave dos metadados do objeto com as tags definidas
+	// no upload, codificadas como uma query string ("env=prod&owner=ana")
+	s3TagsMetadata = "tags"
+)
+
+// S3Storage implementa FileService sobre um bucket de um serviço compatível
+// com a API do S3. Cada arquivo é um objeto cuja chave é o prefixo seguido do
+// nome; diretórios criados com CreateDirectory são objetos vazios terminados
+// em "/" e os demais existem implicitamente enquanto tiverem conteúdo, sem
+// data de modificação
+//
+// As operações sobre um mesmo nome são serializadas dentro do processo, como
+// no LocalStorage. Entre processos, os uploads condicionais usam as condições
+// If-Match / If-None-Match do próprio S3 para que a verificação e a gravação
+// sejam atômicas. RenameFile copia e remove os objetos, então não é atômico
+type S3Storage struct {
+	client    *s3.Client
+	bucket    string
+	prefix    string
+	partSize  int64
+	locks     *lockTable
+	infoCache sync.Map // nome -> s3CachedInfo
+}
+
+// S3StorageOptions configura um S3Storage
+type S3StorageOptions struct {
+	Bucket       string
+	Prefix       string // Prefixo das chaves dos objetos, como "fileshare/"; vazio usa o bucket inteiro
+	Region       string // Vazio usa us-east-1
+	Endpoint     string // URL de um serviço compatível, como MinIO; vazio usa a AWS
+	UsePathStyle bool   // Endereça o bucket no caminho da URL em vez do host
+
+	// Credenciais de acesso; sem AccessKeyID as requisições são anônimas
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// PartSize é o tamanho das partes dos uploads multipart; 0 usa DefaultS3PartSize
+	PartSize int64
+}
+
+// s3CachedInfo guarda as informações de um arquivo junto com o ETag do S3 do
+// objeto de onde foram lidas, para detectar quando o objeto é substituído
+type s3CachedInfo struct {
+	objectETag string
+	info       FileInfo
+}
+
+// NewS3Storage cria uma nova instância de S3Storage
+// Verifica se o bucket existe e está acessível com as credenciais informadas
+func NewS3Storage(opts S3StorageOptions) (*S3Storage, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("bucket do S3 não informado")
+	}
+
+	partSize := opts.PartSize
+	if partSize == 0 {
+		partSize = DefaultS3PartSize
+	}
+	if partSize < minS3PartSize {
+		return nil, fmt.Errorf("tamanho de parte %d menor que o mínimo do S3 (%d bytes)", partSize, minS3PartSize)
+	}
+
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	var credentials aws.CredentialsProvider = aws.AnonymousCredentials{}
+	if opts.AccessKeyID != "" {
+		static := aws.Credentials{
+			AccessKeyID:     opts.AccessKeyID,
+			SecretAccessKey: opts.SecretAccessKey,
+			SessionToken:    opts.SessionToken,
+			Source:          "S3StorageOptions",
+		}
+		credentials = aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return static, nil
+		}))
+	}
+
+	var endpoint *string
+	if opts.Endpoint != "" {
+		endpoint = aws.String(opts.Endpoint)
+	}
+
+	client := s3.New(s3.Options{
+		Region:       region,
+		Credentials:  credentials,
+		BaseEndpoint: endpoint,
+		UsePathStyle: opts.UsePathStyle,
+
+		// Checksums adicionais só quando exigidos pela operação, para manter a
+		// compatibilidade com serviços que não os suportam
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+
+	if _, err := client.HeadBucket(context.Background(), &s3.HeadBucketInput{Bucket: aws.String(opts.Bucket)}); err != nil {
+		return nil, fmt.Errorf("falha ao acessar bucket %s: %w", opts.Bucket, err)
+	}
+
+	return &S3Storage{
+		client:   client,
+		bucket:   opts.Bucket,
+		prefix:   prefix,
+		partSize: partSize,
+		locks:    newLockTable(),
+	}, nil
+}
+
+// ListFiles retorna as informações dos arquivos e diretórios de opts.Dir, na
+// mesma ordem do LocalStorage. Os objetos são listados por inteiro, com
+// opts.Prefix como prefixo da chave, mas na ordem por nome só os arquivos da
+// página são consultados
+func (s *S3Storage) ListFiles(opts ListOptions) ([]FileInfo, string, error) {
+	if err := opts.check(); err != nil {
+		return nil, "", err
+	}
+	ctx := context.Background()
+
+	if opts.Dir != "" {
+		if err := validateName(opts.Dir); err != nil {
+			return nil, "", err
+		}
+
+		unlock := s.locks.LockPaths(false, opts.Dir)
+		defer unlock()
+
+		if err := s.resolve(ctx, opts.Dir); err != nil {
+			return nil, "", err
+		}
+		if _, found, err := s.head(ctx, opts.Dir); err != nil {
+			return nil, "", err
+		} else if found {
+			return nil, "", fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, opts.Dir)
+		}
+		if found, _, err := s.dirInfo(ctx, opts.Dir); err != nil {
+			return nil, "", err
+		} else if !found {
+			return nil, "", fmt.Errorf("%w: %s", ErrNotFound, opts.Dir)
+		}
+	}
+
+	// Sem opts.Recursive, um prefixo com "/" não seleciona nada em
+	// opts.Dir, mas como prefixo da chave listaria objetos dos subdiretórios
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.dirKey(opts.Dir)),
+	}
+	if opts.Recursive || !strings.Contains(opts.Prefix, "/") {
+		input.Prefix = aws.String(s.dirKey(opts.Dir) + opts.Prefix)
+	}
+	if !opts.Recursive {
+		input.Delimiter = aws.String("/")
+	}
+
+	objects := make(map[string]types.Object)
+	dirs := make(map[string]time.Time)
+
+	pages := s3.NewListObjectsV2Paginator(s.client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("erro ao listar diretório %s: %w", opts.Dir, err)
+		}
+
+		for _, prefix := range page.CommonPrefixes {
+			name := strings.TrimSuffix(s.name(aws.ToString(prefix.Prefix)), "/")
+			if _, ok := dirs[name]; !ok {
+				dirs[name] = time.Time{}
+			}
+		}
+
+		for _, object := range page.Contents {
+			name := s.name(aws.ToString(object.Key))
+
+			// Nas listagens recursivas os diretórios intermediários só
+			// aparecem no caminho dos objetos
+			if opts.Recursive {
+				for dir := memoryParent(name); dir != opts.Dir && dir != ""; dir = memoryParent(dir) {
+					if _, ok := dirs[dir]; !ok {
+						dirs[dir] = time.Time{}
+					}
+				}
+			}
+
+			if strings.HasSuffix(name, "/") {
+				if dir := strings.TrimSuffix(name, "/"); dir != opts.Dir {
+					dirs[dir] = aws.ToTime(object.LastModified)
+				}
+				continue
+			}
+			if validateName(name) != nil {
+				// Objeto gravado por outra ferramenta com um nome que os
+				// clientes não conseguiriam usar
+				continue
+			}
+			objects[name] = object
+		}
+	}
+
+	names := make([]string, 0, len(objects)+len(dirs))
+	for name := range objects {
+		names = append(names, name)
+	}
+	for dir := range dirs {
+		if _, ok := objects[dir]; !ok && validateName(dir) == nil {
+			names = append(names, dir)
+		}
+	}
+
+	return listPage(names, opts, func(name string) (FileInfo, error) {
+		if object, ok := objects[name]; ok {
+			return s.listedInfo(ctx, name, object)
+		}
+		return FileInfo{Name: name, ModTime: dirs[name], IsDir: true}, nil
+	})
+}
+
+// UploadFile grava o conteúdo lido de r no arquivo especificado
+// Arquivos de até uma parte são enviados com PutObject e os maiores com um
+// upload multipart, lendo uma parte de cada vez. Em ambos os casos o objeto
+// só se torna visível, por inteiro, ao final do envio
+func (s *S3Storage) UploadFile(name string, r io.Reader, opts UploadOptions) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+	if err := validateTags(opts.Tags); err != nil {
+		return FileInfo{}, err
+	}
+	ctx := context.Background()
+
+	// A primeira parte é lida antes de qualquer requisição: se ela contiver o
+	// arquivo inteiro, basta um PutObject
+	hash := sha256.New()
+	r = io.TeeReader(r, hash)
+	buf := make([]byte, s.partSize)
+	n, last, err := readPart(r, buf)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+	}
+	contentType := DetectContentType(name, buf[:min(n, sniffLen)])
+
+	unlock := s.locks.LockPaths(true, name)
+	defer unlock()
+
+	if err := s.resolve(ctx, name); err != nil {
+		return FileInfo{}, err
+	}
+	if found, _, err := s.dirInfo(ctx, name); err != nil {
+		return FileInfo{}, err
+	} else if found {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+
+	// As condições são verificadas aqui e repetidas pelo S3 na gravação, com
+	// o ETag do objeto avaliado, para o caso de outro processo alterá-lo
+	var ifMatch, ifNoneMatch *string
+	if opts.conditional() {
+		current, found, err := s.head(ctx, name)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		var currentInfo *FileInfo
+		if found {
+			info, err := s.fileInfo(ctx, name, current)
+			if err != nil {
+				return FileInfo{}, err
+			}
+			currentInfo = &info
+			ifMatch = current.ETag
+		} else {
+			ifNoneMatch = aws.String("*")
+		}
+		if err := opts.check(name, currentInfo); err != nil {
+			return FileInfo{}, err
+		}
+	}
+
+	var size int64
+	var objectETag string
+	expiresAt := opts.expiresAt(time.Now())
+	tags := cloneTags(opts.Tags)
+	if last {
+		size = int64(n)
+		sum := hex.EncodeToString(hash.Sum(nil))
+		out, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(s.key(name)),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(size),
+			ContentType:   aws.String(contentType),
+			Metadata:      s3Metadata(sum, expiresAt, tags),
+			IfMatch:       ifMatch,
+			IfNoneMatch:   ifNoneMatch,
+		})
+		if err != nil {
+			return FileInfo{}, s.uploadError(name, err)
+		}
+		objectETag = aws.ToString(out.ETag)
+	} else {
+		size, objectETag, err = s.multipartUpload(ctx, name, r, buf, contentType, expiresAt, tags, ifMatch, ifNoneMatch)
+		if err != nil {
+			return FileInfo{}, err
+		}
+
+		// O SHA-256 só é conhecido ao final do envio; é gravado nos metadados
+		// copiando o objeto sobre ele mesmo. Acima do limite de CopyObject ele
+		// é calculado na primeira consulta
+		if size <= maxS3CopySize {
+			objectETag = s.attachChecksum(ctx, name, objectETag, contentType, s3Metadata(hex.EncodeToString(hash.Sum(nil)), expiresAt, tags))
+		}
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	info := FileInfo{
+		Name:        name,
+		Size:        size,
+		ModTime:     time.Now(),
+		SHA256:      sum,
+		ContentType: contentType,
+		ETag:        sum,
+		ExpiresAt:   expiresAt,
+		Tags:        tags,
+	}
+
+	// A data de modificação é a registrada pelo S3; o cache só é atualizado
+	// se o objeto ainda for o que acabou de ser gravado
+	if head, found, err := s.head(ctx, name); err == nil && found {
+		info.ModTime = aws.ToTime(head.LastModified)
+		if aws.ToString(head.ETag) == objectETag {
+			s.infoCache.Store(name, s3CachedInfo{objectETag: objectETag, info: info})
+		}
+	}
+
+	return info, nil
+}
+
+// multipartUpload envia o arquivo em partes: a primeira já lida em buf e as
+// seguintes lidas de r. Retorna o tamanho total e o ETag do objeto criado.
+// Em caso de erro o upload é abortado e nenhuma parte fica armazenada
+func (s *S3Storage) multipartUpload(ctx context.Context, name string, r io.Reader, buf []byte, contentType string, expiresAt time.Time, tags map[string]string, ifMatch, ifNoneMatch *string) (int64, string, error) {
+	key := s.key(name)
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Metadata:    s3Metadata("", expiresAt, tags),
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("erro ao iniciar upload de %s: %w", name, err)
+	}
+
+	abort := func(err error) (int64, string, error) {
+		s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		return 0, "", err
+	}
+
+	var parts []types.CompletedPart
+	var size int64
+	n, last := len(buf), false
+	for {
+		if len(parts) == maxS3Parts {
+			return abort(fmt.Errorf("erro ao escrever arquivo %s: mais de %d partes de %d bytes", name, maxS3Parts, s.partSize))
+		}
+
+		number := aws.Int32(int32(len(parts) + 1))
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(key),
+			UploadId:      created.UploadId,
+			PartNumber:    number,
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("erro ao enviar parte %d de %s: %w", *number, name, err))
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: number})
+		size += int64(n)
+
+		if last {
+			break
+		}
+		if n, last, err = readPart(r, buf); err != nil {
+			return abort(fmt.Errorf("erro ao escrever arquivo %s: %w", name, err))
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	out, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		IfMatch:         ifMatch,
+		IfNoneMatch:     ifNoneMatch,
+	})
+	if err != nil {
+		return abort(s.uploadError(name, err))
+	}
+
+	return size, aws.ToString(out.ETag), nil
+}
+
+// attachChecksum grava metadata, com o SHA-256, nos metadados de um objeto
+// recém-criado por um upload multipart e retorna o novo ETag do objeto. Se o
+// objeto já tiver sido substituído, ou a cópia falhar, ele é mantido como
+// está e o checksum será calculado na primeira consulta
+func (s *S3Storage) attachChecksum(ctx context.Context, name, objectETag, contentType string, metadata map[string]string) string {
+	out, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(s.key(name)),
+		CopySource:        aws.String(s.copySource(name)),
+		CopySourceIfMatch: aws.String(objectETag),
+		MetadataDirective: types.MetadataDirectiveReplace,
+		ContentType:       aws.String(contentType),
+		Metadata:          metadata,
+	})
+	if err != nil || out.CopyObjectResult == nil {
+		return objectETag
+	}
+	return aws.ToString(out.CopyObjectResult.ETag)
+}
+
+// DownloadFile abre para leitura o trecho de um arquivo selecionado por opts
+// O conteúdo é lido do S3 à medida que o io.ReadCloser é consumido. O
+// conteúdo inteiro é conferido com o SHA-256 gravado nos metadados do objeto;
+// objetos sem ele são conferidos com o checksum em cache, se o objeto não
+// mudou desde que foi calculado, ou apenas pelo tamanho
+func (s *S3Storage) DownloadFile(name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileInfo{}, err
+	}
+	ctx := context.Background()
+
+	if opts.Partial() {
+		return s.downloadRange(ctx, name, opts)
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if isS3NotFound(err) {
+		return nil, FileInfo{}, s.notFound(ctx, name)
+	}
+	if err != nil {
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir arquivo %s: %w", name, err)
+	}
+
+	info := s.downloadInfo(name, aws.ToString(out.ETag), FileInfo{
+		Name:        name,
+		Size:        aws.ToInt64(out.ContentLength),
+		ModTime:     aws.ToTime(out.LastModified),
+		SHA256:      out.Metadata[s3ChecksumMetadata],
+		ContentType: aws.ToString(out.ContentType),
+		ExpiresAt:   s3ExpiresAt(out.Metadata),
+		Tags:        s3Tags(out.Metadata),
+	})
+
+	content := verifyStored(out.Body, name, info.Size, info.SHA256)
+	return readCloser{content, out.Body}, info, nil
+}
+
+// downloadRange abre o trecho de name selecionado por opts com um GetObject
+// com Range. O tamanho do objeto, necessário para resolver o trecho, vem de
+// um HeadObject, e o GetObject exige o mesmo ETag para ler o mesmo objeto
+func (s *S3Storage) downloadRange(ctx context.Context, name string, opts DownloadOptions) (io.ReadCloser, FileInfo, error) {
+	head, found, err := s.head(ctx, name)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	if !found {
+		return nil, FileInfo{}, s.notFound(ctx, name)
+	}
+
+	info := s.downloadInfo(name, aws.ToString(head.ETag), FileInfo{
+		Name:        name,
+		Size:        aws.ToInt64(head.ContentLength),
+		ModTime:     aws.ToTime(head.LastModified),
+		SHA256:      head.Metadata[s3ChecksumMetadata],
+		ContentType: aws.ToString(head.ContentType),
+		ExpiresAt:   s3ExpiresAt(head.Metadata),
+		Tags:        s3Tags(head.Metadata),
+	})
+
+	offset, length, err := opts.Range(info.Size)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	// O S3 recusa um Range vazio
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), info, nil
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(s.key(name)),
+		IfMatch: head.ETag,
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if isS3NotFound(err) {
+		return nil, FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if isS3PreconditionFailed(err) {
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir arquivo %s: o arquivo foi substituído durante a consulta", name)
+	}
+	if err != nil {
+		return nil, FileInfo{}, fmt.Errorf("erro ao abrir arquivo %s: %w", name, err)
+	}
+
+	return out.Body, info, nil
+}
+
+// downloadInfo completa as informações info do objeto de name aberto para
+// download sem ler seu conteúdo: um objeto sem o SHA-256 nos metadados usa o
+// checksum em cache, se o objeto não mudou desde que foi calculado
+func (s *S3Storage) downloadInfo(name, objectETag string, info FileInfo) FileInfo {
+	if cached, ok := s.infoCache.Load(name); ok {
+		if c := cached.(s3CachedInfo); c.objectETag == objectETag {
+			info = c.info
+		}
+	}
+	info.ETag = info.SHA256
+	return info
+}
+
+// DeleteFile remove um arquivo ou um diretório vazio
+func (s *S3Storage) DeleteFile(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	unlock := s.locks.LockPaths(true, name)
+	defer unlock()
+
+	key := s.key(name)
+	if _, found, err := s.head(ctx, name); err != nil {
+		return err
+	} else if !found {
+		// Um diretório só pode ser removido se tiver apenas o marcador
+		keys, err := s.listKeys(ctx, s.dirKey(name), 2)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return s.notFound(ctx, name)
+		}
+		if len(keys) > 1 || keys[0] != s.dirKey(name) {
+			return fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, name)
+		}
+		key = s.dirKey(name)
+	}
+
+	if err := s.deleteObject(ctx, key); err != nil {
+		return fmt.Errorf("erro ao remover arquivo %s: %w", name, err)
+	}
+	s.infoCache.Delete(name)
+
+	return nil
+}
+
+// RenameFile renomeia ou move um arquivo ou diretório
+// O S3 não renomeia objetos: cada objeto é copiado para a nova chave e então
+// removido, então outros processos podem observar a operação pela metade
+func (s *S3Storage) RenameFile(oldName, newName string) error {
+	if err := validateName(oldName); err != nil {
+		return err
+	}
+	if err := validateName(newName); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+	if strings.HasPrefix(newName, oldName+"/") {
+		return fmt.Errorf("%w: %s não pode ser movido para dentro de si mesmo", ErrInvalidName, oldName)
+	}
+	ctx := context.Background()
+
+	unlock := s.locks.LockPaths(true, oldName, newName)
+	defer unlock()
+
+	source, isFile, err := s.head(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	// Para um diretório, todas as chaves sob ele, incluindo o marcador
+	var keys []string
+	if !isFile {
+		if keys, err = s.listKeys(ctx, s.dirKey(oldName), 0); err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return s.notFound(ctx, oldName)
+		}
+	}
+
+	if err := s.resolve(ctx, newName); err != nil {
+		return err
+	}
+	if _, found, err := s.head(ctx, newName); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, newName)
+	}
+	if found, _, err := s.dirInfo(ctx, newName); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %s", ErrIsDirectory, newName)
+	}
+
+	if isFile {
+		if err := s.copyObject(ctx, s.key(oldName), s.key(newName), aws.ToInt64(source.ContentLength)); err != nil {
+			return fmt.Errorf("erro ao renomear %s para %s: %w", oldName, newName, err)
+		}
+		if err := s.deleteObject(ctx, s.key(oldName)); err != nil {
+			return fmt.Errorf("erro ao renomear %s para %s: %w", oldName, newName, err)
+		}
+	} else {
+		oldPrefix, newPrefix := s.dirKey(oldName), s.dirKey(newName)
+		for _, key := range keys {
+			head, found, err := s.head(ctx, s.name(key))
+			if err != nil {
+				return err
+			}
+			if !found {
+				continue
+			}
+			target := newPrefix + strings.TrimPrefix(key, oldPrefix)
+			if err := s.copyObject(ctx, key, target, aws.ToInt64(head.ContentLength)); err != nil {
+				return fmt.Errorf("erro ao renomear %s para %s: %w", oldName, newName, err)
+			}
+		}
+		for _, key := range keys {
+			if err := s.deleteObject(ctx, key); err != nil {
+				return fmt.Errorf("erro ao renomear %s para %s: %w", oldName, newName, err)
+			}
+		}
+	}
+
+	// Descarta o cache do arquivo ou de todo o conteúdo do diretório movido
+	s.infoCache.Range(func(key, _ any) bool {
+		if k := key.(string); k == oldName || strings.HasPrefix(k, oldName+"/") {
+			s.infoCache.Delete(k)
+		}
+		return true
+	})
+
+	return nil
+}
+
+// StatFile retorna as informações de um arquivo ou diretório
+func (s *S3Storage) StatFile(name string) (FileInfo, error) {
+	if err := validateName(name); err != nil {
+		return FileInfo{}, err
+	}
+	ctx := context.Background()
+
+	head, found, err := s.head(ctx, name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if found {
+		return s.fileInfo(ctx, name, head)
+	}
+
+	isDir, modTime, err := s.dirInfo(ctx, name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if isDir {
+		return FileInfo{Name: name, ModTime: modTime, IsDir: true}, nil
+	}
+
+	return FileInfo{}, s.notFound(ctx, name)
+}
+
+// CreateDirectory cria um diretório, gravando seu marcador
+// Os diretórios intermediários existem implicitamente pelo caminho do marcador
+func (s *S3Storage) CreateDirectory(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	unlock := s.locks.LockPaths(true, name)
+	defer unlock()
+
+	if err := s.resolve(ctx, name); err != nil {
+		return err
+	}
+	if _, found, err := s.head(ctx, name); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, name)
+	}
+	if found, _, err := s.dirInfo(ctx, name); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, name)
+	}
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.dirKey(name)),
+		Body:          bytes.NewReader(nil),
+		ContentLength: aws.Int64(0),
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// fileInfo monta as informações de um arquivo a partir do HeadObject do seu
+// objeto. Objetos sem o SHA-256 nos metadados, como os gravados por outras
+// ferramentas, são lidos por inteiro para calculá-lo; o resultado fica em
+// cache enquanto o ETag do objeto não mudar
+func (s *S3Storage) fileInfo(ctx context.Context, name string, head *s3.HeadObjectOutput) (FileInfo, error) {
+	objectETag := aws.ToString(head.ETag)
+	if cached, ok := s.infoCache.Load(name); ok {
+		if c := cached.(s3CachedInfo); c.objectETag == objectETag {
+			return c.info, nil
+		}
+	}
+
+	info := FileInfo{
+		Name:        name,
+		Size:        aws.ToInt64(head.ContentLength),
+		ModTime:     aws.ToTime(head.LastModified),
+		SHA256:      head.Metadata[s3ChecksumMetadata],
+		ContentType: aws.ToString(head.ContentType),
+		ExpiresAt:   s3ExpiresAt(head.Metadata),
+		Tags:        s3Tags(head.Metadata),
+	}
+
+	if info.SHA256 == "" {
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:  aws.String(s.bucket),
+			Key:     aws.String(s.key(name)),
+			IfMatch: head.ETag,
+		})
+		if isS3NotFound(err) {
+			return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+		}
+		defer out.Body.Close()
+
+		sniff := &prefixWriter{limit: sniffLen}
+		sum, _, err := Checksum(io.TeeReader(out.Body, sniff))
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("erro ao ler arquivo %s: %w", name, err)
+		}
+		info.SHA256 = sum
+		if info.ContentType == "" || info.ContentType == "binary/octet-stream" {
+			info.ContentType = DetectContentType(name, sniff.buf)
+		}
+	}
+
+	info.ETag = info.SHA256
+	s.infoCache.Store(name, s3CachedInfo{objectETag: objectETag, info: info})
+
+	return info, nil
+}
+
+// listedInfo retorna as informações de um arquivo encontrado em uma listagem,
+// usando o cache se o objeto não mudou e consultando-o caso contrário
+func (s *S3Storage) listedInfo(ctx context.Context, name string, object types.Object) (FileInfo, error) {
+	if cached, ok := s.infoCache.Load(name); ok {
+		if c := cached.(s3CachedInfo); c.objectETag == aws.ToString(object.ETag) {
+			return c.info, nil
+		}
+	}
+
+	head, found, err := s.head(ctx, name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if !found {
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return s.fileInfo(ctx, name, head)
+}
+
+// s3Metadata monta os metadados de um objeto gravado por UploadFile, com o
+// SHA-256 do conteúdo, se conhecido, a expiração e as tags, se houver
+func s3Metadata(sum string, expiresAt time.Time, tags map[string]string) map[string]string {
+	metadata := make(map[string]string)
+	if sum != "" {
+		metadata[s3ChecksumMetadata] = sum
+	}
+	if !expiresAt.IsZero() {
+		metadata[s3ExpiresMetadata] = expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	if len(tags) > 0 {
+		values := make(url.Values, len(tags))
+		for key, value := range tags {
+			values.Set(key, value)
+		}
+		metadata[s3TagsMetadata] = values.Encode()
+	}
+	return metadata
+}
+
+// s3ExpiresAt lê a expiração dos metadados de um objeto, ou zero se o
+// objeto não expira
+func s3ExpiresAt(metadata map[string]string) time.Time {
+	expiresAt, err := time.Parse(time.RFC3339Nano, metadata[s3ExpiresMetadata])
+	if err != nil {
+		return time.Time{}
+	}
+	return expiresAt
+}
+
+// s3Tags lê as tags dos metadados de um objeto, ou nil se ele não tiver
+// tags. Tags ilegíveis, de um objeto gravado por outra ferramenta, são
+// ignoradas
+func s3Tags(metadata map[string]string) map[string]string {
+	values, err := url.ParseQuery(metadata[s3TagsMetadata])
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+
+	tags := make(map[string]string, len(values))
+	for key := range values {
+		tags[key] = values.Get(key)
+	}
+	if validateTags(tags) != nil {
+		return nil
+	}
+	return tags
+}
+
+// head consulta o objeto do arquivo name, indicando se ele existe
+func (s *S3Storage) head(ctx context.Context, name string) (*s3.HeadObjectOutput, bool, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if isS3NotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+	return out, true, nil
+}
+
+// dirInfo indica se name é um diretório, explícito ou implícito, e retorna a
+// data de criação do seu marcador, se houver
+func (s *S3Storage) dirInfo(ctx context.Context, name string) (bool, time.Time, error) {
+	out, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(s.dirKey(name)),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("erro ao consultar diretório %s: %w", name, err)
+	}
+	if len(out.Contents) == 0 {
+		return false, time.Time{}, nil
+	}
+
+	// O marcador é a primeira chave com o prefixo do diretório
+	var modTime time.Time
+	if aws.ToString(out.Contents[0].Key) == s.dirKey(name) {
+		modTime = aws.ToTime(out.Contents[0].LastModified)
+	}
+	return true, modTime, nil
+}
+
+// resolve recusa nomes cujo caminho passe por um arquivo, como o LocalStorage
+func (s *S3Storage) resolve(ctx context.Context, name string) error {
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if _, found, err := s.head(ctx, dir); err != nil {
+			return err
+		} else if found {
+			return fmt.Errorf("%w: %s não é um diretório", ErrInvalidName, dir)
+		}
+	}
+	return nil
+}
+
+// notFound monta o erro de uma operação sobre name que não encontrou o
+// arquivo, distinguindo um caminho inválido ou um diretório
+func (s *S3Storage) notFound(ctx context.Context, name string) error {
+	if err := s.resolve(ctx, name); err != nil {
+		return err
+	}
+	if isDir, _, err := s.dirInfo(ctx, name); err != nil {
+		return err
+	} else if isDir {
+		return fmt.Errorf("%w: %s", ErrIsDirectory, name)
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// listKeys retorna as chaves com o prefixo informado, até limit chaves se
+// limit for positivo
+func (s *S3Storage) listKeys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	if limit > 0 {
+		input.MaxKeys = aws.Int32(int32(limit))
+	}
+
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar %s: %w", prefix, err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+			if limit > 0 && len(keys) == limit {
+				return keys, nil
+			}
+		}
+	}
+	return keys, nil
+}
+
+// copyObject copia o objeto source para target, mantendo seus metadados
+// Objetos acima do limite de CopyObject são copiados em partes
+func (s *S3Storage) copyObject(ctx context.Context, source, target string, size int64) error {
+	if size <= maxS3CopySize {
+		_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(target),
+			CopySource: aws.String(s.copySource(s.name(source))),
+		})
+		return err
+	}
+
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(source),
+	})
+	if err != nil {
+		return err
+	}
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(target),
+		ContentType: head.ContentType,
+		Metadata:    head.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Partes grandes o bastante para não exceder o número máximo de partes
+	partSize := max(s.partSize, (size+maxS3Parts-1)/maxS3Parts)
+
+	var parts []types.CompletedPart
+	for offset := int64(0); offset < size; offset += partSize {
+		number := aws.Int32(int32(len(parts) + 1))
+		out, err := s.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(s.bucket),
+			Key:               aws.String(target),
+			UploadId:          created.UploadId,
+			PartNumber:        number,
+			CopySource:        aws.String(s.copySource(s.name(source))),
+			CopySourceIfMatch: head.ETag,
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, min(offset+partSize, size)-1)),
+		})
+		if err == nil && out.CopyPartResult == nil {
+			err = fmt.Errorf("resposta sem resultado da cópia da parte %d", *number)
+		}
+		if err != nil {
+			s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(s.bucket),
+				Key:      aws.String(target),
+				UploadId: created.UploadId,
+			})
+			return err
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.CopyPartResult.ETag, PartNumber: number})
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(target),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// deleteObject remove o objeto key
+func (s *S3Storage) deleteObject(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// uploadError converte o erro de uma gravação de name, identificando as
+// condições recusadas pelo S3
+func (s *S3Storage) uploadError(name string, err error) error {
+	if isS3PreconditionFailed(err) {
+		return fmt.Errorf("%w: %s foi alterado por outro processo", ErrPreconditionFailed, name)
+	}
+	return fmt.Errorf("erro ao escrever arquivo %s: %w", name, err)
+}
+
+// key retorna a chave do objeto do arquivo name
+func (s *S3Storage) key(name string) string {
+	return s.prefix + name
+}
+
+// dirKey retorna a chave do marcador do diretório name, que também é o
+// prefixo das chaves do seu conteúdo; para a raiz, o próprio prefixo
+func (s *S3Storage) dirKey(name string) string {
+	if name == "" {
+		return s.prefix
+	}
+	return s.prefix + name + "/"
+}
+
+// name retorna o nome correspondente a uma chave
+func (s *S3Storage) name(key string) string {
+	return strings.TrimPrefix(key, s.prefix)
+}
+
+// copySource retorna o valor de CopySource que identifica o objeto de name,
+// com cada componente codificado para a URL
+func (s *S3Storage) copySource(name string) string {
+	parts := strings.Split(s.key(name), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return s.bucket + "/" + strings.Join(parts, "/")
+}
+
+// readPart lê de r até preencher buf, indicando se o fim do stream foi atingido
+func readPart(r io.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	return n, false, err
+}
+
+// isS3NotFound indica se err é a resposta do S3 para um objeto inexistente
+func isS3NotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NoSuchKey", "NotFound":
+		return true
+	}
+	return false
+}
+
+// isS3PreconditionFailed indica se err é a resposta do S3 para uma gravação
+// condicional recusada
+func isS3PreconditionFailed(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,665 @@
+package common
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3Object é um objeto armazenado pelo fakeS3
+type fakeS3Object struct {
+	data        []byte
+	etag        string
+	contentType string
+	metadata    map[string]string
+	modTime     time.Time
+}
+
+// fakeS3Upload é um upload multipart em andamento no fakeS3
+type fakeS3Upload struct {
+	key         string
+	contentType string
+	metadata    map[string]string
+	parts       map[int][]byte
+}
+
+// fakeS3 é um servidor S3 em memória com o subconjunto da API usado pelo
+// S3Storage: objetos com metadados, leituras com Range, gravações e cópias
+// condicionais, uploads multipart e listagens paginadas com delimitador. O
+// bucket é endereçado no caminho da URL e as assinaturas não são conferidas
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeS3Object
+	uploads map[string]*fakeS3Upload
+	nextID  int
+
+	// pageSize limita o número de entradas por página das listagens, para
+	// exercitar a paginação; zero usa o max-keys da requisição
+	pageSize int
+
+	// beforeWrite, se definida, é chamada com a chave de cada gravação antes
+	// de as condições serem avaliadas, simulando outro processo que altera o
+	// objeto no meio do upload
+	beforeWrite func(key string)
+
+	// Contadores das requisições recebidas
+	puts, multipartParts, copies, completed, aborted int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: make(map[string]*fakeS3Object),
+		uploads: make(map[string]*fakeS3Upload),
+	}
+}
+
+// newTestS3Storage cria um S3Storage sobre um fakeS3 servido por httptest
+func newTestS3Storage(t *testing.T, fake *fakeS3) *S3Storage {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3Storage(S3StorageOptions{
+		Bucket:          "bucket",
+		Prefix:          "fileshare",
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyID:     "teste",
+		SecretAccessKey: "teste",
+		PartSize:        minS3PartSize,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s
+}
+
+// fakeETag retorna o ETag de um objeto com o conteúdo data
+func fakeETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		// HeadBucket
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, query)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = &fakeS3Upload{key: key, contentType: r.Header.Get("Content-Type"), metadata: fakeMetadata(r), parts: make(map[int][]byte)}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, id)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		f.uploadPart(w, r, query)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, r, key, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborted++
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		f.copy(w, r, key)
+	case r.Method == http.MethodPut:
+		if f.beforeWrite != nil {
+			f.beforeWrite(key)
+		}
+		if !f.checkConditions(w, r, f.objects[key]) {
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.puts++
+		object := &fakeS3Object{data: data, etag: fakeETag(data), contentType: r.Header.Get("Content-Type"), metadata: fakeMetadata(r), modTime: time.Now()}
+		f.objects[key] = object
+		w.Header().Set("ETag", object.etag)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		f.get(w, r, key)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeS3Error(w, r, http.StatusBadRequest, "InvalidRequest")
+	}
+}
+
+// list responde a um ListObjectsV2, com continuation-token igual à última
+// chave ou prefixo comum da página anterior
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	limit := 1000
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		limit, _ = strconv.Atoi(maxKeys)
+	}
+	if f.pageSize > 0 {
+		limit = min(limit, f.pageSize)
+	}
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		CommonPrefixes        []commonPrefix
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+
+	after := query.Get("continuation-token")
+	for _, key := range keys {
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= after {
+			continue
+		}
+		if result.KeyCount == limit {
+			result.IsTruncated = true
+			break
+		}
+
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{entry})
+		} else {
+			object := f.objects[key]
+			result.Contents = append(result.Contents, content{key, object.modTime.UTC().Format(time.RFC3339), object.etag, len(object.data)})
+		}
+		result.KeyCount++
+		result.NextContinuationToken = entry
+		after = entry
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	xml.NewEncoder(w).Encode(result)
+}
+
+// get responde a um HeadObject ou GetObject, com Range e If-Match
+func (f *fakeS3) get(w http.ResponseWriter, r *http.Request, key string) {
+	object := f.objects[key]
+	if object == nil {
+		fakeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	if !f.checkConditions(w, r, object) {
+		return
+	}
+
+	w.Header().Set("ETag", object.etag)
+	w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+	if object.contentType != "" {
+		w.Header().Set("Content-Type", object.contentType)
+	}
+	for k, v := range object.metadata {
+		w.Header().Set("X-Amz-Meta-"+k, v)
+	}
+
+	data := object.data
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" && r.Method == http.MethodGet {
+		var first, last int
+		fmt.Sscanf(rng, "bytes=%d-%d", &first, &last)
+		data = data[first:min(last+1, len(data))]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+// uploadPart responde a um UploadPart ou UploadPartCopy
+func (f *fakeS3) uploadPart(w http.ResponseWriter, r *http.Request, query url.Values) {
+	upload := f.uploads[query.Get("uploadId")]
+	if upload == nil {
+		fakeS3Error(w, r, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	number, _ := strconv.Atoi(query.Get("partNumber"))
+
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		source := f.copySource(r)
+		if source == nil {
+			fakeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		var first, last int
+		fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &first, &last)
+		part := bytes.Clone(source.data[first : last+1])
+		upload.parts[number] = part
+		fmt.Fprintf(w, "<CopyPartResult><ETag>%s</ETag></CopyPartResult>", fakeETag(part))
+		return
+	}
+
+	part, _ := io.ReadAll(r.Body)
+	upload.parts[number] = part
+	f.multipartParts++
+	w.Header().Set("ETag", fakeETag(part))
+}
+
+// complete responde a um CompleteMultipartUpload, com If-Match e If-None-Match
+func (f *fakeS3) complete(w http.ResponseWriter, r *http.Request, key, id string) {
+	upload := f.uploads[id]
+	if upload == nil {
+		fakeS3Error(w, r, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+	if f.beforeWrite != nil {
+		f.beforeWrite(key)
+	}
+	if !f.checkConditions(w, r, f.objects[key]) {
+		return
+	}
+
+	var data []byte
+	for i := 1; i <= len(upload.parts); i++ {
+		data = append(data, upload.parts[i]...)
+	}
+	object := &fakeS3Object{
+		data:        data,
+		etag:        fmt.Sprintf(`"%s-%d"`, strings.Trim(fakeETag(data), `"`), len(upload.parts)),
+		contentType: upload.contentType,
+		metadata:    upload.metadata,
+		modTime:     time.Now(),
+	}
+	f.objects[key] = object
+	delete(f.uploads, id)
+	f.completed++
+	fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>", key, object.etag)
+}
+
+// copy responde a um CopyObject, com x-amz-copy-source-if-match e a
+// substituição dos metadados
+func (f *fakeS3) copy(w http.ResponseWriter, r *http.Request, key string) {
+	source := f.copySource(r)
+	if source == nil {
+		fakeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	if etag := r.Header.Get("X-Amz-Copy-Source-If-Match"); etag != "" && etag != source.etag {
+		fakeS3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	object := &fakeS3Object{data: source.data, etag: fakeETag(source.data), contentType: source.contentType, metadata: source.metadata, modTime: time.Now()}
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		object.contentType, object.metadata = r.Header.Get("Content-Type"), fakeMetadata(r)
+	}
+	f.objects[key] = object
+	f.copies++
+	fmt.Fprintf(w, "<CopyObjectResult><ETag>%s</ETag><LastModified>%s</LastModified></CopyObjectResult>", object.etag, object.modTime.UTC().Format(time.RFC3339))
+}
+
+// copySource retorna o objeto indicado em x-amz-copy-source, ou nil
+func (f *fakeS3) copySource(r *http.Request) *fakeS3Object {
+	source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	_, key, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	return f.objects[key]
+}
+
+// checkConditions avalia If-Match e If-None-Match contra current, o objeto
+// atual, respondendo 412 se alguma falhar
+func (f *fakeS3) checkConditions(w http.ResponseWriter, r *http.Request, current *fakeS3Object) bool {
+	if etag := r.Header.Get("If-Match"); etag != "" && (current == nil || current.etag != etag) {
+		fakeS3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+		return false
+	}
+	if r.Header.Get("If-None-Match") == "*" && current != nil {
+		fakeS3Error(w, r, http.StatusPreconditionFailed, "PreconditionFailed")
+		return false
+	}
+	return true
+}
+
+// fakeMetadata retorna os metadados x-amz-meta-* de uma gravação
+func fakeMetadata(r *http.Request) map[string]string {
+	metadata := make(map[string]string)
+	for header, values := range r.Header {
+		if name, ok := strings.CutPrefix(strings.ToLower(header), "x-amz-meta-"); ok {
+			metadata[name] = values[0]
+		}
+	}
+	return metadata
+}
+
+// fakeS3Error responde com um erro no formato do S3
+func fakeS3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+	}
+}
+
+// downloadAll baixa o trecho de name selecionado por opts
+func downloadAll(s FileService, name string, opts DownloadOptions) ([]byte, FileInfo, error) {
+	rc, info, err := s.DownloadFile(name, opts)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	return data, info, err
+}
+
+// TestS3PutGetRange grava um arquivo com PutObject e o lê inteiro, em
+// trechos e com StatFile
+func TestS3PutGetRange(t *testing.T) {
+	fake := newFakeS3()
+	s := newTestS3Storage(t, fake)
+
+	content := []byte(strings.Repeat("0123456789", 1000))
+	uploaded, err := s.UploadFile("docs/a.txt", bytes.NewReader(content), UploadOptions{TTL: time.Hour, Tags: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if fake.puts != 1 || fake.completed != 0 {
+		t.Fatalf("%d PutObject e %d multipart, esperado um PutObject", fake.puts, fake.completed)
+	}
+	if _, ok := fake.objects["fileshare/docs/a.txt"]; !ok {
+		t.Fatalf("objeto gravado fora do prefixo: %v", fake.objects)
+	}
+
+	data, info, err := downloadAll(s, "docs/a.txt", DownloadOptions{})
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("DownloadFile: %d bytes, %v", len(data), err)
+	}
+	if info.SHA256 != uploaded.SHA256 || info.Size != int64(len(content)) || info.Tags["env"] != "prod" {
+		t.Errorf("DownloadFile: informações %+v, esperado %+v", info, uploaded)
+	}
+
+	for _, opts := range []DownloadOptions{{Offset: 5, Length: 10}, {Offset: -7}, {Offset: 9990, Length: 100}} {
+		data, _, err := downloadAll(s, "docs/a.txt", opts)
+		offset, length, _ := opts.Range(int64(len(content)))
+		if err != nil || !bytes.Equal(data, content[offset:offset+length]) {
+			t.Errorf("DownloadFile %+v: %q, %v", opts, data, err)
+		}
+	}
+	if _, _, err := downloadAll(s, "docs/a.txt", DownloadOptions{Offset: int64(len(content)) + 1}); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("DownloadFile além do fim: %v, esperado ErrInvalidRange", err)
+	}
+
+	stat, err := s.StatFile("docs/a.txt")
+	if err != nil || stat.SHA256 != uploaded.SHA256 || stat.ExpiresAt.IsZero() || stat.ContentType != uploaded.ContentType {
+		t.Errorf("StatFile: %+v, %v", stat, err)
+	}
+	if stat, err := s.StatFile("docs"); err != nil || !stat.IsDir {
+		t.Errorf("StatFile do diretório implícito: %+v, %v", stat, err)
+	}
+	if _, _, err := downloadAll(s, "nope.txt", DownloadOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DownloadFile inexistente: %v, esperado ErrNotFound", err)
+	}
+
+	// Um objeto gravado por outra ferramenta, sem o SHA-256 nos metadados,
+	// tem o checksum calculado na consulta
+	fake.objects["fileshare/foreign.txt"] = &fakeS3Object{data: []byte("hello"), etag: fakeETag([]byte("hello")), modTime: time.Now()}
+	if stat, err := s.StatFile("foreign.txt"); err != nil || stat.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("StatFile de objeto externo: %+v, %v", stat, err)
+	}
+}
+
+// TestS3Multipart grava arquivos maiores que uma parte, que usam upload
+// multipart seguido da cópia que grava o SHA-256 nos metadados
+func TestS3Multipart(t *testing.T) {
+	fake := newFakeS3()
+	s := newTestS3Storage(t, fake)
+
+	content := make([]byte, 2*minS3PartSize+12345)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	uploaded, err := s.UploadFile("big.bin", bytes.NewReader(content), UploadOptions{Tags: map[string]string{"size": "big"}})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if fake.multipartParts != 3 || fake.completed != 1 || fake.puts != 0 {
+		t.Fatalf("%d partes, %d multipart e %d PutObject, esperado 3 partes em um multipart", fake.multipartParts, fake.completed, fake.puts)
+	}
+
+	// attachChecksum regrava os metadados com o SHA-256
+	object := fake.objects["fileshare/big.bin"]
+	if object.metadata[s3ChecksumMetadata] != uploaded.SHA256 || object.metadata[s3TagsMetadata] != "size=big" {
+		t.Errorf("metadados do objeto: %v", object.metadata)
+	}
+
+	data, info, err := downloadAll(s, "big.bin", DownloadOptions{})
+	if err != nil || !bytes.Equal(data, content) || info.SHA256 != uploaded.SHA256 {
+		t.Fatalf("DownloadFile: %d bytes, %+v, %v", len(data), info, err)
+	}
+	data, _, err = downloadAll(s, "big.bin", DownloadOptions{Offset: minS3PartSize - 3, Length: 6})
+	if err != nil || !bytes.Equal(data, content[minS3PartSize-3:minS3PartSize+3]) {
+		t.Errorf("DownloadFile de trecho entre partes: %v, %v", data, err)
+	}
+
+	// Um envio que falha no meio aborta o upload sem deixar o objeto
+	failing := io.MultiReader(bytes.NewReader(content[:minS3PartSize+10]), failingReader{})
+	if _, err := s.UploadFile("broken.bin", failing, UploadOptions{}); err == nil {
+		t.Fatal("UploadFile com leitura falha: esperado erro")
+	}
+	if _, ok := fake.objects["fileshare/broken.bin"]; ok || len(fake.uploads) != 0 || fake.aborted != 1 {
+		t.Errorf("upload falho deixou objeto ou partes: %d uploads, %d abortados", len(fake.uploads), fake.aborted)
+	}
+}
+
+// failingReader falha em toda leitura
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("conexão perdida") }
+
+// TestS3Conditional confere as gravações condicionais, avaliadas pelo
+// S3Storage e repetidas pelo S3 na gravação
+func TestS3Conditional(t *testing.T) {
+	fake := newFakeS3()
+	s := newTestS3Storage(t, fake)
+
+	first, err := s.UploadFile("a.txt", strings.NewReader("primeira"), UploadOptions{IfNoneMatch: "*"})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if _, err := s.UploadFile("a.txt", strings.NewReader("outra"), UploadOptions{IfNoneMatch: "*"}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("If-None-Match com arquivo existente: %v, esperado ErrPreconditionFailed", err)
+	}
+	if _, err := s.UploadFile("a.txt", strings.NewReader("outra"), UploadOptions{IfMatch: "etag-antigo"}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("If-Match com ETag antigo: %v, esperado ErrPreconditionFailed", err)
+	}
+	if _, err := s.UploadFile("b.txt", strings.NewReader("outra"), UploadOptions{IfMatch: first.ETag}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("If-Match com arquivo inexistente: %v, esperado ErrPreconditionFailed", err)
+	}
+	second, err := s.UploadFile("a.txt", strings.NewReader("segunda"), UploadOptions{IfMatch: first.ETag})
+	if err != nil {
+		t.Fatalf("If-Match com ETag atual: %v", err)
+	}
+
+	// Outro processo grava o objeto entre a verificação e a gravação: o S3
+	// recusa a gravação pela condição enviada junto com ela
+	for _, content := range []string{"terceira", strings.Repeat("x", minS3PartSize+1)} {
+		fake.beforeWrite = func(key string) {
+			fake.objects[key] = &fakeS3Object{data: []byte("de outro processo"), etag: `"outro"`, modTime: time.Now()}
+		}
+		_, err := s.UploadFile("a.txt", strings.NewReader(content), UploadOptions{IfMatch: second.ETag})
+		fake.beforeWrite = nil
+		if !errors.Is(err, ErrPreconditionFailed) {
+			t.Errorf("If-Match com objeto alterado durante o upload de %d bytes: %v, esperado ErrPreconditionFailed", len(content), err)
+		}
+		if string(fake.objects["fileshare/a.txt"].data) != "de outro processo" {
+			t.Errorf("upload recusado substituiu o objeto")
+		}
+	}
+	if len(fake.uploads) != 0 {
+		t.Errorf("%d uploads multipart restantes", len(fake.uploads))
+	}
+}
+
+// TestS3List lista diretórios explícitos e implícitos, com páginas do S3
+// menores que a listagem
+func TestS3List(t *testing.T) {
+	fake := newFakeS3()
+	fake.pageSize = 2
+	s := newTestS3Storage(t, fake)
+
+	for _, name := range []string{"a.txt", "b.txt", "docs/c.txt", "docs/2024/d.txt", "docs/2024/e.txt"} {
+		if _, err := s.UploadFile(name, strings.NewReader(name), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile %s: %v", name, err)
+		}
+	}
+	if err := s.CreateDirectory("empty"); err != nil {
+		t.Fatalf("CreateDirectory: %v", err)
+	}
+
+	for _, tc := range []struct {
+		opts ListOptions
+		want string
+	}{
+		{ListOptions{}, "a.txt b.txt docs/ empty/"},
+		{ListOptions{Recursive: true}, "a.txt b.txt docs/ docs/2024/ docs/2024/d.txt docs/2024/e.txt docs/c.txt empty/"},
+		{ListOptions{Dir: "docs"}, "docs/2024/ docs/c.txt"},
+		{ListOptions{Dir: "docs", Recursive: true, Pattern: "*.txt"}, "docs/2024/d.txt docs/2024/e.txt docs/c.txt"},
+		{ListOptions{Recursive: true, Prefix: "docs/2024/"}, "docs/2024/d.txt docs/2024/e.txt"},
+	} {
+		var got []string
+		opts := tc.opts
+		opts.PageSize = 3
+		for {
+			files, next, err := s.ListFiles(opts)
+			if err != nil {
+				t.Fatalf("ListFiles %+v: %v", opts, err)
+			}
+			for _, file := range files {
+				if file.IsDir {
+					got = append(got, file.Name+"/")
+				} else {
+					got = append(got, file.Name)
+				}
+			}
+			if next == "" {
+				break
+			}
+			opts.PageToken = next
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("ListFiles %+v: %v, esperado %s", tc.opts, got, tc.want)
+		}
+	}
+
+	if _, _, err := s.ListFiles(ListOptions{Dir: "nope"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListFiles de diretório inexistente: %v, esperado ErrNotFound", err)
+	}
+	if _, _, err := s.ListFiles(ListOptions{Dir: "a.txt"}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("ListFiles de arquivo: %v, esperado ErrInvalidName", err)
+	}
+}
+
+// TestS3RenameDelete renomeia arquivos e diretórios, com cópia e remoção
+// dos objetos, e remove arquivos e diretórios
+func TestS3RenameDelete(t *testing.T) {
+	fake := newFakeS3()
+	s := newTestS3Storage(t, fake)
+
+	uploaded, err := s.UploadFile("a.txt", strings.NewReader("conteúdo"), UploadOptions{Tags: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	for _, name := range []string{"docs/x.txt", "docs/sub/y.txt"} {
+		if _, err := s.UploadFile(name, strings.NewReader(name), UploadOptions{}); err != nil {
+			t.Fatalf("UploadFile %s: %v", name, err)
+		}
+	}
+	if err := s.CreateDirectory("docs/empty"); err != nil {
+		t.Fatalf("CreateDirectory: %v", err)
+	}
+
+	if err := s.RenameFile("a.txt", "moved/a.txt"); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	if _, ok := fake.objects["fileshare/a.txt"]; ok {
+		t.Error("objeto de origem não foi removido")
+	}
+	data, info, err := downloadAll(s, "moved/a.txt", DownloadOptions{})
+	if err != nil || string(data) != "conteúdo" || info.SHA256 != uploaded.SHA256 || info.Tags["env"] != "prod" {
+		t.Errorf("arquivo renomeado: %q, %+v, %v", data, info, err)
+	}
+
+	if err := s.RenameFile("docs", "archive/docs"); err != nil {
+		t.Fatalf("RenameFile de diretório: %v", err)
+	}
+	for _, name := range []string{"archive/docs/x.txt", "archive/docs/sub/y.txt"} {
+		if data, _, err := downloadAll(s, name, DownloadOptions{}); err != nil || string(data) != strings.TrimPrefix(name, "archive/") {
+			t.Errorf("%s: %q, %v", name, data, err)
+		}
+	}
+	if stat, err := s.StatFile("archive/docs/empty"); err != nil || !stat.IsDir {
+		t.Errorf("marcador do diretório vazio: %+v, %v", stat, err)
+	}
+	for key := range fake.objects {
+		if strings.HasPrefix(key, "fileshare/docs/") {
+			t.Errorf("objeto de origem restante: %s", key)
+		}
+	}
+
+	if err := s.RenameFile("moved/a.txt", "archive/docs/x.txt"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("RenameFile sobre arquivo existente: %v, esperado ErrAlreadyExists", err)
+	}
+	if err := s.RenameFile("nope", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RenameFile inexistente: %v, esperado ErrNotFound", err)
+	}
+	if err := s.RenameFile("archive", "archive/inside"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("RenameFile para dentro de si mesmo: %v, esperado ErrInvalidName", err)
+	}
+
+	if err := s.DeleteFile("archive/docs"); !errors.Is(err, ErrDirectoryNotEmpty) {
+		t.Errorf("DeleteFile de diretório com conteúdo: %v, esperado ErrDirectoryNotEmpty", err)
+	}
+	if err := s.DeleteFile("archive/docs/empty"); err != nil {
+		t.Errorf("DeleteFile de diretório vazio: %v", err)
+	}
+	if err := s.DeleteFile("moved/a.txt"); err != nil {
+		t.Errorf("DeleteFile: %v", err)
+	}
+	if _, err := s.StatFile("moved/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("StatFile depois de DeleteFile: %v, esperado ErrNotFound", err)
+	}
+	if err := s.DeleteFile("moved/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteFile repetido: %v, esperado ErrNotFound", err)
+	}
+}
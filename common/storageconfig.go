@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// KeysEnv é a variável de ambiente com as chaves mestras dos servidores, no
+// formato de ParseKeyRing, usada quando não há um arquivo de chaves
+const KeysEnv = "ENCRYPTION_KEYS"
+
+// StorageConfig descreve o FileService que os servidores criam a partir das
+// suas flags com NewStorage
+type StorageConfig struct {
+	// Kind é o armazenamento: local, memory, dedup ou s3
+	Kind string
+
+	// DataDir é o diretório de dados dos armazenamentos local e dedup e das
+	// chaves de dados, com qualquer armazenamento
+	DataDir string
+
+	// Local configura o armazenamento local; o memory só usa Versions
+	Local LocalStorageOptions
+
+	// S3 configura o armazenamento s3
+	S3 S3StorageOptions
+
+	// Keys, se não for nil, faz o FileService cifrar os arquivos, com as
+	// chaves de dados em KeysDirName dentro de DataDir. Conteúdo cifrado não
+	// diminui com a compressão, então Local.CompressionLevel é ignorado
+	Keys *KeyRing
+}
+
+// NewStorage cria o FileService descrito por cfg
+func NewStorage(cfg StorageConfig) (FileService, error) {
+	localOpts := cfg.Local
+	if cfg.Keys != nil {
+		localOpts.CompressionLevel = 0
+	}
+
+	var storage FileService
+	var err error
+	switch cfg.Kind {
+	case "local":
+		storage, err = NewLocalStorage(cfg.DataDir, localOpts)
+	case "memory":
+		storage = NewMemoryStorage(MemoryStorageOptions{Versions: localOpts.Versions})
+	case "dedup":
+		storage, err = NewDedupStorage(cfg.DataDir)
+	case "s3":
+		storage, err = NewS3Storage(cfg.S3)
+	default:
+		return nil, fmt.Errorf("armazenamento desconhecido: %s (use local, memory, dedup ou s3)", cfg.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Keys == nil {
+		return storage, nil
+	}
+	storage, err = NewEncryptedStorage(storage, EncryptionOptions{
+		Keys:    cfg.Keys,
+		KeysDir: filepath.Join(cfg.DataDir, KeysDirName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao configurar criptografia: %w", err)
+	}
+	return storage, nil
+}
+
+// LoadServerKeyRing carrega as chaves mestras de keyFile ou, se vazio, da
+// variável KeysEnv; retorna nil se nenhuma das duas estiver definida
+func LoadServerKeyRing(keyFile string) (*KeyRing, error) {
+	if keyFile != "" {
+		return LoadKeyRing(keyFile)
+	}
+	if env := os.Getenv(KeysEnv); env != "" {
+		return ParseKeyRing(env)
+	}
+	return nil, nil
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewStorage confere o FileService criado para cada configuração dos
+// servidores
+func TestNewStorage(t *testing.T) {
+	if _, err := NewStorage(StorageConfig{Kind: "ftp", DataDir: t.TempDir()}); err == nil {
+		t.Errorf("NewStorage com armazenamento desconhecido não falhou")
+	}
+
+	memory, err := NewStorage(StorageConfig{Kind: "memory", Local: LocalStorageOptions{Versions: VersionPolicy{MaxVersions: 1}}})
+	if err != nil {
+		t.Fatalf("NewStorage(memory): %v", err)
+	}
+	if _, ok := memory.(Versioner); !ok {
+		t.Errorf("memory sem Versioner com versionamento ativo")
+	}
+
+	// Com chaves, o armazenamento é cifrado, mantém a lixeira do
+	// armazenamento local e não compacta
+	dir := t.TempDir()
+	content := strings.Repeat("texto compressível ", 1000)
+	encrypted, err := NewStorage(StorageConfig{
+		Kind:    "local",
+		DataDir: dir,
+		Local:   LocalStorageOptions{CompressionLevel: MaxCompressionLevel, TrashRetention: time.Hour},
+		Keys:    testKeyRing(t, testKey(t, "k1")),
+	})
+	if err != nil {
+		t.Fatalf("NewStorage(local) com chaves: %v", err)
+	}
+	if _, ok := encrypted.(Trasher); !ok {
+		t.Errorf("armazenamento cifrado sem Trasher com a lixeira ativa")
+	}
+	if _, ok := encrypted.(KeyCollector); !ok {
+		t.Errorf("armazenamento cifrado sem KeyCollector")
+	}
+	uploadString(t, encrypted, "a.txt", content)
+	checkDownload(t, encrypted, "a.txt", content)
+
+	stat, err := os.Stat(filepath.Join(dir, "a.txt"))
+	if err != nil || stat.Size() < int64(len(content)) {
+		t.Errorf("arquivo cifrado em disco: %v, %v; esperado sem compressão", stat, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, KeysDirName)); err != nil {
+		t.Errorf("chaves de dados fora de %s: %v", KeysDirName, err)
+	}
+}
+
+// TestLoadServerKeyRing confere que o arquivo de chaves tem precedência sobre
+// KeysEnv e que sem nenhum dos dois não há chaves
+func TestLoadServerKeyRing(t *testing.T) {
+	t.Setenv(KeysEnv, "")
+	if keys, err := LoadServerKeyRing(""); keys != nil || err != nil {
+		t.Errorf("LoadServerKeyRing sem chaves: %v, %v", keys, err)
+	}
+
+	t.Setenv(KeysEnv, testKey(t, "env"))
+	if keys, err := LoadServerKeyRing(""); err != nil || keys.CurrentID() != "env" {
+		t.Errorf("LoadServerKeyRing de %s: %v, %v", KeysEnv, keys, err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keyFile, []byte(testKey(t, "file")+"\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if keys, err := LoadServerKeyRing(keyFile); err != nil || keys.CurrentID() != "file" {
+		t.Errorf("LoadServerKeyRing(%s): %v, %v", keyFile, keys, err)
+	}
+}
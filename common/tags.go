@@ -0,0 +1,140 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limites das tags de um arquivo (ver UploadOptions.Tags)
+const (
+	MaxTags           = 16
+	MaxTagKeyLength   = 63
+	MaxTagValueLength = 255
+)
+
+// ParseTag converte uma tag no formato "chave=valor", como usado nos
+// clientes, na sua chave e no seu valor
+func ParseTag(s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("%w: %q não está no formato chave=valor", ErrInvalidTags, s)
+	}
+	if err := validateTag(key, value); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// validateTags confere as tags de um upload
+func validateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: %d tags (o máximo é %d)", ErrInvalidTags, len(tags), MaxTags)
+	}
+	for key, value := range tags {
+		if err := validateTag(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTag confere uma tag. As chaves têm letras, dígitos, ".", "_" e
+// "-"; os valores podem ser vazios e não têm vírgulas nem caracteres de
+// controle, para que caibam em um seletor (ver ParseTagSelector)
+func validateTag(key, value string) error {
+	if key == "" || len(key) > MaxTagKeyLength {
+		return fmt.Errorf("%w: a chave %q deve ter de 1 a %d caracteres", ErrInvalidTags, key, MaxTagKeyLength)
+	}
+	for _, c := range key {
+		if !validTagKeyChar(c) {
+			return fmt.Errorf("%w: caractere %q não permitido na chave %q", ErrInvalidTags, c, key)
+		}
+	}
+
+	if len(value) > MaxTagValueLength {
+		return fmt.Errorf("%w: o valor de %s tem mais de %d bytes", ErrInvalidTags, key, MaxTagValueLength)
+	}
+	if !utf8.ValidString(value) || strings.ContainsFunc(value, func(c rune) bool { return c == ',' || unicode.IsControl(c) }) {
+		return fmt.Errorf("%w: o valor de %s não pode ter vírgulas nem caracteres de controle", ErrInvalidTags, key)
+	}
+	return nil
+}
+
+// validTagKeyChar indica se c pode fazer parte da chave de uma tag
+func validTagKeyChar(c rune) bool {
+	return c < utf8.RuneSelf && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-')
+}
+
+// cloneTags copia as tags de um upload, para que o chamador possa alterar o
+// mapa depois; tags vazias viram nil
+func cloneTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return maps.Clone(tags)
+}
+
+// TagSelector seleciona arquivos pelas suas tags, com uma lista de
+// requisitos que precisam ser todos atendidos
+type TagSelector []tagRequirement
+
+// tagRequirement é um requisito de um TagSelector sobre a tag key
+type tagRequirement struct {
+	key    string
+	value  string
+	negate bool // Exige valor diferente de value ou, com exists, a ausência da tag
+	exists bool // Exige apenas que a tag exista, com qualquer valor
+}
+
+// ParseTagSelector converte um seletor em TagSelector. O seletor é uma lista
+// de requisitos separados por vírgulas: "chave=valor" exige a tag com esse
+// valor, "chave!=valor" exige um valor diferente ou a ausência da tag,
+// "chave" exige a tag com qualquer valor e "!chave" exige a sua ausência,
+// como em "env=prod,owner,!temp". Um seletor vazio seleciona tudo
+func ParseTagSelector(s string) (TagSelector, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var selector TagSelector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+
+		var req tagRequirement
+		if key, value, ok := strings.Cut(term, "="); ok {
+			req.key, req.value = key, value
+			if k, found := strings.CutSuffix(key, "!"); found {
+				req.key, req.negate = k, true
+			}
+		} else {
+			req.exists = true
+			req.key, req.negate = strings.CutPrefix(term, "!")
+		}
+
+		if err := validateTag(req.key, req.value); err != nil {
+			return nil, fmt.Errorf("%w: seletor %q inválido", ErrInvalidListOptions, s)
+		}
+		selector = append(selector, req)
+	}
+
+	return selector, nil
+}
+
+// Matches indica se as tags atendem a todos os requisitos do seletor
+func (selector TagSelector) Matches(tags map[string]string) bool {
+	for _, req := range selector {
+		value, ok := tags[req.key]
+		if req.exists {
+			ok = ok != req.negate
+		} else {
+			ok = (ok && value == req.value) != req.negate
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
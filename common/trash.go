@@ -0,0 +1,452 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TrashDirName é o nome do diretório, dentro do diretório de dados, onde
+// ficam os arquivos removidos e substituídos enquanto estão na lixeira
+const TrashDirName = ".trash"
+
+// Cada entrada da lixeira é um diretório em TrashDirName, nomeado pelo seu
+// ID, com o conteúdo do arquivo como foi gravado e a descrição da entrada
+const (
+	trashContentName = "content"
+	trashEntryName   = "entry.json"
+)
+
+// TrashEntry descreve um arquivo removido ou substituído que está na lixeira
+// Os IDs têm o formato dos IDs de versão: o instante da remoção, de modo que
+// a ordem alfabética dos IDs é a cronológica
+type TrashEntry struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"` // Nome do arquivo quando foi removido
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	ModTime     time.Time `json:"mod_time"`              // Data em que o conteúdo foi gravado
+	DeletedAt   time.Time `json:"deleted_at"`            // Data em que foi removido ou substituído
+	PurgeAt     time.Time `json:"purge_at,omitzero"`     // Data em que deixa a lixeira; zero se não houver prazo
+	Overwritten bool      `json:"overwritten,omitempty"` // Substituído por um upload, e não removido
+
+	Tags map[string]string `json:"tags,omitempty"` // Tags do arquivo, regravadas ao restaurá-lo
+}
+
+// trashRecord é a descrição gravada em trashEntryName: a entrada e, fora
+// dela, se o conteúdo foi compactado pelo armazenamento; Compressed é nil nas
+// entradas gravadas antes do campo existir (ver openContent)
+type trashRecord struct {
+	TrashEntry
+	Compressed *bool `json:"compressed,omitempty"`
+}
+
+// Trasher é implementado pelos armazenamentos que guardam em uma lixeira os
+// arquivos removidos e substituídos. Os servidores verificam com type
+// assertion se o FileService configurado também o implementa
+type Trasher interface {
+	// ListTrash retorna as entradas da lixeira, da mais recente para a mais
+	// antiga. As entradas além do prazo de retenção são removidas antes
+	ListTrash() ([]TrashEntry, error)
+
+	// DownloadTrash abre o conteúdo de uma entrada da lixeira para leitura
+	// O chamador é responsável por fechar o io.ReadCloser retornado
+	DownloadTrash(id string) (io.ReadCloser, error)
+
+	// RestoreTrash grava o conteúdo de uma entrada da lixeira de volta no
+	// nome de onde foi removido, com as suas tags, como um novo upload, e
+	// tira a entrada da lixeira. Um arquivo que exista com esse nome é substituído e, como em
+	// qualquer upload, vai para a lixeira
+	RestoreTrash(id string) (FileInfo, error)
+
+	// PurgeTrash remove definitivamente uma entrada da lixeira ou, com id
+	// vazio, todas elas, e retorna as entradas removidas
+	PurgeTrash(id string) ([]TrashEntry, error)
+
+	// PruneTrash remove as entradas além do prazo de retenção e as retorna
+	PruneTrash() ([]TrashEntry, error)
+}
+
+// ListTrash retorna as entradas da lixeira, da mais recente para a mais
+// antiga. Entradas além do prazo de retenção são removidas antes
+func (ls *LocalStorage) ListTrash() ([]TrashEntry, error) {
+	if _, err := ls.PruneTrash(); err != nil {
+		return nil, err
+	}
+
+	ids, err := ls.trashIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TrashEntry
+	for i := len(ids) - 1; i >= 0; i-- {
+		entry, err := ls.trashEntry(ids[i])
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// DownloadTrash abre o conteúdo de uma entrada da lixeira para leitura
+func (ls *LocalStorage) DownloadTrash(id string) (io.ReadCloser, error) {
+	if !validVersionID(id) {
+		return nil, fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+	}
+
+	unlock, err := ls.lock(trashLockName(id), false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	record, err := ls.readTrash(id)
+	if err != nil {
+		return nil, err
+	}
+	return ls.openTrash(id, record.Compressed)
+}
+
+// RestoreTrash grava o conteúdo de uma entrada da lixeira de volta no nome
+// de onde foi removido e tira a entrada da lixeira. O conteúdo é gravado
+// como um novo upload, com as tags do arquivo removido, então a troca é tão
+// atômica quanto UploadFile; o arquivo restaurado não expira, mesmo que o
+// removido expirasse
+func (ls *LocalStorage) RestoreTrash(id string) (FileInfo, error) {
+	if !validVersionID(id) {
+		return FileInfo{}, fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+	}
+
+	// O lock exclusivo da entrada impede que ela seja restaurada duas vezes
+	// ou removida durante o upload
+	unlock, err := ls.lock(trashLockName(id), true)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer unlock()
+
+	entry, err := ls.readTrash(id)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	content, err := ls.openTrash(id, entry.Compressed)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer content.Close()
+
+	info, err := ls.UploadFile(entry.Name, content, UploadOptions{Tags: entry.Tags})
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao restaurar %s da lixeira: %w", entry.Name, err)
+	}
+
+	if err := removeAll(ls.root, ls.trashDir(id)); err != nil {
+		return info, fmt.Errorf("erro ao remover entrada %s da lixeira: %w", id, err)
+	}
+
+	return info, nil
+}
+
+// PurgeTrash remove definitivamente a entrada id da lixeira ou, com id
+// vazio, todas as entradas, e retorna as entradas removidas
+func (ls *LocalStorage) PurgeTrash(id string) ([]TrashEntry, error) {
+	if id != "" {
+		if !validVersionID(id) {
+			return nil, fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+		}
+		entry, removed, err := ls.removeTrash(id)
+		if err != nil {
+			return nil, err
+		}
+		if !removed {
+			return nil, fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+		}
+		return []TrashEntry{entry}, nil
+	}
+
+	ids, err := ls.trashIDs()
+	if err != nil {
+		return nil, err
+	}
+	return ls.removeTrashEntries(ids)
+}
+
+// PruneTrash remove as entradas da lixeira além do prazo de retenção e as
+// retorna. Sem lixeira ativa as entradas existentes são preservadas
+func (ls *LocalStorage) PruneTrash() ([]TrashEntry, error) {
+	if ls.trashRetention <= 0 {
+		return nil, nil
+	}
+
+	ids, err := ls.trashIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	// Os IDs estão em ordem cronológica, então as entradas expiradas são as
+	// primeiras
+	limit := time.Now().Add(-ls.trashRetention)
+	expired := 0
+	for _, id := range ids {
+		deletedAt, _ := time.Parse(versionIDLayout, id)
+		if !deletedAt.Before(limit) {
+			break
+		}
+		expired++
+	}
+
+	return ls.removeTrashEntries(ids[:expired])
+}
+
+// trashFile move name, em filePath, para uma nova entrada da lixeira e
+// retorna o caminho para onde o conteúdo foi movido, ou "" se a lixeira não
+// estiver ativa ou name não for um arquivo comum
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) trashFile(name, filePath string, overwritten bool) (string, error) {
+	entryDir, err := ls.newTrashEntry(name, filePath, overwritten)
+	if err != nil || entryDir == "" {
+		return "", err
+	}
+
+	contentPath := filepath.Join(entryDir, trashContentName)
+	if err := renameInRoot(ls.root, filePath, contentPath); err != nil {
+		removeAll(ls.root, entryDir)
+		return "", fmt.Errorf("erro ao mover %s para a lixeira: %w", name, err)
+	}
+
+	return contentPath, nil
+}
+
+// newTrashEntry cria a entrada da lixeira de name, em filePath, ainda sem o
+// conteúdo, e retorna seu diretório, ou "" se a lixeira não estiver ativa ou
+// name não for um arquivo comum. Uma entrada sem conteúdo, deixada por uma
+// queda antes de o arquivo ser movido, é ignorada na listagem
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) newTrashEntry(name, filePath string, overwritten bool) (string, error) {
+	if ls.trashRetention <= 0 {
+		return "", nil
+	}
+
+	stat, err := ls.root.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+	if !stat.Mode().IsRegular() {
+		return "", nil
+	}
+
+	// As informações são lidas antes de o registro de checksum ser removido,
+	// para que o conteúdo não precise ser lido de novo
+	info, compressed, err := ls.storedInfo(name, false)
+	if err != nil {
+		return "", err
+	}
+
+	if err := mkdirAll(ls.root, TrashDirName); err != nil {
+		return "", fmt.Errorf("erro ao criar a lixeira: %w", err)
+	}
+
+	// O Mkdir falha se o ID já estiver em uso, inclusive por outro processo;
+	// avança o instante até encontrar um ID livre
+	now := time.Now().UTC()
+	id := now.Format(versionIDLayout)
+	for {
+		err := ls.root.Mkdir(ls.trashDir(id), 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("erro ao criar entrada da lixeira para %s: %w", name, err)
+		}
+		now = now.Add(time.Nanosecond)
+		id = now.Format(versionIDLayout)
+	}
+
+	entry := TrashEntry{
+		ID:          id,
+		Name:        name,
+		Size:        info.Size,
+		SHA256:      info.SHA256,
+		ContentType: info.ContentType,
+		ModTime:     info.ModTime,
+		DeletedAt:   now,
+		Overwritten: overwritten,
+		Tags:        info.Tags,
+	}
+	if err := ls.writeTrashEntry(trashRecord{entry, &compressed}); err != nil {
+		removeAll(ls.root, ls.trashDir(id))
+		return "", fmt.Errorf("erro ao criar entrada da lixeira para %s: %w", name, err)
+	}
+
+	return ls.trashDir(id), nil
+}
+
+// writeTrashEntry grava a descrição de entry no seu diretório. A descrição é
+// sincronizada em disco antes de o conteúdo ser movido para a entrada, para
+// que uma queda não deixe um conteúdo sem descrição
+func (ls *LocalStorage) writeTrashEntry(entry trashRecord) error {
+	file, err := ls.root.OpenFile(filepath.Join(ls.trashDir(entry.ID), trashEntryName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(entry); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// trashEntry lê a entrada id da lixeira, falhando com ErrNotFound se ela não
+// existir ou não tiver conteúdo
+func (ls *LocalStorage) trashEntry(id string) (TrashEntry, error) {
+	record, err := ls.readTrash(id)
+	return record.TrashEntry, err
+}
+
+// readTrash lê a descrição completa da entrada id da lixeira, como trashEntry
+func (ls *LocalStorage) readTrash(id string) (trashRecord, error) {
+	dir := ls.trashDir(id)
+	notFound := fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+
+	if _, err := ls.root.Lstat(filepath.Join(dir, trashContentName)); os.IsNotExist(err) {
+		return trashRecord{}, notFound
+	} else if err != nil {
+		return trashRecord{}, fmt.Errorf("erro ao consultar entrada %s da lixeira: %w", id, err)
+	}
+
+	file, err := ls.root.Open(filepath.Join(dir, trashEntryName))
+	if os.IsNotExist(err) {
+		return trashRecord{}, notFound
+	}
+	if err != nil {
+		return trashRecord{}, fmt.Errorf("erro ao ler entrada %s da lixeira: %w", id, err)
+	}
+	defer file.Close()
+
+	var entry trashRecord
+	if err := json.NewDecoder(file).Decode(&entry); err != nil {
+		return trashRecord{}, fmt.Errorf("erro ao ler entrada %s da lixeira: %w", id, err)
+	}
+
+	// O ID vem do diretório, e o prazo, da retenção atual
+	entry.ID = id
+	entry.PurgeAt = time.Time{}
+	if ls.trashRetention > 0 {
+		entry.PurgeAt = entry.DeletedAt.Add(ls.trashRetention)
+	}
+	return entry, nil
+}
+
+// openTrash abre o conteúdo da entrada id, descompactando-o se a descrição
+// da entrada indicar compressed (ver openContent)
+// NOTA: Esta função assume que o chamador obteve o lock da entrada
+func (ls *LocalStorage) openTrash(id string, compressed *bool) (io.ReadCloser, error) {
+	file, err := ls.root.Open(filepath.Join(ls.trashDir(id), trashContentName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: entrada %s da lixeira", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("erro ao abrir entrada %s da lixeira: %w", id, err)
+	}
+
+	// O conteúdo foi movido como estava gravado, então pode estar compactado
+	content, stored, err := openContent(file, compressed)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("erro ao ler entrada %s da lixeira: %w", id, err)
+	}
+	if stored != nil {
+		return readCloser{content, file}, nil
+	}
+
+	return file, nil
+}
+
+// removeTrashEntries remove as entradas ids da lixeira e retorna as que
+// tinham conteúdo; as demais eram restos de remoções interrompidas
+func (ls *LocalStorage) removeTrashEntries(ids []string) ([]TrashEntry, error) {
+	var removed []TrashEntry
+	for _, id := range ids {
+		entry, ok, err := ls.removeTrash(id)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed = append(removed, entry)
+		}
+	}
+	return removed, nil
+}
+
+// removeTrash remove a entrada id da lixeira e indica se ela tinha conteúdo
+func (ls *LocalStorage) removeTrash(id string) (TrashEntry, bool, error) {
+	unlock, err := ls.lock(trashLockName(id), true)
+	if err != nil {
+		return TrashEntry{}, false, err
+	}
+	defer unlock()
+
+	entry, err := ls.trashEntry(id)
+	complete := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		// Uma descrição ilegível não impede a remoção
+		entry, complete = TrashEntry{ID: id}, true
+	}
+
+	dir := ls.trashDir(id)
+	if _, err := ls.root.Lstat(dir); os.IsNotExist(err) {
+		return TrashEntry{}, false, nil
+	}
+	if err := removeAll(ls.root, dir); err != nil {
+		return TrashEntry{}, false, fmt.Errorf("erro ao remover entrada %s da lixeira: %w", id, err)
+	}
+
+	return entry, complete, nil
+}
+
+// trashIDs retorna os IDs das entradas da lixeira em ordem cronológica
+func (ls *LocalStorage) trashIDs() ([]string, error) {
+	entries, err := readDir(ls.root, TrashDirName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler a lixeira: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && validVersionID(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+// trashDir retorna o diretório, relativo ao diretório base, da entrada id
+func (ls *LocalStorage) trashDir(id string) string {
+	return filepath.Join(TrashDirName, id)
+}
+
+// trashLockName retorna o nome travado por lock para a entrada id. Os nomes
+// dos arquivos nunca começam com TrashDirName, então não há conflito
+func trashLockName(id string) string {
+	return TrashDirName + "/" + id
+}
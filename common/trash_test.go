@@ -0,0 +1,154 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTrashStorage cria um LocalStorage em dir com a lixeira ativa
+func newTestTrashStorage(t *testing.T, dir string) *LocalStorage {
+	t.Helper()
+
+	ls, err := NewLocalStorage(dir, LocalStorageOptions{TrashRetention: time.Hour})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return ls
+}
+
+// listTrash retorna as entradas da lixeira de ls
+func listTrash(t *testing.T, ls *LocalStorage) []TrashEntry {
+	t.Helper()
+
+	entries, err := ls.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	return entries
+}
+
+// checkTrashEntry falha se entry não descrever content, removido de name
+func checkTrashEntry(t *testing.T, ls *LocalStorage, entry TrashEntry, name, content string, overwritten bool) {
+	t.Helper()
+
+	if entry.Name != name || entry.Size != int64(len(content)) || entry.SHA256 != sumOf(content) || entry.Overwritten != overwritten {
+		t.Errorf("entrada %+v, esperado %s com %q, overwritten=%v", entry, name, content, overwritten)
+	}
+	if got, err := readAllClose(ls.DownloadTrash(entry.ID)); err != nil || string(got) != content {
+		t.Errorf("DownloadTrash(%s): %q, %v; esperado %q", entry.ID, got, err, content)
+	}
+}
+
+// TestTrashRestore manda para a lixeira um arquivo substituído e um removido
+// e confere que eles são restaurados com as suas tags, e que restaurar sobre
+// um arquivo existente manda esse arquivo para a lixeira
+func TestTrashRestore(t *testing.T) {
+	dir := t.TempDir()
+	ls := newTestTrashStorage(t, dir)
+
+	if _, err := ls.UploadFile("a.txt", strings.NewReader("v1"), UploadOptions{Tags: map[string]string{"k": "v1"}}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if _, err := ls.UploadFile("a.txt", strings.NewReader("v2"), UploadOptions{Tags: map[string]string{"k": "v2"}}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if err := ls.DeleteFile("a.txt"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+
+	// Da mais recente para a mais antiga
+	entries := listTrash(t, ls)
+	if len(entries) != 2 {
+		t.Fatalf("ListTrash: %+v, esperado 2 entradas", entries)
+	}
+	deleted, overwritten := entries[0], entries[1]
+	checkTrashEntry(t, ls, deleted, "a.txt", "v2", false)
+	checkTrashEntry(t, ls, overwritten, "a.txt", "v1", true)
+
+	info, err := ls.RestoreTrash(overwritten.ID)
+	if err != nil {
+		t.Fatalf("RestoreTrash: %v", err)
+	}
+	if info.Tags["k"] != "v1" || info.SHA256 != sumOf("v1") {
+		t.Errorf("RestoreTrash: %+v", info)
+	}
+	checkDownload(t, ls, "a.txt", "v1")
+	if entries := listTrash(t, ls); len(entries) != 1 || entries[0].ID != deleted.ID {
+		t.Fatalf("ListTrash depois do restore: %+v", entries)
+	}
+
+	// Restaurar sobre o arquivo restaurado o manda para a lixeira
+	if _, err := ls.RestoreTrash(deleted.ID); err != nil {
+		t.Fatalf("RestoreTrash: %v", err)
+	}
+	checkDownload(t, ls, "a.txt", "v2")
+	if info, err := ls.StatFile("a.txt"); err != nil || info.Tags["k"] != "v2" {
+		t.Errorf("StatFile: %+v, %v", info, err)
+	}
+	entries = listTrash(t, ls)
+	if len(entries) != 1 {
+		t.Fatalf("ListTrash: %+v, esperado 1 entrada", entries)
+	}
+	checkTrashEntry(t, ls, entries[0], "a.txt", "v1", true)
+
+	if _, err := ls.RestoreTrash(deleted.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RestoreTrash de entrada restaurada: %v, esperado ErrNotFound", err)
+	}
+	if files := listSummary(t, ls, "", true); len(files) != 1 {
+		t.Errorf("ListFiles mostra a lixeira: %v", files)
+	}
+}
+
+// TestTrashPurge confere que PurgeTrash remove uma entrada ou todas, e que
+// PruneTrash remove só as entradas além do prazo de retenção
+func TestTrashPurge(t *testing.T) {
+	dir := t.TempDir()
+	ls := newTestTrashStorage(t, dir)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		uploadString(t, ls, name, name)
+		if err := ls.DeleteFile(name); err != nil {
+			t.Fatalf("DeleteFile: %v", err)
+		}
+	}
+	entries := listTrash(t, ls)
+	if len(entries) != 4 {
+		t.Fatalf("ListTrash: %+v, esperado 4 entradas", entries)
+	}
+
+	purged, err := ls.PurgeTrash(entries[0].ID)
+	if err != nil || len(purged) != 1 || purged[0].Name != "d.txt" {
+		t.Fatalf("PurgeTrash(%s): %+v, %v", entries[0].ID, purged, err)
+	}
+	for _, id := range []string{entries[0].ID, "../a.txt", "x"} {
+		if _, err := ls.PurgeTrash(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("PurgeTrash(%q): %v, esperado ErrNotFound", id, err)
+		}
+	}
+
+	// O ID é o instante da remoção, então renomear a entrada de a.txt para
+	// um ID de duas horas atrás a põe além do prazo de retenção
+	oldID := time.Now().UTC().Add(-2 * time.Hour).Format(versionIDLayout)
+	if err := os.Rename(filepath.Join(dir, ls.trashDir(entries[3].ID)), filepath.Join(dir, ls.trashDir(oldID))); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	pruned, err := ls.PruneTrash()
+	if err != nil || len(pruned) != 1 || pruned[0].Name != "a.txt" {
+		t.Fatalf("PruneTrash: %+v, %v", pruned, err)
+	}
+
+	purged, err = ls.PurgeTrash("")
+	if err != nil || len(purged) != 2 {
+		t.Fatalf("PurgeTrash de todas: %+v, %v", purged, err)
+	}
+	if entries := listTrash(t, ls); len(entries) != 0 {
+		t.Errorf("ListTrash depois de esvaziar: %+v", entries)
+	}
+	if trash, err := os.ReadDir(filepath.Join(dir, TrashDirName)); err != nil || len(trash) != 0 {
+		t.Errorf("lixeira no disco: %v, %v", trash, err)
+	}
+}
@@ -0,0 +1,437 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// UploadsDirName é o nome do diretório, dentro do diretório de dados, onde
+// ficam os uploads retomáveis ainda não concluídos
+const UploadsDirName = ".uploads"
+
+var (
+	// ErrSessionNotFound indica que a sessão de upload não existe ou já foi encerrada
+	ErrSessionNotFound = errors.New("sessão de upload não encontrada")
+
+	// ErrOffsetMismatch indica que um bloco foi enviado a partir de um offset
+	// posterior ao último byte confirmado da sessão
+	ErrOffsetMismatch = errors.New("offset não corresponde ao progresso da sessão")
+
+	// ErrSessionIncomplete indica que a sessão ainda não recebeu todos os bytes
+	ErrSessionIncomplete = errors.New("sessão de upload incompleta")
+)
+
+// UploadSession descreve um upload retomável em andamento
+type UploadSession struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Size      int64         `json:"size"`
+	Checksum  string        `json:"checksum,omitempty"`
+	TTL       time.Duration `json:"ttl,omitempty"` // TTL do arquivo, contado da conclusão da sessão (ver UploadOptions.TTL)
+	CreatedAt time.Time     `json:"created_at"`
+
+	Tags map[string]string `json:"tags,omitempty"` // Tags gravadas com o arquivo (ver UploadOptions.Tags)
+
+	// Offset é o número de bytes já gravados de forma durável na área de
+	// staging; é calculado a partir do arquivo parcial e não é persistido
+	Offset int64 `json:"-"`
+}
+
+// UploadSessions gerencia uploads retomáveis
+// Os bytes recebidos de cada sessão são gravados em uma área de staging e só
+// são enviados ao FileService de destino quando a sessão é concluída. Como no
+// LocalStorage, o acesso à área de staging passa por um os.Root
+type UploadSessions struct {
+	root    *os.Root
+	storage FileService
+	locks   *lockTable
+}
+
+// NewUploadSessions cria o gerenciador de sessões usando dir como área de
+// staging e storage como destino dos arquivos concluídos
+func NewUploadSessions(dir string, storage FileService) (*UploadSessions, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de staging %s: %w", dir, err)
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir diretório de staging %s: %w", dir, err)
+	}
+
+	return &UploadSessions{
+		root:    root,
+		storage: storage,
+		locks:   newLockTable(),
+	}, nil
+}
+
+// Create inicia uma nova sessão de upload para o arquivo name, que expira ttl
+// depois da conclusão da sessão se ttl for positivo e é gravado com tags
+func (us *UploadSessions) Create(name string, size int64, checksum string, ttl time.Duration, tags map[string]string) (*UploadSession, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("tamanho do arquivo deve ser maior que zero")
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &UploadSession{
+		ID:        id,
+		Name:      name,
+		Size:      size,
+		Checksum:  checksum,
+		TTL:       ttl,
+		CreatedAt: time.Now(),
+		Tags:      cloneTags(tags),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar sessão: %w", err)
+	}
+
+	// O arquivo parcial é criado antes dos metadados para que uma sessão
+	// visível sempre tenha onde receber dados
+	part, err := us.root.OpenFile(us.partPath(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo parcial: %w", err)
+	}
+	part.Close()
+
+	if err := us.writeMeta(id, data); err != nil {
+		us.root.Remove(us.partPath(id))
+		return nil, fmt.Errorf("erro ao gravar sessão: %w", err)
+	}
+
+	return session, nil
+}
+
+// Get retorna a sessão com o offset confirmado atual
+func (us *UploadSessions) Get(id string) (*UploadSession, error) {
+	part, release, err := us.acquire(id)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return us.load(id, part)
+}
+
+// Append grava os dados lidos de r na sessão a partir de offset e retorna o
+// novo offset confirmado. O offset pode ser anterior ao confirmado (o trecho
+// é regravado), mas nunca posterior. Se r falhar no meio do envio, os bytes
+// já recebidos permanecem gravados e o offset retornado reflete esse progresso
+func (us *UploadSessions) Append(id string, offset int64, r io.Reader) (int64, error) {
+	part, release, err := us.acquire(id)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	session, err := us.load(id, part)
+	if err != nil {
+		return 0, err
+	}
+
+	if offset < 0 || offset > session.Offset {
+		return session.Offset, fmt.Errorf("%w: offset %d, confirmado %d", ErrOffsetMismatch, offset, session.Offset)
+	}
+
+	// Descarta qualquer trecho posterior ao offset que será regravado
+	if err := part.Truncate(offset); err != nil {
+		return session.Offset, fmt.Errorf("erro ao truncar arquivo parcial: %w", err)
+	}
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		return session.Offset, fmt.Errorf("erro ao posicionar arquivo parcial: %w", err)
+	}
+
+	// Nunca aceita mais bytes do que o tamanho anunciado na criação da sessão
+	limit := session.Size - offset
+	n, copyErr := io.CopyBuffer(part, io.LimitReader(r, limit+1), make([]byte, ChunkSize))
+	if n > limit {
+		n = limit
+		part.Truncate(session.Size)
+		copyErr = fmt.Errorf("%w: recebidos mais de %d bytes", ErrSizeMismatch, session.Size)
+	}
+
+	// Os dados recebidos são persistidos mesmo que o envio tenha sido interrompido
+	if err := part.Sync(); err != nil {
+		return offset, fmt.Errorf("erro ao sincronizar arquivo parcial: %w", err)
+	}
+
+	if copyErr != nil {
+		return offset + n, fmt.Errorf("upload interrompido: %w", copyErr)
+	}
+
+	return offset + n, nil
+}
+
+// Commit conclui a sessão: confere tamanho e checksum, envia o arquivo ao
+// FileService de destino e remove os dados de staging
+func (us *UploadSessions) Commit(id string) (*UploadSession, error) {
+	part, release, err := us.acquire(id)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	session, err := us.load(id, part)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Offset != session.Size {
+		return session, fmt.Errorf("%w: recebidos %d de %d bytes", ErrSessionIncomplete, session.Offset, session.Size)
+	}
+
+	if _, err := part.Seek(0, io.SeekStart); err != nil {
+		return session, fmt.Errorf("erro ao posicionar arquivo parcial: %w", err)
+	}
+
+	if _, err := us.storage.UploadFile(session.Name, NewVerifyingReader(part, session.Size, session.Checksum), UploadOptions{TTL: session.TTL, Tags: session.Tags}); err != nil {
+		return session, err
+	}
+
+	us.remove(id)
+	return session, nil
+}
+
+// Abort cancela a sessão e descarta os dados recebidos
+func (us *UploadSessions) Abort(id string) error {
+	part, release, err := us.acquire(id)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := us.load(id, part); err != nil {
+		return err
+	}
+
+	us.remove(id)
+	return nil
+}
+
+// RemoveStale remove as sessões sem atividade há mais de maxAge, contada do
+// último bloco recebido ou, antes dele, da criação, e retorna os IDs das
+// sessões removidas. Cada sessão é removida com o seu lock, então uma
+// operação em andamento sobre ela termina antes. Os arquivos de sessões cuja
+// criação ou remoção foi interrompida por uma queda também são removidos
+// depois de maxAge. Os erros são retornados juntos ao final
+func (us *UploadSessions) RemoveStale(maxAge time.Duration) ([]string, error) {
+	entries, err := readDir(us.root, ".")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler área de staging: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".part")
+		if !ok {
+			id, ok = strings.CutSuffix(entry.Name(), ".json")
+		}
+		if ok && validSessionID(id) && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var removed []string
+	var errs []error
+	for _, id := range ids {
+		stale, err := us.removeIfStale(id, cutoff)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if stale {
+			removed = append(removed, id)
+		}
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// removeIfStale remove a sessão id se sua última atividade for anterior a
+// cutoff e informa se ela foi removida
+func (us *UploadSessions) removeIfStale(id string, cutoff time.Time) (bool, error) {
+	part, release, err := us.acquire(id)
+	if errors.Is(err, ErrSessionNotFound) {
+		// Metadados sem o arquivo parcial, de uma remoção interrompida
+		stat, err := us.root.Lstat(us.metaPath(id))
+		if err != nil || stat.ModTime().After(cutoff) {
+			return false, nil
+		}
+		us.root.Remove(us.metaPath(id))
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	stat, err := part.Stat()
+	if err != nil {
+		return false, fmt.Errorf("erro ao ler arquivo parcial da sessão %s: %w", id, err)
+	}
+	lastActivity := stat.ModTime()
+
+	// Um arquivo parcial sem metadados é de uma criação interrompida
+	session, err := us.load(id, part)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return false, err
+	}
+	if session != nil && session.CreatedAt.After(lastActivity) {
+		lastActivity = session.CreatedAt
+	}
+	if lastActivity.After(cutoff) {
+		return false, nil
+	}
+
+	us.remove(id)
+	return true, nil
+}
+
+// acquire obtém acesso exclusivo à sessão e retorna seu arquivo parcial
+// aberto para leitura e escrita. A tabela de locks serializa as operações sobre
+// a mesma sessão dentro do processo, sem bloquear as demais sessões, e o flock sobre
+// o arquivo parcial estende a exclusão a outros servidores que compartilham a
+// área de staging. A função release retornada libera ambos
+func (us *UploadSessions) acquire(id string) (*os.File, func(), error) {
+	if !validSessionID(id) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+
+	unlock := us.locks.Lock(id)
+
+	// Um link simbólico no lugar do arquivo parcial não é seguido, para que
+	// a sessão nunca grave nem leia um arquivo de fora da área de staging
+	part, err := openRegular(us.root, us.partPath(id), os.O_RDWR, 0644)
+	if err != nil {
+		unlock()
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+		}
+		return nil, nil, fmt.Errorf("erro ao abrir arquivo parcial: %w", err)
+	}
+
+	if err := lockFile(part, true); err != nil {
+		part.Close()
+		unlock()
+		return nil, nil, fmt.Errorf("erro ao travar sessão %s: %w", id, err)
+	}
+
+	release := func() {
+		part.Close()
+		unlock()
+	}
+
+	return part, release, nil
+}
+
+// load lê os metadados da sessão e calcula o offset a partir do arquivo parcial
+// NOTA: Esta função assume que a sessão foi obtida com acquire pelo chamador
+func (us *UploadSessions) load(id string, part *os.File) (*UploadSession, error) {
+	// Os metadados são lidos após obter o lock: se outro processo concluiu ou
+	// cancelou a sessão enquanto esperávamos, eles já não existem
+	data, err := us.readMeta(id)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler sessão %s: %w", id, err)
+	}
+
+	var session UploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar sessão %s: %w", id, err)
+	}
+
+	info, err := part.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo parcial da sessão %s: %w", id, err)
+	}
+	session.Offset = info.Size()
+
+	return &session, nil
+}
+
+// remove apaga os arquivos da sessão
+// NOTA: Esta função assume que a sessão foi obtida com acquire pelo chamador
+func (us *UploadSessions) remove(id string) {
+	us.root.Remove(us.metaPath(id))
+	us.root.Remove(us.partPath(id))
+}
+
+// writeMeta grava os metadados da sessão
+func (us *UploadSessions) writeMeta(id string, data []byte) error {
+	f, err := us.root.OpenFile(us.metaPath(id), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readMeta lê os metadados da sessão
+func (us *UploadSessions) readMeta(id string) ([]byte, error) {
+	f, err := openRegular(us.root, us.metaPath(id), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// metaPath retorna o caminho, relativo à área de staging, do arquivo de
+// metadados da sessão
+func (us *UploadSessions) metaPath(id string) string {
+	return id + ".json"
+}
+
+// partPath retorna o caminho, relativo à área de staging, do arquivo com os
+// bytes recebidos da sessão
+func (us *UploadSessions) partPath(id string) string {
+	return id + ".part"
+}
+
+// newSessionID gera um identificador aleatório para uma sessão
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("erro ao gerar ID da sessão: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validSessionID verifica se id tem o formato gerado por newSessionID,
+// impedindo que seja usado para acessar arquivos fora da área de staging
+func validSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,187 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUploadSessionSymlinks substitui os arquivos de uma sessão por links
+// simbólicos para fora da área de staging e confere que as operações da
+// sessão não os seguem. Os arquivos de uma sessão ficam direto na área de
+// staging, então um diretório intermediário só pode ser a própria área
+func TestUploadSessionSymlinks(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewLocalStorage(dataDir, LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	stagingDir := filepath.Join(dataDir, UploadsDirName)
+	sessions, err := NewUploadSessions(stagingDir, storage)
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+
+	for _, file := range []string{"part", "json"} {
+		t.Run(file, func(t *testing.T) {
+			outside, secret := plantOutside(t)
+			session, err := sessions.Create("out.txt", 7, "", 0, nil)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			path := filepath.Join(stagingDir, session.ID+"."+file)
+			if err := os.Remove(path); err != nil {
+				t.Fatalf("Remove: %v", err)
+			}
+			if err := os.Symlink(secret, path); err != nil {
+				t.Fatalf("Symlink: %v", err)
+			}
+
+			if _, err := sessions.Get(session.ID); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Get: %v, esperado ErrSessionNotFound", err)
+			}
+			if _, err := sessions.Append(session.ID, 0, strings.NewReader("escrito")); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Append: %v, esperado ErrSessionNotFound", err)
+			}
+			if _, err := sessions.Commit(session.ID); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Commit: %v, esperado ErrSessionNotFound", err)
+			}
+			if err := sessions.Abort(session.ID); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("Abort: %v, esperado ErrSessionNotFound", err)
+			}
+			if _, err := storage.StatFile("out.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("StatFile: %v, esperado ErrNotFound", err)
+			}
+			checkOutside(t, outside, secret)
+		})
+	}
+
+	// Um link para outra sessão, dentro da área de staging, também é recusado
+	victim, err := sessions.Create("victim.txt", 7, "", 0, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	session, err := sessions.Create("other.txt", 7, "", 0, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	path := filepath.Join(stagingDir, session.ID+".part")
+	os.Remove(path)
+	if err := os.Symlink(victim.ID+".part", path); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if _, err := sessions.Append(session.ID, 0, strings.NewReader("escrito")); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Append: %v, esperado ErrSessionNotFound", err)
+	}
+	if got, err := sessions.Get(victim.ID); err != nil || got.Offset != 0 {
+		t.Errorf("sessão vizinha alterada: %+v, %v", got, err)
+	}
+}
+
+// TestRemoveStale confere que só as sessões sem atividade há mais do prazo
+// são removidas, inclusive os restos de sessões interrompidas, e que uma
+// sessão em uso é removida só depois de liberada
+func TestRemoveStale(t *testing.T) {
+	dataDir := t.TempDir()
+	storage, err := NewLocalStorage(dataDir, LocalStorageOptions{})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	stagingDir := filepath.Join(dataDir, UploadsDirName)
+	sessions, err := NewUploadSessions(stagingDir, storage)
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+
+	create := func() string {
+		session, err := sessions.Create("a.txt", 10, "", 0, nil)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return session.ID
+	}
+	// age faz a sessão id parecer criada, e com o último bloco recebido, há
+	// duas horas
+	old := time.Now().Add(-2 * time.Hour)
+	age := func(id string) {
+		data, err := os.ReadFile(filepath.Join(stagingDir, id+".json"))
+		if err == nil {
+			var session UploadSession
+			json.Unmarshal(data, &session)
+			session.CreatedAt = old
+			data, _ = json.Marshal(session)
+			err = os.WriteFile(filepath.Join(stagingDir, id+".json"), data, 0644)
+		}
+		for _, path := range []string{id + ".json", id + ".part"} {
+			if err == nil || os.IsNotExist(err) {
+				err = os.Chtimes(filepath.Join(stagingDir, path), old, old)
+			}
+		}
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatalf("erro ao envelhecer a sessão %s: %v", id, err)
+		}
+	}
+
+	// Uma sessão recente, uma abandonada e uma antiga, mas com um bloco
+	// recebido agora
+	fresh, abandoned, active := create(), create(), create()
+	age(abandoned)
+	age(active)
+	if _, err := sessions.Append(active, 0, strings.NewReader("abc")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	// Restos de uma criação e de uma remoção interrompidas
+	orphanPart, orphanMeta := create(), create()
+	os.Remove(filepath.Join(stagingDir, orphanPart+".json"))
+	os.Remove(filepath.Join(stagingDir, orphanMeta+".part"))
+	age(orphanPart)
+	age(orphanMeta)
+
+	// A sessão abandonada está em uso: a remoção espera o lock
+	_, release, err := sessions.acquire(abandoned)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	done := make(chan []string, 1)
+	go func() {
+		removed, err := sessions.RemoveStale(time.Hour)
+		if err != nil {
+			t.Errorf("RemoveStale: %v", err)
+		}
+		done <- removed
+	}()
+	select {
+	case <-done:
+		t.Fatal("RemoveStale terminou com a sessão em uso")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+
+	removed := <-done
+	slices.Sort(removed)
+	want := []string{abandoned, orphanPart, orphanMeta}
+	slices.Sort(want)
+	if !slices.Equal(removed, want) {
+		t.Errorf("RemoveStale removeu %v, esperado %v", removed, want)
+	}
+
+	if _, err := sessions.Get(abandoned); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get da sessão abandonada: %v, esperado ErrSessionNotFound", err)
+	}
+	for _, id := range []string{fresh, active} {
+		if _, err := sessions.Get(id); err != nil {
+			t.Errorf("Get de %s: %v", id, err)
+		}
+	}
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil || len(entries) != 4 {
+		t.Errorf("área de staging com %d entradas, esperado 4: %v", len(entries), err)
+	}
+}
@@ -0,0 +1,399 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// VersionsDirName é o nome do diretório, dentro do diretório de dados, onde
+// ficam as versões anteriores dos arquivos
+const VersionsDirName = ".versions"
+
+// versionIDLayout formata o ID de uma versão a partir do instante em que ela
+// foi substituída, de modo que a ordem alfabética dos IDs é a cronológica
+const versionIDLayout = "20060102T150405.000000000Z"
+
+// versionRecordSuffix identifica, ao lado de cada versão, o registro com o
+// SHA-256 do seu conteúdo, no formato dos registros de ChecksumsDirName
+const versionRecordSuffix = ".json"
+
+// VersionPolicy define quantas versões anteriores de cada arquivo são mantidas
+// Com os dois campos zerados o versionamento fica desativado e um novo upload
+// simplesmente substitui o arquivo
+type VersionPolicy struct {
+	MaxVersions int           // Número máximo de versões anteriores por arquivo; 0 não limita
+	MaxAge      time.Duration // Tempo máximo que uma versão é mantida após ser substituída; 0 não limita
+}
+
+// Enabled indica se a política mantém versões anteriores
+func (p VersionPolicy) Enabled() bool {
+	return p.MaxVersions > 0 || p.MaxAge > 0
+}
+
+// FileVersion descreve uma versão anterior de um arquivo
+type FileVersion struct {
+	ID         string    `json:"id"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256,omitempty"` // Vazio se o armazenamento não o conhecer
+	ModTime    time.Time `json:"mod_time"`         // Data em que o conteúdo foi gravado
+	ReplacedAt time.Time `json:"replaced_at"`      // Data em que foi substituído por outra versão
+}
+
+// Versioner é implementado pelos armazenamentos que mantêm versões anteriores
+// dos arquivos. Os servidores verificam com type assertion se o FileService
+// configurado também o implementa
+type Versioner interface {
+	// ListVersions retorna as versões anteriores de um arquivo, da mais
+	// recente para a mais antiga
+	ListVersions(name string) ([]FileVersion, error)
+
+	// DownloadVersion abre uma versão anterior de um arquivo para leitura e
+	// retorna as suas informações, com o SHA-256 registrado quando ela foi
+	// guardada, ou vazio se o armazenamento não o conhecer. Como em
+	// FileService.DownloadFile, a leitura falha com ErrCorrupted no lugar de
+	// io.EOF se o conteúdo lido não conferir com esse registro
+	// O chamador é responsável por fechar o io.ReadCloser retornado
+	DownloadVersion(name, versionID string) (io.ReadCloser, FileVersion, error)
+
+	// RestoreVersion torna uma versão anterior a versão atual do arquivo
+	// A versão restaurada continua no histórico e a versão atual é guardada
+	// como qualquer versão substituída por um upload
+	RestoreVersion(name, versionID string) error
+}
+
+// ListVersions retorna as versões anteriores de um arquivo, da mais recente
+// para a mais antiga. Versões expiradas pela política são removidas antes
+func (ls *LocalStorage) ListVersions(name string) ([]FileVersion, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	// Exclusivo porque a limpeza das versões expiradas altera o histórico
+	unlock, err := ls.lockPaths(true, name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	filePath, err := ls.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ls.pruneVersions(name); err != nil {
+		return nil, err
+	}
+
+	versions, err := ls.versions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		if _, err := ls.root.Lstat(filePath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+	}
+
+	return versions, nil
+}
+
+// DownloadVersion abre uma versão anterior de um arquivo para leitura,
+// conferindo o conteúdo com o registro gravado quando ela foi guardada
+func (ls *LocalStorage) DownloadVersion(name, versionID string) (io.ReadCloser, FileVersion, error) {
+	if err := validateName(name); err != nil {
+		return nil, FileVersion{}, err
+	}
+	if !validVersionID(versionID) {
+		return nil, FileVersion{}, fmt.Errorf("%w: versão %s de %s", ErrNotFound, versionID, name)
+	}
+
+	unlock, err := ls.lockPaths(false, name)
+	if err != nil {
+		return nil, FileVersion{}, err
+	}
+	defer unlock()
+
+	if _, err := ls.resolve(name); err != nil {
+		return nil, FileVersion{}, err
+	}
+
+	versionPath := filepath.Join(ls.versionsDir(name), versionID)
+	file, err := ls.root.Open(versionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, FileVersion{}, fmt.Errorf("%w: versão %s de %s", ErrNotFound, versionID, name)
+		}
+		return nil, FileVersion{}, fmt.Errorf("erro ao abrir versão %s de %s: %w", versionID, name, err)
+	}
+
+	content, version, err := ls.openVersion(versionPath, file)
+	if err != nil {
+		file.Close()
+		return nil, FileVersion{}, fmt.Errorf("erro ao ler versão %s de %s: %w", versionID, name, err)
+	}
+	if version.SHA256 != "" {
+		content = verifyStored(content, name, version.Size, version.SHA256)
+	}
+
+	return readCloser{content, file}, version, nil
+}
+
+// RestoreVersion torna uma versão anterior a versão atual do arquivo
+// O conteúdo da versão é gravado como um novo upload, então a troca é tão
+// atômica quanto UploadFile e a versão atual entra no histórico. As versões
+// não guardam tags, então o arquivo mantém as tags da versão atual
+func (ls *LocalStorage) RestoreVersion(name, versionID string) error {
+	// O descritor aberto continua válido mesmo que a versão seja removida
+	// pela política de retenção durante o upload
+	version, _, err := ls.DownloadVersion(name, versionID)
+	if err != nil {
+		return err
+	}
+	defer version.Close()
+
+	// Um arquivo atual ausente ou ilegível apenas não tem tags a manter
+	var tags map[string]string
+	if current, err := ls.fileInfo(name, false); err == nil {
+		tags = current.Tags
+	}
+
+	if _, err := ls.UploadFile(name, version, UploadOptions{Tags: tags}); err != nil {
+		return fmt.Errorf("erro ao restaurar versão %s de %s: %w", versionID, name, err)
+	}
+
+	return nil
+}
+
+// archiveVersion move a versão atual de name, se existir, para o histórico e
+// retorna o caminho para onde foi movida, ou "" se nada foi guardado
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) archiveVersion(name, filePath string) (string, error) {
+	if !ls.versionPolicy.Enabled() {
+		return "", nil
+	}
+
+	stat, err := ls.root.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao consultar arquivo %s: %w", name, err)
+	}
+	if !stat.Mode().IsRegular() {
+		return "", nil
+	}
+
+	// As informações são lidas antes de o registro de checksum ser removido,
+	// para que o conteúdo não precise ser lido de novo
+	info, compressed, err := ls.storedInfo(name, false)
+	if err != nil {
+		return "", err
+	}
+
+	dir := ls.versionsDir(name)
+	if err := mkdirAll(ls.root, dir); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de versões de %s: %w", name, err)
+	}
+
+	// O rename substituiria uma versão com o mesmo ID; avança o instante até
+	// encontrar um ID livre. O registro da versão é criado exclusivamente, o
+	// que também reserva o ID contra outros processos
+	now := time.Now().UTC()
+	versionPath := filepath.Join(dir, now.Format(versionIDLayout))
+	for {
+		if _, err := ls.root.Lstat(versionPath); os.IsNotExist(err) {
+			err := ls.writeVersionRecord(versionPath, checksumRecord{
+				SHA256:     info.SHA256,
+				Size:       info.Size,
+				StoredSize: stat.Size(),
+				ModTime:    stat.ModTime(),
+				Compressed: &compressed,
+			})
+			if err == nil {
+				break
+			}
+			if !os.IsExist(err) {
+				return "", fmt.Errorf("erro ao registrar versão de %s: %w", name, err)
+			}
+		}
+		now = now.Add(time.Nanosecond)
+		versionPath = filepath.Join(dir, now.Format(versionIDLayout))
+	}
+
+	if err := renameInRoot(ls.root, filePath, versionPath); err != nil {
+		ls.root.Remove(versionPath + versionRecordSuffix)
+		return "", fmt.Errorf("erro ao guardar versão de %s: %w", name, err)
+	}
+
+	return versionPath, nil
+}
+
+// pruneVersions remove as versões de name que excedem a política de retenção
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) pruneVersions(name string) error {
+	// Sem versionamento ativo o histórico existente é preservado
+	policy := ls.versionPolicy
+	if !policy.Enabled() {
+		return nil
+	}
+
+	versions, err := ls.versions(name)
+	if err != nil {
+		return err
+	}
+
+	for i, version := range versions {
+		expired := (policy.MaxVersions > 0 && i >= policy.MaxVersions) ||
+			(policy.MaxAge > 0 && time.Since(version.ReplacedAt) > policy.MaxAge)
+		if !expired {
+			continue
+		}
+
+		path := filepath.Join(ls.versionsDir(name), version.ID)
+		if err := ls.root.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("erro ao remover versão %s de %s: %w", version.ID, name, err)
+		}
+		ls.root.Remove(path + versionRecordSuffix)
+	}
+
+	return nil
+}
+
+// versions lê o histórico de name, da versão mais recente para a mais antiga
+func (ls *LocalStorage) versions(name string) ([]FileVersion, error) {
+	dir := ls.versionsDir(name)
+
+	entries, err := readDir(ls.root, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler versões de %s: %w", name, err)
+	}
+
+	var versions []FileVersion
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !entry.Type().IsRegular() || !validVersionID(entry.Name()) {
+			continue
+		}
+
+		version, err := ls.versionInfo(filepath.Join(dir, entry.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao consultar versão %s de %s: %w", entry.Name(), name, err)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// versionInfo retorna as informações da versão em versionPath
+func (ls *LocalStorage) versionInfo(versionPath string) (FileVersion, error) {
+	file, err := ls.root.Open(versionPath)
+	if err != nil {
+		return FileVersion{}, err
+	}
+	defer file.Close()
+
+	_, version, err := ls.openVersion(versionPath, file)
+	return version, err
+}
+
+// openVersion retorna um leitor do conteúdo original da versão em
+// versionPath, aberta em file, e as suas informações. O tamanho vem do
+// cabeçalho se a versão estiver compactada, e o SHA-256, do registro da
+// versão ou, na falta dele, do cabeçalho; uma versão sem nenhum dos dois,
+// guardada antes dos registros existirem, fica sem checksum
+func (ls *LocalStorage) openVersion(versionPath string, file *os.File) (io.Reader, FileVersion, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, FileVersion{}, err
+	}
+
+	id := filepath.Base(versionPath)
+	replacedAt, _ := time.Parse(versionIDLayout, id)
+	version := FileVersion{
+		ID:         id,
+		Size:       stat.Size(),
+		ModTime:    stat.ModTime(),
+		ReplacedAt: replacedAt,
+	}
+
+	// As versões são arquivos guardados como foram gravados, então também
+	// podem estar compactadas, o que o registro indica quando existe
+	record, recorded := ls.readRecord(versionPath+versionRecordSuffix, stat)
+	content, stored, err := openContent(file, record.Compressed)
+	if err != nil {
+		return nil, FileVersion{}, err
+	}
+	if stored != nil {
+		version.Size = stored.Size
+		version.SHA256 = stored.SHA256
+	}
+	if recorded {
+		version.Size = record.Size
+		version.SHA256 = record.SHA256
+	}
+
+	return content, version, nil
+}
+
+// writeVersionRecord cria o registro da versão em versionPath, falhando com
+// um erro de os.IsExist se ele já existir. Como os registros de checksum, o
+// registro não é sincronizado em disco: um registro perdido ou incompleto
+// após uma queda é ignorado, e um registro sem versão não é listado
+func (ls *LocalStorage) writeVersionRecord(versionPath string, record checksumRecord) error {
+	file, err := ls.root.OpenFile(versionPath+versionRecordSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(record)
+}
+
+// moveVersions move o histórico de oldName, se existir, para newName
+// NOTA: Esta função assume que o chamador obteve o lock exclusivo com lockPaths
+func (ls *LocalStorage) moveVersions(oldName, newName string) error {
+	oldDir := ls.versionsDir(oldName)
+	if _, err := ls.root.Lstat(oldDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	newDir := ls.versionsDir(newName)
+	if err := removeAll(ls.root, newDir); err != nil {
+		return fmt.Errorf("erro ao remover versões de %s: %w", newName, err)
+	}
+	if err := mkdirAll(ls.root, filepath.Dir(newDir)); err != nil {
+		return fmt.Errorf("erro ao criar diretório de versões de %s: %w", newName, err)
+	}
+	if err := renameInRoot(ls.root, oldDir, newDir); err != nil {
+		return fmt.Errorf("erro ao mover versões de %s para %s: %w", oldName, newName, err)
+	}
+
+	return nil
+}
+
+// versionsDir retorna o diretório, relativo ao diretório base, com o
+// histórico de name
+func (ls *LocalStorage) versionsDir(name string) string {
+	return filepath.Join(VersionsDirName, ls.path(name))
+}
+
+// validVersionID verifica se id tem o formato gerado por archiveVersion,
+// impedindo que seja usado para acessar arquivos fora do histórico
+func validVersionID(id string) bool {
+	t, err := time.Parse(versionIDLayout, id)
+	return err == nil && t.Format(versionIDLayout) == id
+}
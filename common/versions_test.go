@@ -0,0 +1,185 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestVersionedStorage cria um LocalStorage em dir com a política policy
+func newTestVersionedStorage(t *testing.T, dir string, policy VersionPolicy) *LocalStorage {
+	t.Helper()
+
+	ls, err := NewLocalStorage(dir, LocalStorageOptions{Versions: policy})
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	return ls
+}
+
+// versionContents retorna o conteúdo de cada versão anterior de name, da
+// mais recente para a mais antiga, conferindo o checksum de cada uma
+func versionContents(t *testing.T, ls *LocalStorage, name string) []string {
+	t.Helper()
+
+	versions, err := ls.ListVersions(name)
+	if err != nil {
+		t.Fatalf("ListVersions(%s): %v", name, err)
+	}
+
+	var contents []string
+	for _, version := range versions {
+		rc, downloaded, err := ls.DownloadVersion(name, version.ID)
+		got, err := readAllClose(rc, err)
+		if err != nil {
+			t.Fatalf("DownloadVersion(%s, %s): %v", name, version.ID, err)
+		}
+		if version.SHA256 != sumOf(string(got)) || downloaded.SHA256 != version.SHA256 || version.Size != int64(len(got)) {
+			t.Errorf("versão %s de %s: %+v e %+v, conteúdo %q", version.ID, name, version, downloaded, got)
+		}
+		contents = append(contents, string(got))
+	}
+	return contents
+}
+
+// TestVersionsMaxVersions confere que a política mantém só as MaxVersions
+// versões mais recentes, da mais recente para a mais antiga
+func TestVersionsMaxVersions(t *testing.T) {
+	dir := t.TempDir()
+	ls := newTestVersionedStorage(t, dir, VersionPolicy{MaxVersions: 2})
+
+	for _, content := range []string{"v1", "v2", "v3", "v4"} {
+		uploadString(t, ls, "a.txt", content)
+	}
+
+	if got := versionContents(t, ls, "a.txt"); !slices.Equal(got, []string{"v3", "v2"}) {
+		t.Errorf("versões %v, esperado [v3 v2]", got)
+	}
+	checkDownload(t, ls, "a.txt", "v4")
+
+	// Só restam as versões mantidas e os seus registros
+	entries, err := os.ReadDir(filepath.Join(dir, ls.versionsDir("a.txt")))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("%d arquivos no histórico, esperado 2 versões e 2 registros", len(entries))
+	}
+}
+
+// TestVersionsMaxAge envelhece uma versão além de MaxAge e confere que ela é
+// removida, com o seu registro, e que as mais novas são mantidas
+func TestVersionsMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	ls := newTestVersionedStorage(t, dir, VersionPolicy{MaxAge: time.Hour})
+
+	uploadString(t, ls, "a.txt", "v1")
+	uploadString(t, ls, "a.txt", "v2")
+	uploadString(t, ls, "a.txt", "v3")
+
+	versions, err := ls.ListVersions("a.txt")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("ListVersions: %v, %v", versions, err)
+	}
+
+	// O ID é o instante da substituição, então renomear a versão mais
+	// antiga para um ID de duas horas atrás a envelhece
+	versionsDir := filepath.Join(dir, ls.versionsDir("a.txt"))
+	oldID := time.Now().UTC().Add(-2 * time.Hour).Format(versionIDLayout)
+	for _, suffix := range []string{"", versionRecordSuffix} {
+		err := os.Rename(filepath.Join(versionsDir, versions[1].ID+suffix), filepath.Join(versionsDir, oldID+suffix))
+		if err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+	}
+
+	if got := versionContents(t, ls, "a.txt"); !slices.Equal(got, []string{"v2"}) {
+		t.Errorf("versões %v, esperado [v2]", got)
+	}
+	for _, suffix := range []string{"", versionRecordSuffix} {
+		if _, err := os.Lstat(filepath.Join(versionsDir, oldID+suffix)); !os.IsNotExist(err) {
+			t.Errorf("versão expirada %s ainda no disco: %v", oldID+suffix, err)
+		}
+	}
+}
+
+// TestRestoreVersion restaura uma versão anterior e confere que a versão
+// atual entra no histórico, que a restaurada continua nele e que as tags da
+// versão atual são mantidas
+func TestRestoreVersion(t *testing.T) {
+	ls := newTestVersionedStorage(t, t.TempDir(), VersionPolicy{MaxVersions: 5})
+
+	if _, err := ls.UploadFile("a.txt", strings.NewReader("v1"), UploadOptions{Tags: map[string]string{"k": "v1"}}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+	if _, err := ls.UploadFile("a.txt", strings.NewReader("v2"), UploadOptions{Tags: map[string]string{"k": "v2"}}); err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	versions, err := ls.ListVersions("a.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("ListVersions: %v, %v", versions, err)
+	}
+	if err := ls.RestoreVersion("a.txt", versions[0].ID); err != nil {
+		t.Fatalf("RestoreVersion: %v", err)
+	}
+
+	checkDownload(t, ls, "a.txt", "v1")
+	if info, err := ls.StatFile("a.txt"); err != nil || info.Tags["k"] != "v2" || info.SHA256 != sumOf("v1") {
+		t.Errorf("StatFile depois do restore: %+v, %v", info, err)
+	}
+	if got := versionContents(t, ls, "a.txt"); !slices.Equal(got, []string{"v2", "v1"}) {
+		t.Errorf("versões %v, esperado [v2 v1]", got)
+	}
+}
+
+// TestVersionInvalidID confere que IDs fora do formato gerado por
+// archiveVersion, inclusive caminhos, são rejeitados com ErrNotFound sem
+// acessar outros arquivos
+func TestVersionInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	ls := newTestVersionedStorage(t, dir, VersionPolicy{MaxVersions: 5})
+
+	uploadString(t, ls, "a.txt", "v1")
+	uploadString(t, ls, "a.txt", "v2")
+	uploadString(t, ls, "b.txt", "b")
+
+	versions, err := ls.ListVersions("a.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("ListVersions: %v, %v", versions, err)
+	}
+
+	ids := []string{
+		"",
+		".",
+		"..",
+		"../../b.txt",
+		versions[0].ID + versionRecordSuffix,
+		versions[0].ID + "/..",
+		strings.TrimSuffix(versions[0].ID, "Z"),
+		time.Now().UTC().Format(versionIDLayout),
+	}
+	for _, id := range ids {
+		rc, _, err := ls.DownloadVersion("a.txt", id)
+		if !errors.Is(err, ErrNotFound) {
+			if err == nil {
+				rc.Close()
+			}
+			t.Errorf("DownloadVersion(%q): %v, esperado ErrNotFound", id, err)
+		}
+		if err := ls.RestoreVersion("a.txt", id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("RestoreVersion(%q): %v, esperado ErrNotFound", id, err)
+		}
+	}
+
+	// A versão de a.txt não é de b.txt
+	if _, _, err := ls.DownloadVersion("b.txt", versions[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DownloadVersion de outro arquivo: %v, esperado ErrNotFound", err)
+	}
+	checkDownload(t, ls, "a.txt", "v2")
+	checkDownload(t, ls, "b.txt", "b")
+}
@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"grpc-rabbitmq-fileshare/common"
 	"grpc-rabbitmq-fileshare/grpc-server/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // Client representa o cliente gRPC
@@ -28,8 +37,8 @@ func NewClient(serverAddr string) (*Client, error) {
 		serverAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(50 * 1024 * 1024), // 50MB
-			grpc.MaxCallSendMsgSize(50 * 1024 * 1024), // 50MB
+			grpc.MaxCallRecvMsgSize(50*1024*1024), // 50MB
+			grpc.MaxCallSendMsgSize(50*1024*1024), // 50MB
 		),
 	)
 	if err != nil {
@@ -52,34 +61,138 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// ListFiles lista todos os arquivos disponíveis no servidor
-func (c *Client) ListFiles() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// ListFiles lista os arquivos e diretórios de opts.Dir no servidor, com os
+// filtros e a ordem de opts. Com opts.PageSize, lista uma página pela RPC
+// ListFiles e exibe o token da seguinte; sem ele, recebe a listagem inteira
+// pela RPC ListFilesStream, exibindo as entradas à medida que chegam
+func (c *Client) ListFiles(opts common.ListOptions) error {
+	req := &proto.ListRequest{
+		Dir:       opts.Dir,
+		Recursive: opts.Recursive,
+		Prefix:    opts.Prefix,
+		Pattern:   opts.Pattern,
+		Sort:      listSortProto(opts.Sort),
+		Reverse:   opts.Reverse,
+		PageSize:  int32(opts.PageSize),
+		PageToken: opts.PageToken,
+		Selector:  opts.Selector,
+	}
 
-	resp, err := c.client.ListFiles(ctx, &proto.Empty{})
-	if err != nil {
-		return fmt.Errorf("erro ao listar arquivos: %w", err)
+	count := 0
+	printFiles := func(files []*proto.FileInfo) {
+		for _, file := range files {
+			if count == 0 {
+				fmt.Println("📁 Arquivos disponíveis no servidor:")
+				fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+			}
+			count++
+			if file.IsDir {
+				fmt.Printf("  %d. %s/\n", count, file.Name)
+			} else {
+				fmt.Printf("  %d. %s\n", count, file.Name)
+			}
+			printFileDetails(file)
+		}
+	}
+
+	next := ""
+	if opts.PageSize > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resp, err := c.client.ListFiles(ctx, req)
+		if err != nil {
+			return fmt.Errorf("erro ao listar arquivos: %w", err)
+		}
+		printFiles(resp.FileInfos)
+		next = resp.NextPageToken
+	} else {
+		// Sem prazo fixo: a duração depende do tamanho do diretório
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := c.client.ListFilesStream(ctx, req)
+		if err != nil {
+			return fmt.Errorf("erro ao listar arquivos: %w", err)
+		}
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("erro ao listar arquivos: %w", err)
+			}
+			printFiles(resp.FileInfos)
+		}
 	}
 
-	if len(resp.Files) == 0 {
+	if count == 0 {
 		fmt.Println("📁 Nenhum arquivo encontrado no servidor")
 		return nil
 	}
-
-	fmt.Println("📁 Arquivos disponíveis no servidor:")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	for i, file := range resp.Files {
-		fmt.Printf("  %d. %s\n", i+1, file)
+	fmt.Printf("Total: %d item(ns)\n", count)
+	if next != "" {
+		fmt.Printf("Próxima página: --page-token %s\n", next)
 	}
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("Total: %d arquivo(s)\n", len(resp.Files))
 
 	return nil
 }
 
+// listSortProto converte a ordem de uma listagem para o enum do protocolo
+func listSortProto(sort common.ListSort) proto.ListSort {
+	switch sort {
+	case common.SortBySize:
+		return proto.ListSort_LIST_SORT_SIZE
+	case common.SortByModTime:
+		return proto.ListSort_LIST_SORT_MOD_TIME
+	default:
+		return proto.ListSort_LIST_SORT_NAME
+	}
+}
+
+// printFileDetails exibe tamanho, tipo, data de modificação e checksum de um
+// arquivo, ou apenas a data de modificação de um diretório
+func printFileDetails(file *proto.FileInfo) {
+	if file.IsDir {
+		fmt.Printf("     diretório | %s\n", file.ModTime.AsTime().Local().Format("2006-01-02 15:04:05"))
+		return
+	}
+	fmt.Printf("     %d bytes | %s | %s\n", file.Size, file.ContentType,
+		file.ModTime.AsTime().Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("     SHA-256: %s\n", file.Sha256)
+	if file.StoredSize > 0 && file.StoredSize < file.Size {
+		fmt.Printf("     Armazenado: %d bytes (compactado)\n", file.StoredSize)
+	} else if file.StoredSize > file.Size {
+		fmt.Printf("     Armazenado: %d bytes\n", file.StoredSize)
+	}
+	if file.Etag != "" {
+		fmt.Printf("     ETag: %s\n", file.Etag)
+	}
+	if file.ExpiresAt != nil {
+		fmt.Printf("     Expira em: %s\n", file.ExpiresAt.AsTime().Local().Format("2006-01-02 15:04:05"))
+	}
+	if len(file.Tags) > 0 {
+		fmt.Printf("     Tags: %s\n", formatTags(file.Tags))
+	}
+}
+
+// formatTags formata tags como "chave=valor", em ordem alfabética de chave
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
 // UploadFile faz upload de um arquivo para o servidor
-func (c *Client) UploadFile(filePath string) error {
+// O arquivo é enviado em blocos pela RPC UploadFileStream, então seu tamanho
+// não é limitado pelo tamanho máximo de mensagem do gRPC. As condições de opts
+// são avaliadas pelo servidor antes de substituir o arquivo. ttl é o TTL do
+// arquivo em segundos, negativo para não expirar e zero para o padrão do servidor
+func (c *Client) UploadFile(filePath string, dest string, opts common.UploadOptions, ttl int64) error {
 	// Abre o arquivo
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -87,25 +200,68 @@ func (c *Client) UploadFile(filePath string) error {
 	}
 	defer file.Close()
 
-	// Lê o conteúdo do arquivo
-	data, err := io.ReadAll(file)
+	// Calcula o checksum enviado no cabeçalho e volta ao início do arquivo
+	checksum, size, err := common.Checksum(file)
 	if err != nil {
 		return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
 	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+	}
 
-	// Obtém apenas o nome do arquivo (sem o caminho)
-	fileName := filepath.Base(filePath)
+	fileName := remoteName(filePath, dest)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Sem prazo fixo: a duração do upload depende do tamanho do arquivo
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Faz o upload
-	req := &proto.UploadRequest{
-		Name: fileName,
-		Data: data,
+	stream, err := c.client.UploadFileStream(ctx)
+	if err != nil {
+		return fmt.Errorf("erro ao fazer upload: %w", err)
+	}
+
+	// Envia o cabeçalho seguido dos blocos de dados
+	err = stream.Send(&proto.UploadChunk{
+		Payload: &proto.UploadChunk_Header{
+			Header: &proto.UploadHeader{
+				Name:        fileName,
+				Size:        size,
+				Checksum:    checksum,
+				IfMatch:     opts.IfMatch,
+				IfNoneMatch: opts.IfNoneMatch,
+				TtlSeconds:  ttl,
+				Tags:        opts.Tags,
+			},
+		},
+	})
+
+	for err == nil {
+		// Um bloco novo a cada mensagem: o gRPC pode manter referência à
+		// mensagem depois de Send, então ela não pode ser reaproveitada
+		buf := make([]byte, common.ChunkSize)
+		var n int
+		n, err = file.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&proto.UploadChunk{
+				Payload: &proto.UploadChunk_Data{Data: buf[:n]},
+			}); sendErr != nil {
+				err = sendErr
+			}
+		}
+	}
+
+	// io.EOF em Send indica que o servidor encerrou o stream; o motivo é
+	// obtido em CloseAndRecv
+	if err != io.EOF {
+		return fmt.Errorf("erro ao enviar arquivo: %w", err)
 	}
 
-	resp, err := c.client.UploadFile(ctx, req)
+	resp, err := stream.CloseAndRecv()
+	if status.Code(err) == codes.FailedPrecondition {
+		fmt.Printf("⚠️  Upload recusado: o arquivo no servidor não atende à condição\n")
+		fmt.Printf("   Mensagem: %s\n", status.Convert(err).Message())
+		return fmt.Errorf("upload recusado: %w", err)
+	}
 	if err != nil {
 		return fmt.Errorf("erro ao fazer upload: %w", err)
 	}
@@ -113,7 +269,11 @@ func (c *Client) UploadFile(filePath string) error {
 	if resp.Success {
 		fmt.Printf("✅ Upload realizado com sucesso!\n")
 		fmt.Printf("   Arquivo: %s\n", fileName)
-		fmt.Printf("   Tamanho: %d bytes\n", len(data))
+		fmt.Printf("   Tamanho: %d bytes\n", size)
+		fmt.Printf("   ETag: %s\n", resp.Etag)
+		if resp.ExpiresAt != nil {
+			fmt.Printf("   Expira em: %s\n", resp.ExpiresAt.AsTime().Local().Format("2006-01-02 15:04:05"))
+		}
 		fmt.Printf("   Mensagem: %s\n", resp.Message)
 	} else {
 		fmt.Printf("❌ Falha no upload!\n")
@@ -124,37 +284,482 @@ func (c *Client) UploadFile(filePath string) error {
 	return nil
 }
 
-// DownloadFile faz download de um arquivo do servidor
-func (c *Client) DownloadFile(fileName string, outputPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// remoteName retorna o nome no servidor de um upload de filePath: dest, se
+// informado, ou o nome do arquivo local. Um dest terminado em "/" indica o
+// diretório de destino, mantendo o nome do arquivo local
+func remoteName(filePath string, dest string) string {
+	base := filepath.Base(filePath)
+	if dest == "" {
+		return base
+	}
+	if strings.HasSuffix(dest, "/") {
+		return dest + base
+	}
+	return dest
+}
+
+// DownloadFile faz download de um arquivo, ou do trecho selecionado por opts,
+// do servidor. Os blocos recebidos pela RPC DownloadFileStream são gravados
+// direto no arquivo de saída e o conteúdo é conferido com o trailer enviado
+// pelo servidor
+func (c *Client) DownloadFile(fileName string, outputPath string, opts common.DownloadOptions) error {
+	// Sem prazo fixo: a duração do download depende do tamanho do arquivo
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Faz o download
 	req := &proto.DownloadRequest{
-		Name: fileName,
+		Name:   fileName,
+		Offset: opts.Offset,
+		Length: opts.Length,
 	}
 
-	resp, err := c.client.DownloadFile(ctx, req)
+	stream, err := c.client.DownloadFileStream(ctx, req)
 	if err != nil {
 		return fmt.Errorf("erro ao fazer download: %w", err)
 	}
 
-	// Se outputPath não foi especificado, usa o nome do arquivo
+	// Se outputPath não foi especificado, usa o nome do arquivo sem os
+	// diretórios do servidor
 	if outputPath == "" {
-		outputPath = fileName
+		outputPath = path.Base(fileName)
 	}
 
-	// Escreve o arquivo
-	err = os.WriteFile(outputPath, resp.Data, 0644)
+	// O conteúdo é conferido com o checksum enviado pelo servidor no
+	// trailer, o registrado no upload quando o arquivo é baixado inteiro,
+	// antes de o download ser considerado concluído
+	trailer, err := saveStream(stream, outputPath)
 	if err != nil {
-		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+		return fmt.Errorf("erro ao fazer download: %w", err)
 	}
 
 	fmt.Printf("✅ Download realizado com sucesso!\n")
 	fmt.Printf("   Arquivo: %s\n", fileName)
-	fmt.Printf("   Tamanho: %d bytes\n", len(resp.Data))
+	fmt.Printf("   Tamanho: %d bytes\n", trailer.Size)
+	if opts.Partial() {
+		fmt.Printf("   Trecho: %s\n", formatRange(trailer.Offset, trailer.Size, trailer.FileSize))
+	}
+	fmt.Printf("   SHA-256: %s (verificado)\n", trailer.Checksum)
 	fmt.Printf("   Salvo em: %s\n", outputPath)
 
 	return nil
 }
 
+// formatRange descreve o trecho de length bytes a partir de offset de um
+// arquivo de fileSize bytes
+func formatRange(offset, length, fileSize int64) string {
+	if length == 0 {
+		return fmt.Sprintf("vazio, a partir do byte %d de %d", offset, fileSize)
+	}
+	return fmt.Sprintf("bytes %d-%d de %d", offset, offset+length-1, fileSize)
+}
+
+// saveStream grava em outputPath o conteúdo recebido de um download em
+// streaming e retorna o trailer conferido. O conteúdo é gravado num
+// temporário no mesmo diretório, que só substitui outputPath depois de
+// conferido com o trailer; se a transferência falhar, um arquivo já
+// existente em outputPath fica intacto
+func saveStream(stream grpc.ServerStreamingClient[proto.DownloadChunk], outputPath string) (*proto.DownloadTrailer, error) {
+	// Aguarda a primeira mensagem para não criar o temporário se o
+	// servidor recusar a requisição
+	chunk, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(outputPath), ".download-*")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo %s: %w", outputPath, err)
+	}
+	tmpPath := file.Name()
+
+	trailer, err := receiveChunks(stream, chunk, file)
+	if err == nil {
+		// CreateTemp cria com 0600; o arquivo baixado fica com as mesmas
+		// permissões que os.Create daria
+		err = file.Chmod(0644)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, outputPath)
+	}
+	if err != nil {
+		// Remove só o temporário incompleto ou corrompido
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	return trailer, nil
+}
+
+// receiveChunks grava em w os blocos recebidos a partir de chunk até o
+// trailer, que é retornado depois de conferidos o tamanho e o checksum do
+// conteúdo recebido
+func receiveChunks(stream grpc.ServerStreamingClient[proto.DownloadChunk], chunk *proto.DownloadChunk, w io.Writer) (*proto.DownloadTrailer, error) {
+	hash := sha256.New()
+	dst := io.MultiWriter(w, hash)
+	var size int64
+
+	for {
+		if trailer := chunk.GetTrailer(); trailer != nil {
+			if size != trailer.Size {
+				return nil, fmt.Errorf("%w: esperado %d, recebido %d", common.ErrSizeMismatch, trailer.Size, size)
+			}
+			if sum := hex.EncodeToString(hash.Sum(nil)); sum != trailer.Checksum {
+				return nil, fmt.Errorf("%w: esperado %s, calculado %s", common.ErrChecksumMismatch, trailer.Checksum, sum)
+			}
+			return trailer, nil
+		}
+
+		n, err := dst.Write(chunk.GetData())
+		size += int64(n)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao salvar arquivo: %w", err)
+		}
+
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			return nil, fmt.Errorf("stream encerrado antes do trailer")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// UploadFileResumable faz upload de um arquivo usando uma sessão retomável
+// Se sessionID for vazio uma nova sessão é criada; caso contrário o envio
+// continua a partir do último byte confirmado pelo servidor. ttl, como em
+// UploadFile, e as tags só são usados ao criar a sessão
+func (c *Client) UploadFileResumable(filePath string, sessionID string, ttl int64, tags map[string]string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	checksum, size, err := common.Checksum(file)
+	if err != nil {
+		return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+	}
+
+	fileName := filepath.Base(filePath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cria a sessão ou consulta o progresso de uma sessão existente
+	var info *proto.UploadSessionInfo
+	if sessionID == "" {
+		info, err = c.client.CreateUploadSession(ctx, &proto.CreateUploadSessionRequest{
+			Name:       fileName,
+			Size:       size,
+			Checksum:   checksum,
+			TtlSeconds: ttl,
+			Tags:       tags,
+		})
+		if err != nil {
+			return fmt.Errorf("erro ao criar sessão de upload: %w", err)
+		}
+		fmt.Printf("🆕 Sessão de upload criada: %s\n", info.SessionId)
+	} else {
+		info, err = c.client.GetUploadSession(ctx, &proto.UploadSessionRequest{SessionId: sessionID})
+		if err != nil {
+			return fmt.Errorf("erro ao consultar sessão de upload: %w", err)
+		}
+		if info.Size != size {
+			return fmt.Errorf("a sessão %s espera %d bytes, mas o arquivo tem %d", sessionID, info.Size, size)
+		}
+		fmt.Printf("🔄 Retomando sessão %s a partir de %d de %d bytes\n", info.SessionId, info.Offset, info.Size)
+	}
+
+	if info.Offset < info.Size {
+		info, err = c.appendSession(ctx, file, info)
+		if err != nil {
+			fmt.Printf("❌ Upload interrompido!\n")
+			fmt.Printf("   Para retomar: upload-resume %s %s\n", filePath, info.SessionId)
+			return err
+		}
+	}
+
+	resp, err := c.client.CommitUploadSession(ctx, &proto.UploadSessionRequest{SessionId: info.SessionId})
+	if err != nil {
+		return fmt.Errorf("erro ao concluir sessão de upload: %w", err)
+	}
+
+	if !resp.Success {
+		fmt.Printf("❌ Falha no upload!\n")
+		fmt.Printf("   Mensagem: %s\n", resp.Message)
+		return fmt.Errorf("upload falhou: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ Upload realizado com sucesso!\n")
+	fmt.Printf("   Arquivo: %s\n", fileName)
+	fmt.Printf("   Tamanho: %d bytes\n", size)
+	fmt.Printf("   Mensagem: %s\n", resp.Message)
+
+	return nil
+}
+
+// appendSession envia o restante do arquivo a partir do offset confirmado da
+// sessão e retorna o estado atualizado. Em caso de falha retorna a sessão
+// recebida para que o chamador possa informar como retomar
+func (c *Client) appendSession(ctx context.Context, file *os.File, info *proto.UploadSessionInfo) (*proto.UploadSessionInfo, error) {
+	if _, err := file.Seek(info.Offset, io.SeekStart); err != nil {
+		return info, fmt.Errorf("erro ao ler arquivo: %w", err)
+	}
+
+	stream, err := c.client.AppendUploadSession(ctx)
+	if err != nil {
+		return info, fmt.Errorf("erro ao enviar dados da sessão: %w", err)
+	}
+
+	err = stream.Send(&proto.UploadSessionChunk{
+		Payload: &proto.UploadSessionChunk_Header{
+			Header: &proto.UploadSessionHeader{
+				SessionId: info.SessionId,
+				Offset:    info.Offset,
+			},
+		},
+	})
+
+	for err == nil {
+		// Como em UploadFile, um bloco novo a cada mensagem enviada
+		buf := make([]byte, common.ChunkSize)
+		var n int
+		n, err = file.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&proto.UploadSessionChunk{
+				Payload: &proto.UploadSessionChunk_Data{Data: buf[:n]},
+			}); sendErr != nil {
+				err = sendErr
+			}
+		}
+	}
+
+	if err != io.EOF {
+		return info, fmt.Errorf("erro ao enviar dados da sessão: %w", err)
+	}
+
+	updated, err := stream.CloseAndRecv()
+	if err != nil {
+		return info, fmt.Errorf("erro ao enviar dados da sessão: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteFile remove um arquivo do servidor
+func (c *Client) DeleteFile(fileName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.DeleteFile(ctx, &proto.DeleteRequest{Name: fileName})
+	if err != nil {
+		return fmt.Errorf("erro ao remover arquivo: %w", err)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// RenameFile renomeia um arquivo no servidor
+func (c *Client) RenameFile(oldName string, newName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.RenameFile(ctx, &proto.RenameRequest{
+		OldName: oldName,
+		NewName: newName,
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao renomear arquivo: %w", err)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// StatFile exibe as informações de um arquivo do servidor
+func (c *Client) StatFile(fileName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := c.client.StatFile(ctx, &proto.StatRequest{Name: fileName})
+	if err != nil {
+		return fmt.Errorf("erro ao consultar arquivo: %w", err)
+	}
+
+	fmt.Printf("📄 %s\n", info.Name)
+	printFileDetails(info)
+	return nil
+}
+
+// CreateDirectory cria um diretório no servidor
+func (c *Client) CreateDirectory(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.CreateDirectory(ctx, &proto.CreateDirectoryRequest{Name: name})
+	if err != nil {
+		return fmt.Errorf("erro ao criar diretório: %w", err)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// ListVersions lista as versões anteriores de um arquivo no servidor
+func (c *Client) ListVersions(fileName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ListVersions(ctx, &proto.ListVersionsRequest{Name: fileName})
+	if err != nil {
+		return fmt.Errorf("erro ao listar versões: %w", err)
+	}
+
+	if len(resp.Versions) == 0 {
+		fmt.Printf("🕘 Nenhuma versão anterior de %s\n", fileName)
+		return nil
+	}
+
+	fmt.Printf("🕘 Versões anteriores de %s:\n", fileName)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for i, version := range resp.Versions {
+		fmt.Printf("  %d. %s\n", i+1, version.VersionId)
+		fmt.Printf("     %d bytes | gravada em %s | substituída em %s\n", version.Size,
+			version.ModTime.AsTime().Local().Format("2006-01-02 15:04:05"),
+			version.ReplacedAt.AsTime().Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total: %d versão(ões)\n", len(resp.Versions))
+
+	return nil
+}
+
+// DownloadVersion faz download de uma versão anterior de um arquivo
+func (c *Client) DownloadVersion(fileName string, versionID string, outputPath string) error {
+	// Sem prazo fixo: a duração do download depende do tamanho do arquivo
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.client.DownloadVersionStream(ctx, &proto.VersionRequest{
+		Name:      fileName,
+		VersionId: versionID,
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao fazer download da versão: %w", err)
+	}
+
+	if outputPath == "" {
+		outputPath = path.Base(fileName) + "." + versionID
+	}
+
+	// Como em DownloadFile, o conteúdo é conferido com o checksum do
+	// trailer, o registrado quando a versão foi guardada
+	trailer, err := saveStream(stream, outputPath)
+	if err != nil {
+		return fmt.Errorf("erro ao fazer download da versão: %w", err)
+	}
+
+	fmt.Printf("✅ Download realizado com sucesso!\n")
+	fmt.Printf("   Arquivo: %s (versão %s)\n", fileName, versionID)
+	fmt.Printf("   Tamanho: %d bytes\n", trailer.Size)
+	fmt.Printf("   SHA-256: %s (verificado)\n", trailer.Checksum)
+	fmt.Printf("   Salvo em: %s\n", outputPath)
+
+	return nil
+}
+
+// RestoreVersion torna uma versão anterior a versão atual de um arquivo
+func (c *Client) RestoreVersion(fileName string, versionID string) error {
+	// Sem prazo fixo: a restauração copia o conteúdo da versão
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, err := c.client.RestoreVersion(ctx, &proto.VersionRequest{
+		Name:      fileName,
+		VersionId: versionID,
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao restaurar versão: %w", err)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// ListTrash lista os arquivos removidos e substituídos que estão na lixeira
+func (c *Client) ListTrash() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ListTrash(ctx, &proto.ListTrashRequest{})
+	if err != nil {
+		return fmt.Errorf("erro ao listar a lixeira: %w", err)
+	}
+
+	if len(resp.Entries) == 0 {
+		fmt.Println("🗑️  A lixeira está vazia")
+		return nil
+	}
+
+	fmt.Println("🗑️  Arquivos na lixeira:")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for i, entry := range resp.Entries {
+		action := "removido"
+		if entry.Overwritten {
+			action = "substituído"
+		}
+		fmt.Printf("  %d. %s\n", i+1, entry.Name)
+		fmt.Printf("     %d bytes | %s | %s em %s\n", entry.Size, entry.ContentType, action,
+			entry.DeletedAt.AsTime().Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("     ID: %s\n", entry.Id)
+		if entry.PurgeAt != nil {
+			fmt.Printf("     Removido definitivamente em: %s\n", entry.PurgeAt.AsTime().Local().Format("2006-01-02 15:04:05"))
+		}
+		if len(entry.Tags) > 0 {
+			fmt.Printf("     Tags: %s\n", formatTags(entry.Tags))
+		}
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total: %d entrada(s)\n", len(resp.Entries))
+
+	return nil
+}
+
+// RestoreTrash grava uma entrada da lixeira de volta no nome de onde foi removida
+func (c *Client) RestoreTrash(id string) error {
+	// Sem prazo fixo: a restauração copia o conteúdo da entrada
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, err := c.client.RestoreTrash(ctx, &proto.TrashRequest{Id: id})
+	if err != nil {
+		return fmt.Errorf("erro ao restaurar da lixeira: %w", err)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// PurgeTrash remove definitivamente uma entrada da lixeira ou, com all,
+// esvazia a lixeira
+func (c *Client) PurgeTrash(id string, all bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.client.PurgeTrash(ctx, &proto.TrashRequest{Id: id, All: all})
+	if err != nil {
+		return fmt.Errorf("erro ao esvaziar a lixeira: %w", err)
+	}
+
+	for _, entry := range resp.Entries {
+		fmt.Printf("🔥 %s (%s)\n", entry.Name, entry.Id)
+	}
+	fmt.Printf("✅ %d entrada(s) removida(s) da lixeira\n", len(resp.Entries))
+	return nil
+}
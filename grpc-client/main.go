@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+
+	"grpc-rabbitmq-fileshare/common"
 )
 
 const (
@@ -41,36 +45,285 @@ func main() {
 
 	switch command {
 	case "list":
-		if err := client.ListFiles(); err != nil {
+		// Aceita um diretório opcional, -r para listar os subdiretórios, os
+		// filtros --prefix, --pattern e --selector, a ordem (--sort e
+		// --reverse) e a paginação (--page-size e --page-token)
+		var opts common.ListOptions
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "-r", "--recursive":
+				opts.Recursive = true
+			case "--reverse":
+				opts.Reverse = true
+			case "--prefix", "--pattern", "--selector", "--sort", "--page-size", "--page-token":
+				if i+1 >= len(args) {
+					fmt.Printf("❌ Erro: %s requer um valor\n", args[i])
+					os.Exit(1)
+				}
+				value := args[i+1]
+				switch args[i] {
+				case "--prefix":
+					opts.Prefix = value
+				case "--pattern":
+					opts.Pattern = value
+				case "--selector":
+					if _, err := common.ParseTagSelector(value); err != nil {
+						fmt.Printf("❌ Erro: %v\n", err)
+						os.Exit(1)
+					}
+					opts.Selector = value
+				case "--sort":
+					sort, err := common.ParseListSort(value)
+					if err != nil {
+						fmt.Printf("❌ Erro: %v\n", err)
+						os.Exit(1)
+					}
+					opts.Sort = sort
+				case "--page-size":
+					n, err := strconv.Atoi(value)
+					if err != nil || n <= 0 {
+						fmt.Println("❌ Erro: --page-size requer um número positivo de entradas")
+						os.Exit(1)
+					}
+					opts.PageSize = n
+				case "--page-token":
+					opts.PageToken = value
+				}
+				i++
+			default:
+				opts.Dir = args[i]
+			}
+		}
+		if err := client.ListFiles(opts); err != nil {
 			log.Fatalf("Erro ao listar arquivos: %v", err)
 		}
 
 	case "upload":
-		if len(args) < 2 {
+		// Aceita um destino opcional, as condições --if-match <etag> e
+		// --if-none-match (o arquivo não pode existir no servidor), o TTL
+		// --ttl <duração|never> e tags --tag <chave=valor>, que podem se repetir
+		var positional []string
+		var opts common.UploadOptions
+		var ttl int64
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--ttl":
+				ttl = ttlArg(args, i)
+				i++
+			case "--tag":
+				opts.Tags = tagArg(args, i, opts.Tags)
+				i++
+			case "--if-match":
+				if i+1 >= len(args) {
+					fmt.Println("❌ Erro: --if-match requer o ETag esperado")
+					os.Exit(1)
+				}
+				i++
+				opts.IfMatch = args[i]
+			case "--if-none-match":
+				opts.IfNoneMatch = "*"
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) < 1 {
+			fmt.Println("❌ Erro: especifique o arquivo para upload")
+			fmt.Println("   Uso: upload <arquivo> [destino] [--if-match <etag>] [--if-none-match] [--ttl <duração|never>] [--tag <chave=valor>]...")
+			os.Exit(1)
+		}
+		filePath := positional[0]
+		dest := ""
+		if len(positional) >= 2 {
+			dest = positional[1]
+		}
+		if err := client.UploadFile(filePath, dest, opts, ttl); err != nil {
+			log.Fatalf("Erro ao fazer upload: %v", err)
+		}
+
+	case "upload-resume":
+		// O TTL (--ttl) e as tags (--tag) só são usados ao criar a sessão
+		var positional []string
+		var ttl int64
+		var tags map[string]string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--ttl":
+				ttl = ttlArg(args, i)
+				i++
+			case "--tag":
+				tags = tagArg(args, i, tags)
+				i++
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) < 1 {
 			fmt.Println("❌ Erro: especifique o arquivo para upload")
-			fmt.Println("   Uso: upload <arquivo>")
+			fmt.Println("   Uso: upload-resume <arquivo> [sessao] [--ttl <duração|never>] [--tag <chave=valor>]...")
 			os.Exit(1)
 		}
-		filePath := args[1]
-		if err := client.UploadFile(filePath); err != nil {
+		filePath := positional[0]
+		sessionID := ""
+		if len(positional) >= 2 {
+			sessionID = positional[1]
+		}
+		if err := client.UploadFileResumable(filePath, sessionID, ttl, tags); err != nil {
 			log.Fatalf("Erro ao fazer upload: %v", err)
 		}
 
 	case "download":
-		if len(args) < 2 {
+		// Aceita um arquivo de saída opcional e o trecho a baixar: --offset
+		// <n> e --length <n>, ou --tail <n> para os últimos n bytes
+		var positional []string
+		var opts common.DownloadOptions
+		hasOffset, hasTail := false, false
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--offset", "--length", "--tail":
+				if i+1 >= len(args) {
+					fmt.Printf("❌ Erro: %s requer um número de bytes\n", args[i])
+					os.Exit(1)
+				}
+				n, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil || n < 0 {
+					fmt.Printf("❌ Erro: %s requer um número de bytes não negativo\n", args[i])
+					os.Exit(1)
+				}
+				switch args[i] {
+				case "--offset":
+					opts.Offset, hasOffset = n, true
+				case "--length":
+					opts.Length = n
+				case "--tail":
+					opts.Offset, hasTail = -n, true
+				}
+				i++
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if hasOffset && hasTail {
+			fmt.Println("❌ Erro: use --offset ou --tail, não ambos")
+			os.Exit(1)
+		}
+		if len(positional) < 1 {
 			fmt.Println("❌ Erro: especifique o arquivo para download")
-			fmt.Println("   Uso: download <arquivo> [arquivo_saida]")
+			fmt.Println("   Uso: download <arquivo> [arquivo_saida] [--offset <n>] [--length <n>] [--tail <n>]")
 			os.Exit(1)
 		}
-		fileName := args[1]
+		fileName := positional[0]
 		outputPath := ""
-		if len(args) >= 3 {
-			outputPath = args[2]
+		if len(positional) >= 2 {
+			outputPath = positional[1]
 		}
-		if err := client.DownloadFile(fileName, outputPath); err != nil {
+		if err := client.DownloadFile(fileName, outputPath, opts); err != nil {
 			log.Fatalf("Erro ao fazer download: %v", err)
 		}
 
+	case "delete":
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique o arquivo a remover")
+			fmt.Println("   Uso: delete <arquivo>")
+			os.Exit(1)
+		}
+		if err := client.DeleteFile(args[1]); err != nil {
+			log.Fatalf("Erro ao remover arquivo: %v", err)
+		}
+
+	case "rename":
+		if len(args) < 3 {
+			fmt.Println("❌ Erro: especifique o arquivo e o novo nome")
+			fmt.Println("   Uso: rename <arquivo> <novo_nome>")
+			os.Exit(1)
+		}
+		if err := client.RenameFile(args[1], args[2]); err != nil {
+			log.Fatalf("Erro ao renomear arquivo: %v", err)
+		}
+
+	case "stat":
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique o arquivo")
+			fmt.Println("   Uso: stat <arquivo>")
+			os.Exit(1)
+		}
+		if err := client.StatFile(args[1]); err != nil {
+			log.Fatalf("Erro ao consultar arquivo: %v", err)
+		}
+
+	case "mkdir":
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique o diretório a criar")
+			fmt.Println("   Uso: mkdir <diretorio>")
+			os.Exit(1)
+		}
+		if err := client.CreateDirectory(args[1]); err != nil {
+			log.Fatalf("Erro ao criar diretório: %v", err)
+		}
+
+	case "versions":
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique o arquivo")
+			fmt.Println("   Uso: versions <arquivo>")
+			os.Exit(1)
+		}
+		if err := client.ListVersions(args[1]); err != nil {
+			log.Fatalf("Erro ao listar versões: %v", err)
+		}
+
+	case "download-version":
+		if len(args) < 3 {
+			fmt.Println("❌ Erro: especifique o arquivo e a versão")
+			fmt.Println("   Uso: download-version <arquivo> <versao> [arquivo_saida]")
+			os.Exit(1)
+		}
+		outputPath := ""
+		if len(args) >= 4 {
+			outputPath = args[3]
+		}
+		if err := client.DownloadVersion(args[1], args[2], outputPath); err != nil {
+			log.Fatalf("Erro ao fazer download da versão: %v", err)
+		}
+
+	case "restore":
+		if len(args) < 3 {
+			fmt.Println("❌ Erro: especifique o arquivo e a versão")
+			fmt.Println("   Uso: restore <arquivo> <versao>")
+			os.Exit(1)
+		}
+		if err := client.RestoreVersion(args[1], args[2]); err != nil {
+			log.Fatalf("Erro ao restaurar versão: %v", err)
+		}
+
+	case "list-trash":
+		if err := client.ListTrash(); err != nil {
+			log.Fatalf("Erro ao listar a lixeira: %v", err)
+		}
+
+	case "restore-trash":
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique a entrada da lixeira")
+			fmt.Println("   Uso: restore-trash <id>")
+			os.Exit(1)
+		}
+		if err := client.RestoreTrash(args[1]); err != nil {
+			log.Fatalf("Erro ao restaurar da lixeira: %v", err)
+		}
+
+	case "purge-trash":
+		// Remove uma entrada ou, com --all, todas
+		if len(args) < 2 {
+			fmt.Println("❌ Erro: especifique a entrada da lixeira ou --all")
+			fmt.Println("   Uso: purge-trash <id> | purge-trash --all")
+			os.Exit(1)
+		}
+		id, all := args[1], false
+		if id == "--all" {
+			id, all = "", true
+		}
+		if err := client.PurgeTrash(id, all); err != nil {
+			log.Fatalf("Erro ao esvaziar a lixeira: %v", err)
+		}
+
 	default:
 		fmt.Printf("❌ Comando desconhecido: %s\n", command)
 		printUsage()
@@ -87,9 +340,37 @@ func printUsage() {
 	fmt.Println("  go run main.go client.go [flags] <comando> [argumentos]")
 	fmt.Println()
 	fmt.Println("Comandos:")
-	fmt.Println("  list                          Lista todos os arquivos no servidor")
-	fmt.Println("  upload <arquivo>              Faz upload de um arquivo")
+	fmt.Println("  list [diretorio] [-r]         Lista um diretório do servidor (-r inclui subdiretórios)")
+	fmt.Println("       [--prefix <p>]           Só nomes, relativos ao diretório, que começam com p")
+	fmt.Println("       [--pattern <glob>]       Só nomes que combinam com o padrão, como \"*.txt\"")
+	fmt.Println("       [--selector <seletor>]   Só arquivos com as tags do seletor, como env=prod,owner,!temp")
+	fmt.Println("       [--sort name|size|mod_time] [--reverse]")
+	fmt.Println("                                Ordem da listagem (padrão: name)")
+	fmt.Println("       [--page-size <n>]        Lista só n entradas e exibe o token da próxima página")
+	fmt.Println("       [--page-token <token>]   Continua a listagem a partir da página anterior")
+	fmt.Println("  upload <arquivo> [destino]    Faz upload de um arquivo (destino como docs/ ou docs/a.txt)")
+	fmt.Println("       [--if-match <etag>]      Só substitui se o ETag atual for este")
+	fmt.Println("       [--if-none-match]        Só grava se o arquivo ainda não existir")
+	fmt.Println("       [--ttl <duração|never>]  Remove o arquivo depois da duração, como 90m ou 24h; never")
+	fmt.Println("                                não expira (padrão: o TTL padrão do servidor)")
+	fmt.Println("       [--tag <chave=valor>]    Grava uma tag com o arquivo; pode se repetir")
+	fmt.Println("  upload-resume <arquivo> [sessao] [--ttl <duração|never>] [--tag <chave=valor>]...")
+	fmt.Println("                                Faz upload retomável, continuando a sessão informada")
 	fmt.Println("  download <arquivo> [saida]    Faz download de um arquivo")
+	fmt.Println("       [--offset <n>]           A partir do byte n")
+	fmt.Println("       [--length <n>]           Só n bytes")
+	fmt.Println("       [--tail <n>]             Só os últimos n bytes")
+	fmt.Println("  delete <arquivo>              Remove um arquivo")
+	fmt.Println("  rename <arquivo> <novo_nome>  Renomeia um arquivo")
+	fmt.Println("  stat <arquivo>                Exibe as informações de um arquivo")
+	fmt.Println("  mkdir <diretorio>             Cria um diretório")
+	fmt.Println("  versions <arquivo>            Lista as versões anteriores de um arquivo")
+	fmt.Println("  download-version <arquivo> <versao> [saida]")
+	fmt.Println("                                Faz download de uma versão anterior")
+	fmt.Println("  restore <arquivo> <versao>    Restaura uma versão anterior")
+	fmt.Println("  list-trash                    Lista os arquivos removidos ou substituídos na lixeira")
+	fmt.Println("  restore-trash <id>            Restaura um arquivo da lixeira no seu nome original")
+	fmt.Println("  purge-trash <id> | --all      Remove definitivamente uma entrada, ou todas, da lixeira")
 	fmt.Println()
 	fmt.Println("Flags:")
 	fmt.Println("  -server <endereço>            Endereço do servidor (padrão: localhost:50051)")
@@ -97,7 +378,59 @@ func printUsage() {
 	fmt.Println("Exemplos:")
 	fmt.Println("  go run main.go client.go list")
 	fmt.Println("  go run main.go client.go upload arquivo.txt")
+	fmt.Println("  go run main.go client.go upload arquivo.txt docs/2024/")
+	fmt.Println("  go run main.go client.go upload arquivo.txt --if-match <etag>")
+	fmt.Println("  go run main.go client.go upload rascunho.txt --ttl 24h")
+	fmt.Println("  go run main.go client.go upload app.tar.gz builds/ --tag env=prod --tag build=1234")
+	fmt.Println("  go run main.go client.go list docs -r")
+	fmt.Println("  go run main.go client.go list docs -r --pattern \"*.pdf\" --sort size --reverse")
+	fmt.Println("  go run main.go client.go list --page-size 100")
+	fmt.Println("  go run main.go client.go list builds --selector env=prod")
+	fmt.Println("  go run main.go client.go upload-resume video.mp4 <sessao>")
 	fmt.Println("  go run main.go client.go download arquivo.txt")
 	fmt.Println("  go run main.go client.go download arquivo.txt copia.txt")
+	fmt.Println("  go run main.go client.go download app.log --tail 4096")
+	fmt.Println("  go run main.go client.go rename arquivo.txt relatorio.txt")
+	fmt.Println("  go run main.go client.go restore-trash <id>")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
+
+// ttlArg lê o valor de --ttl em args[i+1] e o converte para o TTL em
+// segundos enviado ao servidor: "never" é -1 (não expira) e uma duração é
+// arredondada para cima até o segundo. Encerra o programa se o valor faltar
+// ou for inválido
+func ttlArg(args []string, i int) int64 {
+	if i+1 >= len(args) {
+		fmt.Println("❌ Erro: --ttl requer uma duração, como 24h, ou never")
+		os.Exit(1)
+	}
+	if args[i+1] == "never" {
+		return -1
+	}
+	ttl, err := time.ParseDuration(args[i+1])
+	if err != nil || ttl <= 0 {
+		fmt.Println("❌ Erro: --ttl requer uma duração positiva, como 90m ou 24h, ou never")
+		os.Exit(1)
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// tagArg lê a tag de --tag em args[i+1], no formato chave=valor, e a
+// acrescenta a tags, que é criado se for nil. Encerra o programa se o valor
+// faltar ou for inválido
+func tagArg(args []string, i int, tags map[string]string) map[string]string {
+	if i+1 >= len(args) {
+		fmt.Println("❌ Erro: --tag requer uma tag no formato chave=valor")
+		os.Exit(1)
+	}
+	key, value, err := common.ParseTag(args[i+1])
+	if err != nil {
+		fmt.Printf("❌ Erro: %v\n", err)
+		os.Exit(1)
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[key] = value
+	return tags
+}
@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,6 +22,56 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Ordem das entradas de uma listagem
+type ListSort int32
+
+const (
+	ListSort_LIST_SORT_NAME     ListSort = 0 // Alfabética, com o conteúdo de cada subdiretório logo após ele
+	ListSort_LIST_SORT_SIZE     ListSort = 1 // Do menor para o maior
+	ListSort_LIST_SORT_MOD_TIME ListSort = 2 // Do mais antigo para o mais recente
+)
+
+// Enum value maps for ListSort.
+var (
+	ListSort_name = map[int32]string{
+		0: "LIST_SORT_NAME",
+		1: "LIST_SORT_SIZE",
+		2: "LIST_SORT_MOD_TIME",
+	}
+	ListSort_value = map[string]int32{
+		"LIST_SORT_NAME":     0,
+		"LIST_SORT_SIZE":     1,
+		"LIST_SORT_MOD_TIME": 2,
+	}
+)
+
+func (x ListSort) Enum() *ListSort {
+	p := new(ListSort)
+	*p = x
+	return p
+}
+
+func (x ListSort) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ListSort) Descriptor() protoreflect.EnumDescriptor {
+	return file_grpc_server_proto_fileservice_proto_enumTypes[0].Descriptor()
+}
+
+func (ListSort) Type() protoreflect.EnumType {
+	return &file_grpc_server_proto_fileservice_proto_enumTypes[0]
+}
+
+func (x ListSort) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ListSort.Descriptor instead.
+func (ListSort) EnumDescriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{0}
+}
+
 // Mensagem vazia para requisições sem parâmetros
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -28,21 +79,1477 @@ type Empty struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[0]
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{0}
+}
+
+// Informações de um arquivo armazenado
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	ModTime       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`                                                                        // Hash do conteúdo em hexadecimal
+	ContentType   string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`                                           // Tipo MIME do conteúdo
+	IsDir         bool                   `protobuf:"varint,6,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`                                                            // Indica um diretório; size, sha256, content_type e etag ficam vazios
+	Etag          string                 `protobuf:"bytes,7,opt,name=etag,proto3" json:"etag,omitempty"`                                                                            // Identifica o conteúdo atual, para uploads condicionais
+	StoredSize    int64                  `protobuf:"varint,8,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"`                                             // Bytes ocupados no armazenamento, menos que size se compactado; zero se desconhecido
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`                                                 // Quando o arquivo expira e é removido; ausente se não expira
+	Tags          map[string]string      `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags gravadas no upload
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FileInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetModTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModTime
+	}
+	return nil
+}
+
+func (x *FileInfo) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileInfo) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileInfo) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *FileInfo) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *FileInfo) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *FileInfo) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *FileInfo) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Requisição para listar um diretório
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dir           string                 `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`              // Diretório listado; vazio lista a raiz
+	Recursive     bool                   `protobuf:"varint,2,opt,name=recursive,proto3" json:"recursive,omitempty"` // Inclui o conteúdo dos subdiretórios
+	Prefix        string                 `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`        // Só nomes, relativos a dir, que começam com prefix
+	Pattern       string                 `protobuf:"bytes,4,opt,name=pattern,proto3" json:"pattern,omitempty"`      // Só nomes cujo último elemento combina com o padrão, como "*.txt"
+	Sort          ListSort               `protobuf:"varint,5,opt,name=sort,proto3,enum=fileservice.ListSort" json:"sort,omitempty"`
+	Reverse       bool                   `protobuf:"varint,6,opt,name=reverse,proto3" json:"reverse,omitempty"`                     // Inverte a ordem
+	PageSize      int32                  `protobuf:"varint,7,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // Máximo de entradas por resposta; zero retorna todas (no streaming, 1000)
+	PageToken     string                 `protobuf:"bytes,8,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token da resposta anterior, para continuar a listagem
+	Selector      string                 `protobuf:"bytes,9,opt,name=selector,proto3" json:"selector,omitempty"`                    // Só arquivos cujas tags atendem ao seletor, como "env=prod,owner"; exclui diretórios
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListRequest) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *ListRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+func (x *ListRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *ListRequest) GetSort() ListSort {
+	if x != nil {
+		return x.Sort
+	}
+	return ListSort_LIST_SORT_NAME
+}
+
+func (x *ListRequest) GetReverse() bool {
+	if x != nil {
+		return x.Reverse
+	}
+	return false
+}
+
+func (x *ListRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListRequest) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+// Resposta com lista de arquivos
+type FileListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []string               `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"` // Apenas os nomes, mantido para clientes antigos
+	FileInfos     []*FileInfo            `protobuf:"bytes,2,rep,name=file_infos,json=fileInfos,proto3" json:"file_infos,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,3,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Token da próxima página; vazio na última
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileListResponse) Reset() {
+	*x = FileListResponse{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileListResponse) ProtoMessage() {}
+
+func (x *FileListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileListResponse.ProtoReflect.Descriptor instead.
+func (*FileListResponse) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FileListResponse) GetFiles() []string {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *FileListResponse) GetFileInfos() []*FileInfo {
+	if x != nil {
+		return x.FileInfos
+	}
+	return nil
+}
+
+func (x *FileListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Requisição para upload de arquivo
+type UploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	IfMatch       string                 `protobuf:"bytes,3,opt,name=if_match,json=ifMatch,proto3" json:"if_match,omitempty"`                                                      // Só grava se o ETag atual for este
+	IfNoneMatch   string                 `protobuf:"bytes,4,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"`                                        // "*": só grava se o arquivo não existir; ETag: só se o atual for outro
+	TtlSeconds    int64                  `protobuf:"varint,5,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`                                            // Tempo até o arquivo expirar; zero usa o padrão do servidor, negativo não expira
+	Tags          map[string]string      `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags gravadas com o arquivo, substituindo as do arquivo anterior
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadRequest) Reset() {
+	*x = UploadRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadRequest) ProtoMessage() {}
+
+func (x *UploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
+func (*UploadRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UploadRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadRequest) GetIfMatch() string {
+	if x != nil {
+		return x.IfMatch
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetIfNoneMatch() string {
+	if x != nil {
+		return x.IfNoneMatch
+	}
+	return ""
+}
+
+func (x *UploadRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *UploadRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Cabeçalho de um upload em streaming, enviado na primeira mensagem
+type UploadHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                                                                          // Tamanho total do arquivo em bytes
+	Checksum      string                 `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`                                                                   // SHA-256 do conteúdo em hexadecimal
+	IfMatch       string                 `protobuf:"bytes,4,opt,name=if_match,json=ifMatch,proto3" json:"if_match,omitempty"`                                                      // Só grava se o ETag atual for este
+	IfNoneMatch   string                 `protobuf:"bytes,5,opt,name=if_none_match,json=ifNoneMatch,proto3" json:"if_none_match,omitempty"`                                        // "*": só grava se o arquivo não existir; ETag: só se o atual for outro
+	TtlSeconds    int64                  `protobuf:"varint,6,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`                                            // Tempo até o arquivo expirar; zero usa o padrão do servidor, negativo não expira
+	Tags          map[string]string      `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags gravadas com o arquivo, substituindo as do arquivo anterior
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadHeader) Reset() {
+	*x = UploadHeader{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadHeader) ProtoMessage() {}
+
+func (x *UploadHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadHeader.ProtoReflect.Descriptor instead.
+func (*UploadHeader) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UploadHeader) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UploadHeader) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadHeader) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *UploadHeader) GetIfMatch() string {
+	if x != nil {
+		return x.IfMatch
+	}
+	return ""
+}
+
+func (x *UploadHeader) GetIfNoneMatch() string {
+	if x != nil {
+		return x.IfNoneMatch
+	}
+	return ""
+}
+
+func (x *UploadHeader) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *UploadHeader) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Mensagem de um upload em streaming: o cabeçalho seguido dos blocos de dados
+type UploadChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*UploadChunk_Header
+	//	*UploadChunk_Data
+	Payload       isUploadChunk_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadChunk) Reset() {
+	*x = UploadChunk{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadChunk) ProtoMessage() {}
+
+func (x *UploadChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadChunk.ProtoReflect.Descriptor instead.
+func (*UploadChunk) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UploadChunk) GetPayload() isUploadChunk_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *UploadChunk) GetHeader() *UploadHeader {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadChunk_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *UploadChunk) GetData() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadChunk_Data); ok {
+			return x.Data
+		}
+	}
+	return nil
+}
+
+type isUploadChunk_Payload interface {
+	isUploadChunk_Payload()
+}
+
+type UploadChunk_Header struct {
+	Header *UploadHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type UploadChunk_Data struct {
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3,oneof"`
+}
+
+func (*UploadChunk_Header) isUploadChunk_Payload() {}
+
+func (*UploadChunk_Data) isUploadChunk_Payload() {}
+
+// Resultado de uma operação
+type OperationResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Etag          string                 `protobuf:"bytes,3,opt,name=etag,proto3" json:"etag,omitempty"`                            // ETag do arquivo gravado, em uploads
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Quando o arquivo gravado expira, em uploads com TTL
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationResult) Reset() {
+	*x = OperationResult{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationResult) ProtoMessage() {}
+
+func (x *OperationResult) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationResult.ProtoReflect.Descriptor instead.
+func (*OperationResult) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *OperationResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *OperationResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *OperationResult) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *OperationResult) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+// Requisição para download de arquivo
+// Com offset ou length, só o trecho selecionado é enviado
+type DownloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // Posição do primeiro byte; negativo conta a partir do fim do arquivo
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"` // Número máximo de bytes; 0 lê até o fim do arquivo
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadRequest) Reset() {
+	*x = DownloadRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadRequest) ProtoMessage() {}
+
+func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
+func (*DownloadRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DownloadRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DownloadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+// Resposta com dados do arquivo
+type DownloadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Checksum      string                 `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`                  // SHA-256 dos dados em hexadecimal; o registrado no upload se for o arquivo inteiro
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`                     // Posição do primeiro byte enviado
+	FileSize      int64                  `protobuf:"varint,4,opt,name=file_size,json=fileSize,proto3" json:"file_size,omitempty"` // Tamanho do arquivo inteiro em bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadResponse) Reset() {
+	*x = DownloadResponse{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadResponse) ProtoMessage() {}
+
+func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
+func (*DownloadResponse) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DownloadResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *DownloadResponse) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *DownloadResponse) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadResponse) GetFileSize() int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return 0
+}
+
+// Trailer de um download em streaming, enviado após o último bloco de dados
+type DownloadTrailer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Size          int64                  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`                         // Total de bytes enviados
+	Checksum      string                 `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`                  // SHA-256 dos dados em hexadecimal; o registrado no upload se for o arquivo inteiro
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`                     // Posição do primeiro byte enviado
+	FileSize      int64                  `protobuf:"varint,4,opt,name=file_size,json=fileSize,proto3" json:"file_size,omitempty"` // Tamanho do arquivo inteiro em bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadTrailer) Reset() {
+	*x = DownloadTrailer{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadTrailer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadTrailer) ProtoMessage() {}
+
+func (x *DownloadTrailer) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadTrailer.ProtoReflect.Descriptor instead.
+func (*DownloadTrailer) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DownloadTrailer) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *DownloadTrailer) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *DownloadTrailer) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadTrailer) GetFileSize() int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return 0
+}
+
+// Mensagem de um download em streaming: blocos de dados seguidos do trailer
+type DownloadChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*DownloadChunk_Data
+	//	*DownloadChunk_Trailer
+	Payload       isDownloadChunk_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadChunk) Reset() {
+	*x = DownloadChunk{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadChunk) ProtoMessage() {}
+
+func (x *DownloadChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadChunk.ProtoReflect.Descriptor instead.
+func (*DownloadChunk) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DownloadChunk) GetPayload() isDownloadChunk_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *DownloadChunk) GetData() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*DownloadChunk_Data); ok {
+			return x.Data
+		}
+	}
+	return nil
+}
+
+func (x *DownloadChunk) GetTrailer() *DownloadTrailer {
+	if x != nil {
+		if x, ok := x.Payload.(*DownloadChunk_Trailer); ok {
+			return x.Trailer
+		}
+	}
+	return nil
+}
+
+type isDownloadChunk_Payload interface {
+	isDownloadChunk_Payload()
+}
+
+type DownloadChunk_Data struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3,oneof"`
+}
+
+type DownloadChunk_Trailer struct {
+	Trailer *DownloadTrailer `protobuf:"bytes,2,opt,name=trailer,proto3,oneof"`
+}
+
+func (*DownloadChunk_Data) isDownloadChunk_Payload() {}
+
+func (*DownloadChunk_Trailer) isDownloadChunk_Payload() {}
+
+// Requisição para iniciar uma sessão de upload retomável
+type CreateUploadSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                                                                          // Tamanho total do arquivo em bytes
+	Checksum      string                 `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`                                                                   // SHA-256 do conteúdo em hexadecimal
+	TtlSeconds    int64                  `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`                                            // Tempo, após a conclusão, até o arquivo expirar; zero usa o padrão do servidor, negativo não expira
+	Tags          map[string]string      `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags gravadas com o arquivo na conclusão
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateUploadSessionRequest) Reset() {
+	*x = CreateUploadSessionRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateUploadSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateUploadSessionRequest) ProtoMessage() {}
+
+func (x *CreateUploadSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateUploadSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateUploadSessionRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CreateUploadSessionRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateUploadSessionRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *CreateUploadSessionRequest) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *CreateUploadSessionRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *CreateUploadSessionRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Requisição que identifica uma sessão de upload
+type UploadSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSessionRequest) Reset() {
+	*x = UploadSessionRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSessionRequest) ProtoMessage() {}
+
+func (x *UploadSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSessionRequest.ProtoReflect.Descriptor instead.
+func (*UploadSessionRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UploadSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+// Estado de uma sessão de upload
+type UploadSessionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Offset        int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"` // Bytes já confirmados pelo servidor
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSessionInfo) Reset() {
+	*x = UploadSessionInfo{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSessionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSessionInfo) ProtoMessage() {}
+
+func (x *UploadSessionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSessionInfo.ProtoReflect.Descriptor instead.
+func (*UploadSessionInfo) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UploadSessionInfo) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UploadSessionInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UploadSessionInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadSessionInfo) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Cabeçalho de um envio de dados para uma sessão de upload
+type UploadSessionHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // Posição do primeiro byte enviado
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSessionHeader) Reset() {
+	*x = UploadSessionHeader{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSessionHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSessionHeader) ProtoMessage() {}
+
+func (x *UploadSessionHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSessionHeader.ProtoReflect.Descriptor instead.
+func (*UploadSessionHeader) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UploadSessionHeader) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *UploadSessionHeader) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Mensagem de um envio para uma sessão: o cabeçalho seguido dos blocos de dados
+type UploadSessionChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*UploadSessionChunk_Header
+	//	*UploadSessionChunk_Data
+	Payload       isUploadSessionChunk_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadSessionChunk) Reset() {
+	*x = UploadSessionChunk{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadSessionChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadSessionChunk) ProtoMessage() {}
+
+func (x *UploadSessionChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadSessionChunk.ProtoReflect.Descriptor instead.
+func (*UploadSessionChunk) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UploadSessionChunk) GetPayload() isUploadSessionChunk_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *UploadSessionChunk) GetHeader() *UploadSessionHeader {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadSessionChunk_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *UploadSessionChunk) GetData() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadSessionChunk_Data); ok {
+			return x.Data
+		}
+	}
+	return nil
+}
+
+type isUploadSessionChunk_Payload interface {
+	isUploadSessionChunk_Payload()
+}
+
+type UploadSessionChunk_Header struct {
+	Header *UploadSessionHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type UploadSessionChunk_Data struct {
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3,oneof"`
+}
+
+func (*UploadSessionChunk_Header) isUploadSessionChunk_Payload() {}
+
+func (*UploadSessionChunk_Data) isUploadSessionChunk_Payload() {}
+
+// Requisição para remover um arquivo
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DeleteRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Requisição para renomear um arquivo
+type RenameRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OldName       string                 `protobuf:"bytes,1,opt,name=old_name,json=oldName,proto3" json:"old_name,omitempty"`
+	NewName       string                 `protobuf:"bytes,2,opt,name=new_name,json=newName,proto3" json:"new_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameRequest) Reset() {
+	*x = RenameRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameRequest) ProtoMessage() {}
+
+func (x *RenameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameRequest.ProtoReflect.Descriptor instead.
+func (*RenameRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RenameRequest) GetOldName() string {
+	if x != nil {
+		return x.OldName
+	}
+	return ""
+}
+
+func (x *RenameRequest) GetNewName() string {
+	if x != nil {
+		return x.NewName
+	}
+	return ""
+}
+
+// Requisição para consultar as informações de um arquivo
+type StatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatRequest) Reset() {
+	*x = StatRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatRequest) ProtoMessage() {}
+
+func (x *StatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *StatRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Requisição para criar um diretório
+type CreateDirectoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDirectoryRequest) Reset() {
+	*x = CreateDirectoryRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDirectoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDirectoryRequest) ProtoMessage() {}
+
+func (x *CreateDirectoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDirectoryRequest.ProtoReflect.Descriptor instead.
+func (*CreateDirectoryRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CreateDirectoryRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Versão anterior de um arquivo
+type FileVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VersionId     string                 `protobuf:"bytes,1,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	ModTime       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`          // Data em que o conteúdo foi gravado
+	ReplacedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=replaced_at,json=replacedAt,proto3" json:"replaced_at,omitempty"` // Data em que foi substituído por outra versão
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileVersion) Reset() {
+	*x = FileVersion{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Empty) String() string {
+func (x *FileVersion) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*FileVersion) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[0]
+func (x *FileVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -53,34 +1560,62 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use FileVersion.ProtoReflect.Descriptor instead.
+func (*FileVersion) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{21}
 }
 
-// Resposta com lista de arquivos
-type FileListResponse struct {
+func (x *FileVersion) GetVersionId() string {
+	if x != nil {
+		return x.VersionId
+	}
+	return ""
+}
+
+func (x *FileVersion) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileVersion) GetModTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModTime
+	}
+	return nil
+}
+
+func (x *FileVersion) GetReplacedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReplacedAt
+	}
+	return nil
+}
+
+// Requisição para listar as versões anteriores de um arquivo
+type ListVersionsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Files         []string               `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileListResponse) Reset() {
-	*x = FileListResponse{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[1]
+func (x *ListVersionsRequest) Reset() {
+	*x = ListVersionsRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileListResponse) String() string {
+func (x *ListVersionsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileListResponse) ProtoMessage() {}
+func (*ListVersionsRequest) ProtoMessage() {}
 
-func (x *FileListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[1]
+func (x *ListVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -91,42 +1626,87 @@ func (x *FileListResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileListResponse.ProtoReflect.Descriptor instead.
-func (*FileListResponse) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use ListVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *FileListResponse) GetFiles() []string {
+func (x *ListVersionsRequest) GetName() string {
 	if x != nil {
-		return x.Files
+		return x.Name
+	}
+	return ""
+}
+
+// Resposta com as versões anteriores de um arquivo, da mais recente para a mais antiga
+type VersionListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Versions      []*FileVersion         `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionListResponse) Reset() {
+	*x = VersionListResponse{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionListResponse) ProtoMessage() {}
+
+func (x *VersionListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionListResponse.ProtoReflect.Descriptor instead.
+func (*VersionListResponse) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *VersionListResponse) GetVersions() []*FileVersion {
+	if x != nil {
+		return x.Versions
 	}
 	return nil
 }
 
-// Requisição para upload de arquivo
-type UploadRequest struct {
+// Requisição que identifica uma versão anterior de um arquivo
+type VersionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	VersionId     string                 `protobuf:"bytes,2,opt,name=version_id,json=versionId,proto3" json:"version_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadRequest) Reset() {
-	*x = UploadRequest{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[2]
+func (x *VersionRequest) Reset() {
+	*x = VersionRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadRequest) String() string {
+func (x *VersionRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadRequest) ProtoMessage() {}
+func (*VersionRequest) ProtoMessage() {}
 
-func (x *UploadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[2]
+func (x *VersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -137,49 +1717,57 @@ func (x *UploadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
-func (*UploadRequest) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use VersionRequest.ProtoReflect.Descriptor instead.
+func (*VersionRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{24}
 }
 
-func (x *UploadRequest) GetName() string {
+func (x *VersionRequest) GetName() string {
 	if x != nil {
 		return x.Name
 	}
 	return ""
 }
 
-func (x *UploadRequest) GetData() []byte {
+func (x *VersionRequest) GetVersionId() string {
 	if x != nil {
-		return x.Data
+		return x.VersionId
 	}
-	return nil
+	return ""
 }
 
-// Resultado de uma operação
-type OperationResult struct {
+// Arquivo removido ou substituído que está na lixeira
+type TrashEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"` // Nome do arquivo quando foi removido
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	ContentType   string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ModTime       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`                                                       // Data em que o conteúdo foi gravado
+	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`                                                 // Data em que foi removido ou substituído
+	PurgeAt       *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=purge_at,json=purgeAt,proto3" json:"purge_at,omitempty"`                                                       // Data em que deixa a lixeira; ausente se não houver prazo
+	Overwritten   bool                   `protobuf:"varint,9,opt,name=overwritten,proto3" json:"overwritten,omitempty"`                                                             // Substituído por um upload, e não removido
+	Tags          map[string]string      `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Tags do arquivo, regravadas ao restaurá-lo
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *OperationResult) Reset() {
-	*x = OperationResult{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[3]
+func (x *TrashEntry) Reset() {
+	*x = TrashEntry{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *OperationResult) String() string {
+func (x *TrashEntry) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OperationResult) ProtoMessage() {}
+func (*TrashEntry) ProtoMessage() {}
 
-func (x *OperationResult) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[3]
+func (x *TrashEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -190,48 +1778,103 @@ func (x *OperationResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OperationResult.ProtoReflect.Descriptor instead.
-func (*OperationResult) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use TrashEntry.ProtoReflect.Descriptor instead.
+func (*TrashEntry) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{25}
 }
 
-func (x *OperationResult) GetSuccess() bool {
+func (x *TrashEntry) GetId() string {
 	if x != nil {
-		return x.Success
+		return x.Id
 	}
-	return false
+	return ""
 }
 
-func (x *OperationResult) GetMessage() string {
+func (x *TrashEntry) GetName() string {
 	if x != nil {
-		return x.Message
+		return x.Name
 	}
 	return ""
 }
 
-// Requisição para download de arquivo
-type DownloadRequest struct {
+func (x *TrashEntry) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TrashEntry) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetModTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ModTime
+	}
+	return nil
+}
+
+func (x *TrashEntry) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+func (x *TrashEntry) GetPurgeAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PurgeAt
+	}
+	return nil
+}
+
+func (x *TrashEntry) GetOverwritten() bool {
+	if x != nil {
+		return x.Overwritten
+	}
+	return false
+}
+
+func (x *TrashEntry) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Requisição para listar a lixeira
+type ListTrashRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DownloadRequest) Reset() {
-	*x = DownloadRequest{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[4]
+func (x *ListTrashRequest) Reset() {
+	*x = ListTrashRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DownloadRequest) String() string {
+func (x *ListTrashRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DownloadRequest) ProtoMessage() {}
+func (*ListTrashRequest) ProtoMessage() {}
 
-func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[4]
+func (x *ListTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -242,41 +1885,80 @@ func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
-func (*DownloadRequest) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use ListTrashRequest.ProtoReflect.Descriptor instead.
+func (*ListTrashRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{26}
 }
 
-func (x *DownloadRequest) GetName() string {
+// Entradas da lixeira, da mais recente para a mais antiga
+type TrashListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*TrashEntry          `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrashListResponse) Reset() {
+	*x = TrashListResponse{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashListResponse) ProtoMessage() {}
+
+func (x *TrashListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[27]
 	if x != nil {
-		return x.Name
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-// Resposta com dados do arquivo
-type DownloadResponse struct {
+// Deprecated: Use TrashListResponse.ProtoReflect.Descriptor instead.
+func (*TrashListResponse) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TrashListResponse) GetEntries() []*TrashEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// Requisição que identifica uma entrada da lixeira
+type TrashRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	All           bool                   `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"` // Em PurgeTrash, sem id: esvazia a lixeira
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DownloadResponse) Reset() {
-	*x = DownloadResponse{}
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[5]
+func (x *TrashRequest) Reset() {
+	*x = TrashRequest{}
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DownloadResponse) String() string {
+func (x *TrashRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DownloadResponse) ProtoMessage() {}
+func (*TrashRequest) ProtoMessage() {}
 
-func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[5]
+func (x *TrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpc_server_proto_fileservice_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -287,41 +1969,219 @@ func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
-func (*DownloadResponse) Descriptor() ([]byte, []int) {
-	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use TrashRequest.ProtoReflect.Descriptor instead.
+func (*TrashRequest) Descriptor() ([]byte, []int) {
+	return file_grpc_server_proto_fileservice_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *DownloadResponse) GetData() []byte {
+func (x *TrashRequest) GetId() string {
 	if x != nil {
-		return x.Data
+		return x.Id
 	}
-	return nil
+	return ""
+}
+
+func (x *TrashRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
 }
 
 var File_grpc_server_proto_fileservice_proto protoreflect.FileDescriptor
 
 const file_grpc_server_proto_fileservice_proto_rawDesc = "" +
 	"\n" +
-	"#grpc-server/proto/fileservice.proto\x12\vfileservice\"\a\n" +
-	"\x05Empty\"(\n" +
+	"#grpc-server/proto/fileservice.proto\x12\vfileservice\x1a\x1fgoogle/protobuf/timestamp.proto\"\a\n" +
+	"\x05Empty\"\x99\x03\n" +
+	"\bFileInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x125\n" +
+	"\bmod_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\amodTime\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x12\x15\n" +
+	"\x06is_dir\x18\x06 \x01(\bR\x05isDir\x12\x12\n" +
+	"\x04etag\x18\a \x01(\tR\x04etag\x12\x1f\n" +
+	"\vstored_size\x18\b \x01(\x03R\n" +
+	"storedSize\x129\n" +
+	"\n" +
+	"expires_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x123\n" +
+	"\x04tags\x18\n" +
+	" \x03(\v2\x1f.fileservice.FileInfo.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8c\x02\n" +
+	"\vListRequest\x12\x10\n" +
+	"\x03dir\x18\x01 \x01(\tR\x03dir\x12\x1c\n" +
+	"\trecursive\x18\x02 \x01(\bR\trecursive\x12\x16\n" +
+	"\x06prefix\x18\x03 \x01(\tR\x06prefix\x12\x18\n" +
+	"\apattern\x18\x04 \x01(\tR\apattern\x12)\n" +
+	"\x04sort\x18\x05 \x01(\x0e2\x15.fileservice.ListSortR\x04sort\x12\x18\n" +
+	"\areverse\x18\x06 \x01(\bR\areverse\x12\x1b\n" +
+	"\tpage_size\x18\a \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\b \x01(\tR\tpageToken\x12\x1a\n" +
+	"\bselector\x18\t \x01(\tR\bselector\"\x86\x01\n" +
 	"\x10FileListResponse\x12\x14\n" +
-	"\x05files\x18\x01 \x03(\tR\x05files\"7\n" +
+	"\x05files\x18\x01 \x03(\tR\x05files\x124\n" +
+	"\n" +
+	"file_infos\x18\x02 \x03(\v2\x15.fileservice.FileInfoR\tfileInfos\x12&\n" +
+	"\x0fnext_page_token\x18\x03 \x01(\tR\rnextPageToken\"\x8a\x02\n" +
 	"\rUploadRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\"E\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x19\n" +
+	"\bif_match\x18\x03 \x01(\tR\aifMatch\x12\"\n" +
+	"\rif_none_match\x18\x04 \x01(\tR\vifNoneMatch\x12\x1f\n" +
+	"\vttl_seconds\x18\x05 \x01(\x03R\n" +
+	"ttlSeconds\x128\n" +
+	"\x04tags\x18\x06 \x03(\v2$.fileservice.UploadRequest.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa4\x02\n" +
+	"\fUploadHeader\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1a\n" +
+	"\bchecksum\x18\x03 \x01(\tR\bchecksum\x12\x19\n" +
+	"\bif_match\x18\x04 \x01(\tR\aifMatch\x12\"\n" +
+	"\rif_none_match\x18\x05 \x01(\tR\vifNoneMatch\x12\x1f\n" +
+	"\vttl_seconds\x18\x06 \x01(\x03R\n" +
+	"ttlSeconds\x127\n" +
+	"\x04tags\x18\a \x03(\v2#.fileservice.UploadHeader.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"c\n" +
+	"\vUploadChunk\x123\n" +
+	"\x06header\x18\x01 \x01(\v2\x19.fileservice.UploadHeaderH\x00R\x06header\x12\x14\n" +
+	"\x04data\x18\x02 \x01(\fH\x00R\x04dataB\t\n" +
+	"\apayload\"\x94\x01\n" +
 	"\x0fOperationResult\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04etag\x18\x03 \x01(\tR\x04etag\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"U\n" +
 	"\x0fDownloadRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\"&\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\"w\n" +
 	"\x10DownloadResponse\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\fR\x04data2\xe2\x01\n" +
-	"\vFileService\x12>\n" +
-	"\tListFiles\x12\x12.fileservice.Empty\x1a\x1d.fileservice.FileListResponse\x12F\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1a\n" +
+	"\bchecksum\x18\x02 \x01(\tR\bchecksum\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x1b\n" +
+	"\tfile_size\x18\x04 \x01(\x03R\bfileSize\"v\n" +
+	"\x0fDownloadTrailer\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x1a\n" +
+	"\bchecksum\x18\x02 \x01(\tR\bchecksum\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x1b\n" +
+	"\tfile_size\x18\x04 \x01(\x03R\bfileSize\"j\n" +
+	"\rDownloadChunk\x12\x14\n" +
+	"\x04data\x18\x01 \x01(\fH\x00R\x04data\x128\n" +
+	"\atrailer\x18\x02 \x01(\v2\x1c.fileservice.DownloadTrailerH\x00R\atrailerB\t\n" +
+	"\apayload\"\x81\x02\n" +
+	"\x1aCreateUploadSessionRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1a\n" +
+	"\bchecksum\x18\x03 \x01(\tR\bchecksum\x12\x1f\n" +
+	"\vttl_seconds\x18\x04 \x01(\x03R\n" +
+	"ttlSeconds\x12E\n" +
+	"\x04tags\x18\x05 \x03(\v21.fileservice.CreateUploadSessionRequest.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"5\n" +
+	"\x14UploadSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"r\n" +
+	"\x11UploadSessionInfo\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\"L\n" +
+	"\x13UploadSessionHeader\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\"q\n" +
+	"\x12UploadSessionChunk\x12:\n" +
+	"\x06header\x18\x01 \x01(\v2 .fileservice.UploadSessionHeaderH\x00R\x06header\x12\x14\n" +
+	"\x04data\x18\x02 \x01(\fH\x00R\x04dataB\t\n" +
+	"\apayload\"#\n" +
+	"\rDeleteRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"E\n" +
+	"\rRenameRequest\x12\x19\n" +
+	"\bold_name\x18\x01 \x01(\tR\aoldName\x12\x19\n" +
+	"\bnew_name\x18\x02 \x01(\tR\anewName\"!\n" +
+	"\vStatRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\",\n" +
+	"\x16CreateDirectoryRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xb4\x01\n" +
+	"\vFileVersion\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x01 \x01(\tR\tversionId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x125\n" +
+	"\bmod_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\amodTime\x12;\n" +
+	"\vreplaced_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"replacedAt\")\n" +
+	"\x13ListVersionsRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"K\n" +
+	"\x13VersionListResponse\x124\n" +
+	"\bversions\x18\x01 \x03(\v2\x18.fileservice.FileVersionR\bversions\"C\n" +
+	"\x0eVersionRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"version_id\x18\x02 \x01(\tR\tversionId\"\xba\x03\n" +
 	"\n" +
-	"UploadFile\x12\x1a.fileservice.UploadRequest\x1a\x1c.fileservice.OperationResult\x12K\n" +
-	"\fDownloadFile\x12\x1c.fileservice.DownloadRequest\x1a\x1d.fileservice.DownloadResponseB+Z)grpc-rabbitmq-fileshare/grpc-server/protob\x06proto3"
+	"TrashEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x125\n" +
+	"\bmod_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\amodTime\x129\n" +
+	"\n" +
+	"deleted_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x125\n" +
+	"\bpurge_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\apurgeAt\x12 \n" +
+	"\voverwritten\x18\t \x01(\bR\voverwritten\x125\n" +
+	"\x04tags\x18\n" +
+	" \x03(\v2!.fileservice.TrashEntry.TagsEntryR\x04tags\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x12\n" +
+	"\x10ListTrashRequest\"F\n" +
+	"\x11TrashListResponse\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.fileservice.TrashEntryR\aentries\"0\n" +
+	"\fTrashRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03all\x18\x02 \x01(\bR\x03all*J\n" +
+	"\bListSort\x12\x12\n" +
+	"\x0eLIST_SORT_NAME\x10\x00\x12\x12\n" +
+	"\x0eLIST_SORT_SIZE\x10\x01\x12\x16\n" +
+	"\x12LIST_SORT_MOD_TIME\x10\x022\x8c\r\n" +
+	"\vFileService\x12D\n" +
+	"\tListFiles\x12\x18.fileservice.ListRequest\x1a\x1d.fileservice.FileListResponse\x12L\n" +
+	"\x0fListFilesStream\x12\x18.fileservice.ListRequest\x1a\x1d.fileservice.FileListResponse0\x01\x12F\n" +
+	"\n" +
+	"UploadFile\x12\x1a.fileservice.UploadRequest\x1a\x1c.fileservice.OperationResult\x12L\n" +
+	"\x10UploadFileStream\x12\x18.fileservice.UploadChunk\x1a\x1c.fileservice.OperationResult(\x01\x12K\n" +
+	"\fDownloadFile\x12\x1c.fileservice.DownloadRequest\x1a\x1d.fileservice.DownloadResponse\x12P\n" +
+	"\x12DownloadFileStream\x12\x1c.fileservice.DownloadRequest\x1a\x1a.fileservice.DownloadChunk0\x01\x12F\n" +
+	"\n" +
+	"DeleteFile\x12\x1a.fileservice.DeleteRequest\x1a\x1c.fileservice.OperationResult\x12F\n" +
+	"\n" +
+	"RenameFile\x12\x1a.fileservice.RenameRequest\x1a\x1c.fileservice.OperationResult\x12;\n" +
+	"\bStatFile\x12\x18.fileservice.StatRequest\x1a\x15.fileservice.FileInfo\x12T\n" +
+	"\x0fCreateDirectory\x12#.fileservice.CreateDirectoryRequest\x1a\x1c.fileservice.OperationResult\x12R\n" +
+	"\fListVersions\x12 .fileservice.ListVersionsRequest\x1a .fileservice.VersionListResponse\x12R\n" +
+	"\x15DownloadVersionStream\x12\x1b.fileservice.VersionRequest\x1a\x1a.fileservice.DownloadChunk0\x01\x12K\n" +
+	"\x0eRestoreVersion\x12\x1b.fileservice.VersionRequest\x1a\x1c.fileservice.OperationResult\x12J\n" +
+	"\tListTrash\x12\x1d.fileservice.ListTrashRequest\x1a\x1e.fileservice.TrashListResponse\x12G\n" +
+	"\fRestoreTrash\x12\x19.fileservice.TrashRequest\x1a\x1c.fileservice.OperationResult\x12G\n" +
+	"\n" +
+	"PurgeTrash\x12\x19.fileservice.TrashRequest\x1a\x1e.fileservice.TrashListResponse\x12^\n" +
+	"\x13CreateUploadSession\x12'.fileservice.CreateUploadSessionRequest\x1a\x1e.fileservice.UploadSessionInfo\x12U\n" +
+	"\x10GetUploadSession\x12!.fileservice.UploadSessionRequest\x1a\x1e.fileservice.UploadSessionInfo\x12X\n" +
+	"\x13AppendUploadSession\x12\x1f.fileservice.UploadSessionChunk\x1a\x1e.fileservice.UploadSessionInfo(\x01\x12V\n" +
+	"\x13CommitUploadSession\x12!.fileservice.UploadSessionRequest\x1a\x1c.fileservice.OperationResult\x12U\n" +
+	"\x12AbortUploadSession\x12!.fileservice.UploadSessionRequest\x1a\x1c.fileservice.OperationResultB+Z)grpc-rabbitmq-fileshare/grpc-server/protob\x06proto3"
 
 var (
 	file_grpc_server_proto_fileservice_proto_rawDescOnce sync.Once
@@ -335,27 +2195,114 @@ func file_grpc_server_proto_fileservice_proto_rawDescGZIP() []byte {
 	return file_grpc_server_proto_fileservice_proto_rawDescData
 }
 
-var file_grpc_server_proto_fileservice_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_grpc_server_proto_fileservice_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_grpc_server_proto_fileservice_proto_msgTypes = make([]protoimpl.MessageInfo, 34)
 var file_grpc_server_proto_fileservice_proto_goTypes = []any{
-	(*Empty)(nil),            // 0: fileservice.Empty
-	(*FileListResponse)(nil), // 1: fileservice.FileListResponse
-	(*UploadRequest)(nil),    // 2: fileservice.UploadRequest
-	(*OperationResult)(nil),  // 3: fileservice.OperationResult
-	(*DownloadRequest)(nil),  // 4: fileservice.DownloadRequest
-	(*DownloadResponse)(nil), // 5: fileservice.DownloadResponse
+	(ListSort)(0),                      // 0: fileservice.ListSort
+	(*Empty)(nil),                      // 1: fileservice.Empty
+	(*FileInfo)(nil),                   // 2: fileservice.FileInfo
+	(*ListRequest)(nil),                // 3: fileservice.ListRequest
+	(*FileListResponse)(nil),           // 4: fileservice.FileListResponse
+	(*UploadRequest)(nil),              // 5: fileservice.UploadRequest
+	(*UploadHeader)(nil),               // 6: fileservice.UploadHeader
+	(*UploadChunk)(nil),                // 7: fileservice.UploadChunk
+	(*OperationResult)(nil),            // 8: fileservice.OperationResult
+	(*DownloadRequest)(nil),            // 9: fileservice.DownloadRequest
+	(*DownloadResponse)(nil),           // 10: fileservice.DownloadResponse
+	(*DownloadTrailer)(nil),            // 11: fileservice.DownloadTrailer
+	(*DownloadChunk)(nil),              // 12: fileservice.DownloadChunk
+	(*CreateUploadSessionRequest)(nil), // 13: fileservice.CreateUploadSessionRequest
+	(*UploadSessionRequest)(nil),       // 14: fileservice.UploadSessionRequest
+	(*UploadSessionInfo)(nil),          // 15: fileservice.UploadSessionInfo
+	(*UploadSessionHeader)(nil),        // 16: fileservice.UploadSessionHeader
+	(*UploadSessionChunk)(nil),         // 17: fileservice.UploadSessionChunk
+	(*DeleteRequest)(nil),              // 18: fileservice.DeleteRequest
+	(*RenameRequest)(nil),              // 19: fileservice.RenameRequest
+	(*StatRequest)(nil),                // 20: fileservice.StatRequest
+	(*CreateDirectoryRequest)(nil),     // 21: fileservice.CreateDirectoryRequest
+	(*FileVersion)(nil),                // 22: fileservice.FileVersion
+	(*ListVersionsRequest)(nil),        // 23: fileservice.ListVersionsRequest
+	(*VersionListResponse)(nil),        // 24: fileservice.VersionListResponse
+	(*VersionRequest)(nil),             // 25: fileservice.VersionRequest
+	(*TrashEntry)(nil),                 // 26: fileservice.TrashEntry
+	(*ListTrashRequest)(nil),           // 27: fileservice.ListTrashRequest
+	(*TrashListResponse)(nil),          // 28: fileservice.TrashListResponse
+	(*TrashRequest)(nil),               // 29: fileservice.TrashRequest
+	nil,                                // 30: fileservice.FileInfo.TagsEntry
+	nil,                                // 31: fileservice.UploadRequest.TagsEntry
+	nil,                                // 32: fileservice.UploadHeader.TagsEntry
+	nil,                                // 33: fileservice.CreateUploadSessionRequest.TagsEntry
+	nil,                                // 34: fileservice.TrashEntry.TagsEntry
+	(*timestamppb.Timestamp)(nil),      // 35: google.protobuf.Timestamp
 }
 var file_grpc_server_proto_fileservice_proto_depIdxs = []int32{
-	0, // 0: fileservice.FileService.ListFiles:input_type -> fileservice.Empty
-	2, // 1: fileservice.FileService.UploadFile:input_type -> fileservice.UploadRequest
-	4, // 2: fileservice.FileService.DownloadFile:input_type -> fileservice.DownloadRequest
-	1, // 3: fileservice.FileService.ListFiles:output_type -> fileservice.FileListResponse
-	3, // 4: fileservice.FileService.UploadFile:output_type -> fileservice.OperationResult
-	5, // 5: fileservice.FileService.DownloadFile:output_type -> fileservice.DownloadResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	35, // 0: fileservice.FileInfo.mod_time:type_name -> google.protobuf.Timestamp
+	35, // 1: fileservice.FileInfo.expires_at:type_name -> google.protobuf.Timestamp
+	30, // 2: fileservice.FileInfo.tags:type_name -> fileservice.FileInfo.TagsEntry
+	0,  // 3: fileservice.ListRequest.sort:type_name -> fileservice.ListSort
+	2,  // 4: fileservice.FileListResponse.file_infos:type_name -> fileservice.FileInfo
+	31, // 5: fileservice.UploadRequest.tags:type_name -> fileservice.UploadRequest.TagsEntry
+	32, // 6: fileservice.UploadHeader.tags:type_name -> fileservice.UploadHeader.TagsEntry
+	6,  // 7: fileservice.UploadChunk.header:type_name -> fileservice.UploadHeader
+	35, // 8: fileservice.OperationResult.expires_at:type_name -> google.protobuf.Timestamp
+	11, // 9: fileservice.DownloadChunk.trailer:type_name -> fileservice.DownloadTrailer
+	33, // 10: fileservice.CreateUploadSessionRequest.tags:type_name -> fileservice.CreateUploadSessionRequest.TagsEntry
+	16, // 11: fileservice.UploadSessionChunk.header:type_name -> fileservice.UploadSessionHeader
+	35, // 12: fileservice.FileVersion.mod_time:type_name -> google.protobuf.Timestamp
+	35, // 13: fileservice.FileVersion.replaced_at:type_name -> google.protobuf.Timestamp
+	22, // 14: fileservice.VersionListResponse.versions:type_name -> fileservice.FileVersion
+	35, // 15: fileservice.TrashEntry.mod_time:type_name -> google.protobuf.Timestamp
+	35, // 16: fileservice.TrashEntry.deleted_at:type_name -> google.protobuf.Timestamp
+	35, // 17: fileservice.TrashEntry.purge_at:type_name -> google.protobuf.Timestamp
+	34, // 18: fileservice.TrashEntry.tags:type_name -> fileservice.TrashEntry.TagsEntry
+	26, // 19: fileservice.TrashListResponse.entries:type_name -> fileservice.TrashEntry
+	3,  // 20: fileservice.FileService.ListFiles:input_type -> fileservice.ListRequest
+	3,  // 21: fileservice.FileService.ListFilesStream:input_type -> fileservice.ListRequest
+	5,  // 22: fileservice.FileService.UploadFile:input_type -> fileservice.UploadRequest
+	7,  // 23: fileservice.FileService.UploadFileStream:input_type -> fileservice.UploadChunk
+	9,  // 24: fileservice.FileService.DownloadFile:input_type -> fileservice.DownloadRequest
+	9,  // 25: fileservice.FileService.DownloadFileStream:input_type -> fileservice.DownloadRequest
+	18, // 26: fileservice.FileService.DeleteFile:input_type -> fileservice.DeleteRequest
+	19, // 27: fileservice.FileService.RenameFile:input_type -> fileservice.RenameRequest
+	20, // 28: fileservice.FileService.StatFile:input_type -> fileservice.StatRequest
+	21, // 29: fileservice.FileService.CreateDirectory:input_type -> fileservice.CreateDirectoryRequest
+	23, // 30: fileservice.FileService.ListVersions:input_type -> fileservice.ListVersionsRequest
+	25, // 31: fileservice.FileService.DownloadVersionStream:input_type -> fileservice.VersionRequest
+	25, // 32: fileservice.FileService.RestoreVersion:input_type -> fileservice.VersionRequest
+	27, // 33: fileservice.FileService.ListTrash:input_type -> fileservice.ListTrashRequest
+	29, // 34: fileservice.FileService.RestoreTrash:input_type -> fileservice.TrashRequest
+	29, // 35: fileservice.FileService.PurgeTrash:input_type -> fileservice.TrashRequest
+	13, // 36: fileservice.FileService.CreateUploadSession:input_type -> fileservice.CreateUploadSessionRequest
+	14, // 37: fileservice.FileService.GetUploadSession:input_type -> fileservice.UploadSessionRequest
+	17, // 38: fileservice.FileService.AppendUploadSession:input_type -> fileservice.UploadSessionChunk
+	14, // 39: fileservice.FileService.CommitUploadSession:input_type -> fileservice.UploadSessionRequest
+	14, // 40: fileservice.FileService.AbortUploadSession:input_type -> fileservice.UploadSessionRequest
+	4,  // 41: fileservice.FileService.ListFiles:output_type -> fileservice.FileListResponse
+	4,  // 42: fileservice.FileService.ListFilesStream:output_type -> fileservice.FileListResponse
+	8,  // 43: fileservice.FileService.UploadFile:output_type -> fileservice.OperationResult
+	8,  // 44: fileservice.FileService.UploadFileStream:output_type -> fileservice.OperationResult
+	10, // 45: fileservice.FileService.DownloadFile:output_type -> fileservice.DownloadResponse
+	12, // 46: fileservice.FileService.DownloadFileStream:output_type -> fileservice.DownloadChunk
+	8,  // 47: fileservice.FileService.DeleteFile:output_type -> fileservice.OperationResult
+	8,  // 48: fileservice.FileService.RenameFile:output_type -> fileservice.OperationResult
+	2,  // 49: fileservice.FileService.StatFile:output_type -> fileservice.FileInfo
+	8,  // 50: fileservice.FileService.CreateDirectory:output_type -> fileservice.OperationResult
+	24, // 51: fileservice.FileService.ListVersions:output_type -> fileservice.VersionListResponse
+	12, // 52: fileservice.FileService.DownloadVersionStream:output_type -> fileservice.DownloadChunk
+	8,  // 53: fileservice.FileService.RestoreVersion:output_type -> fileservice.OperationResult
+	28, // 54: fileservice.FileService.ListTrash:output_type -> fileservice.TrashListResponse
+	8,  // 55: fileservice.FileService.RestoreTrash:output_type -> fileservice.OperationResult
+	28, // 56: fileservice.FileService.PurgeTrash:output_type -> fileservice.TrashListResponse
+	15, // 57: fileservice.FileService.CreateUploadSession:output_type -> fileservice.UploadSessionInfo
+	15, // 58: fileservice.FileService.GetUploadSession:output_type -> fileservice.UploadSessionInfo
+	15, // 59: fileservice.FileService.AppendUploadSession:output_type -> fileservice.UploadSessionInfo
+	8,  // 60: fileservice.FileService.CommitUploadSession:output_type -> fileservice.OperationResult
+	8,  // 61: fileservice.FileService.AbortUploadSession:output_type -> fileservice.OperationResult
+	41, // [41:62] is the sub-list for method output_type
+	20, // [20:41] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_grpc_server_proto_fileservice_proto_init() }
@@ -363,18 +2310,31 @@ func file_grpc_server_proto_fileservice_proto_init() {
 	if File_grpc_server_proto_fileservice_proto != nil {
 		return
 	}
+	file_grpc_server_proto_fileservice_proto_msgTypes[6].OneofWrappers = []any{
+		(*UploadChunk_Header)(nil),
+		(*UploadChunk_Data)(nil),
+	}
+	file_grpc_server_proto_fileservice_proto_msgTypes[11].OneofWrappers = []any{
+		(*DownloadChunk_Data)(nil),
+		(*DownloadChunk_Trailer)(nil),
+	}
+	file_grpc_server_proto_fileservice_proto_msgTypes[16].OneofWrappers = []any{
+		(*UploadSessionChunk_Header)(nil),
+		(*UploadSessionChunk_Data)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_grpc_server_proto_fileservice_proto_rawDesc), len(file_grpc_server_proto_fileservice_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      1,
+			NumMessages:   34,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_grpc_server_proto_fileservice_proto_goTypes,
 		DependencyIndexes: file_grpc_server_proto_fileservice_proto_depIdxs,
+		EnumInfos:         file_grpc_server_proto_fileservice_proto_enumTypes,
 		MessageInfos:      file_grpc_server_proto_fileservice_proto_msgTypes,
 	}.Build()
 	File_grpc_server_proto_fileservice_proto = out.File
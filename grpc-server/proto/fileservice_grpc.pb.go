@@ -19,9 +19,27 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	FileService_ListFiles_FullMethodName    = "/fileservice.FileService/ListFiles"
-	FileService_UploadFile_FullMethodName   = "/fileservice.FileService/UploadFile"
-	FileService_DownloadFile_FullMethodName = "/fileservice.FileService/DownloadFile"
+	FileService_ListFiles_FullMethodName             = "/fileservice.FileService/ListFiles"
+	FileService_ListFilesStream_FullMethodName       = "/fileservice.FileService/ListFilesStream"
+	FileService_UploadFile_FullMethodName            = "/fileservice.FileService/UploadFile"
+	FileService_UploadFileStream_FullMethodName      = "/fileservice.FileService/UploadFileStream"
+	FileService_DownloadFile_FullMethodName          = "/fileservice.FileService/DownloadFile"
+	FileService_DownloadFileStream_FullMethodName    = "/fileservice.FileService/DownloadFileStream"
+	FileService_DeleteFile_FullMethodName            = "/fileservice.FileService/DeleteFile"
+	FileService_RenameFile_FullMethodName            = "/fileservice.FileService/RenameFile"
+	FileService_StatFile_FullMethodName              = "/fileservice.FileService/StatFile"
+	FileService_CreateDirectory_FullMethodName       = "/fileservice.FileService/CreateDirectory"
+	FileService_ListVersions_FullMethodName          = "/fileservice.FileService/ListVersions"
+	FileService_DownloadVersionStream_FullMethodName = "/fileservice.FileService/DownloadVersionStream"
+	FileService_RestoreVersion_FullMethodName        = "/fileservice.FileService/RestoreVersion"
+	FileService_ListTrash_FullMethodName             = "/fileservice.FileService/ListTrash"
+	FileService_RestoreTrash_FullMethodName          = "/fileservice.FileService/RestoreTrash"
+	FileService_PurgeTrash_FullMethodName            = "/fileservice.FileService/PurgeTrash"
+	FileService_CreateUploadSession_FullMethodName   = "/fileservice.FileService/CreateUploadSession"
+	FileService_GetUploadSession_FullMethodName      = "/fileservice.FileService/GetUploadSession"
+	FileService_AppendUploadSession_FullMethodName   = "/fileservice.FileService/AppendUploadSession"
+	FileService_CommitUploadSession_FullMethodName   = "/fileservice.FileService/CommitUploadSession"
+	FileService_AbortUploadSession_FullMethodName    = "/fileservice.FileService/AbortUploadSession"
 )
 
 // FileServiceClient is the client API for FileService service.
@@ -30,12 +48,52 @@ const (
 //
 // Serviço de arquivos
 type FileServiceClient interface {
-	// Lista todos os arquivos disponíveis
-	ListFiles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FileListResponse, error)
+	// Lista os arquivos e diretórios de um diretório
+	// ListRequest substitui Empty mantendo a compatibilidade de wire com clientes antigos
+	ListFiles(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*FileListResponse, error)
+	// Lista um diretório em páginas de page_size entradas, uma por mensagem,
+	// para diretórios grandes demais para uma única resposta
+	ListFilesStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileListResponse], error)
 	// Faz upload de um arquivo
 	UploadFile(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Faz upload de um arquivo em blocos, sem limite de tamanho de mensagem
+	UploadFileStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadChunk, OperationResult], error)
 	// Faz download de um arquivo
 	DownloadFile(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (*DownloadResponse, error)
+	// Faz download de um arquivo em blocos, terminando com um trailer de verificação
+	DownloadFileStream(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadChunk], error)
+	// Remove um arquivo ou um diretório vazio
+	DeleteFile(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Renomeia ou move um arquivo ou diretório; falha com ALREADY_EXISTS se o destino existir
+	RenameFile(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Retorna as informações de um arquivo ou diretório
+	StatFile(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*FileInfo, error)
+	// Cria um diretório e os diretórios intermediários que ainda não existirem
+	CreateDirectory(ctx context.Context, in *CreateDirectoryRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Lista as versões anteriores de um arquivo; UNIMPLEMENTED se o
+	// armazenamento não mantiver versões
+	ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*VersionListResponse, error)
+	// Faz download de uma versão anterior em blocos, terminando com um trailer de verificação
+	DownloadVersionStream(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadChunk], error)
+	// Torna uma versão anterior a versão atual do arquivo
+	RestoreVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Lista os arquivos removidos e substituídos que estão na lixeira;
+	// UNIMPLEMENTED se o armazenamento não mantiver lixeira
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*TrashListResponse, error)
+	// Grava uma entrada da lixeira de volta no seu nome, substituindo o arquivo atual
+	RestoreTrash(ctx context.Context, in *TrashRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Remove definitivamente uma entrada da lixeira, ou todas com all, e retorna as removidas
+	PurgeTrash(ctx context.Context, in *TrashRequest, opts ...grpc.CallOption) (*TrashListResponse, error)
+	// Inicia uma sessão de upload retomável
+	CreateUploadSession(ctx context.Context, in *CreateUploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionInfo, error)
+	// Consulta o offset confirmado de uma sessão de upload
+	GetUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionInfo, error)
+	// Envia dados para uma sessão a partir de um offset
+	AppendUploadSession(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadSessionChunk, UploadSessionInfo], error)
+	// Conclui a sessão, movendo o arquivo recebido para o armazenamento
+	CommitUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*OperationResult, error)
+	// Cancela a sessão e descarta os dados recebidos
+	AbortUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*OperationResult, error)
 }
 
 type fileServiceClient struct {
@@ -46,7 +104,7 @@ func NewFileServiceClient(cc grpc.ClientConnInterface) FileServiceClient {
 	return &fileServiceClient{cc}
 }
 
-func (c *fileServiceClient) ListFiles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FileListResponse, error) {
+func (c *fileServiceClient) ListFiles(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*FileListResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(FileListResponse)
 	err := c.cc.Invoke(ctx, FileService_ListFiles_FullMethodName, in, out, cOpts...)
@@ -56,6 +114,25 @@ func (c *fileServiceClient) ListFiles(ctx context.Context, in *Empty, opts ...gr
 	return out, nil
 }
 
+func (c *fileServiceClient) ListFilesStream(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileListResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[0], FileService_ListFilesStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRequest, FileListResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_ListFilesStreamClient = grpc.ServerStreamingClient[FileListResponse]
+
 func (c *fileServiceClient) UploadFile(ctx context.Context, in *UploadRequest, opts ...grpc.CallOption) (*OperationResult, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(OperationResult)
@@ -66,6 +143,19 @@ func (c *fileServiceClient) UploadFile(ctx context.Context, in *UploadRequest, o
 	return out, nil
 }
 
+func (c *fileServiceClient) UploadFileStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadChunk, OperationResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[1], FileService_UploadFileStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadChunk, OperationResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_UploadFileStreamClient = grpc.ClientStreamingClient[UploadChunk, OperationResult]
+
 func (c *fileServiceClient) DownloadFile(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (*DownloadResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(DownloadResponse)
@@ -76,18 +166,239 @@ func (c *fileServiceClient) DownloadFile(ctx context.Context, in *DownloadReques
 	return out, nil
 }
 
+func (c *fileServiceClient) DownloadFileStream(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[2], FileService_DownloadFileStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadRequest, DownloadChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_DownloadFileStreamClient = grpc.ServerStreamingClient[DownloadChunk]
+
+func (c *fileServiceClient) DeleteFile(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_DeleteFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) RenameFile(ctx context.Context, in *RenameRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_RenameFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) StatFile(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*FileInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileInfo)
+	err := c.cc.Invoke(ctx, FileService_StatFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) CreateDirectory(ctx context.Context, in *CreateDirectoryRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_CreateDirectory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*VersionListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionListResponse)
+	err := c.cc.Invoke(ctx, FileService_ListVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) DownloadVersionStream(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DownloadChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[3], FileService_DownloadVersionStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[VersionRequest, DownloadChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_DownloadVersionStreamClient = grpc.ServerStreamingClient[DownloadChunk]
+
+func (c *fileServiceClient) RestoreVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_RestoreVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*TrashListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrashListResponse)
+	err := c.cc.Invoke(ctx, FileService_ListTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) RestoreTrash(ctx context.Context, in *TrashRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_RestoreTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) PurgeTrash(ctx context.Context, in *TrashRequest, opts ...grpc.CallOption) (*TrashListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrashListResponse)
+	err := c.cc.Invoke(ctx, FileService_PurgeTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) CreateUploadSession(ctx context.Context, in *CreateUploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadSessionInfo)
+	err := c.cc.Invoke(ctx, FileService_CreateUploadSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) GetUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UploadSessionInfo)
+	err := c.cc.Invoke(ctx, FileService_GetUploadSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) AppendUploadSession(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadSessionChunk, UploadSessionInfo], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FileService_ServiceDesc.Streams[4], FileService_AppendUploadSession_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadSessionChunk, UploadSessionInfo]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_AppendUploadSessionClient = grpc.ClientStreamingClient[UploadSessionChunk, UploadSessionInfo]
+
+func (c *fileServiceClient) CommitUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_CommitUploadSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileServiceClient) AbortUploadSession(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*OperationResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OperationResult)
+	err := c.cc.Invoke(ctx, FileService_AbortUploadSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FileServiceServer is the server API for FileService service.
 // All implementations must embed UnimplementedFileServiceServer
 // for forward compatibility.
 //
 // Serviço de arquivos
 type FileServiceServer interface {
-	// Lista todos os arquivos disponíveis
-	ListFiles(context.Context, *Empty) (*FileListResponse, error)
+	// Lista os arquivos e diretórios de um diretório
+	// ListRequest substitui Empty mantendo a compatibilidade de wire com clientes antigos
+	ListFiles(context.Context, *ListRequest) (*FileListResponse, error)
+	// Lista um diretório em páginas de page_size entradas, uma por mensagem,
+	// para diretórios grandes demais para uma única resposta
+	ListFilesStream(*ListRequest, grpc.ServerStreamingServer[FileListResponse]) error
 	// Faz upload de um arquivo
 	UploadFile(context.Context, *UploadRequest) (*OperationResult, error)
+	// Faz upload de um arquivo em blocos, sem limite de tamanho de mensagem
+	UploadFileStream(grpc.ClientStreamingServer[UploadChunk, OperationResult]) error
 	// Faz download de um arquivo
 	DownloadFile(context.Context, *DownloadRequest) (*DownloadResponse, error)
+	// Faz download de um arquivo em blocos, terminando com um trailer de verificação
+	DownloadFileStream(*DownloadRequest, grpc.ServerStreamingServer[DownloadChunk]) error
+	// Remove um arquivo ou um diretório vazio
+	DeleteFile(context.Context, *DeleteRequest) (*OperationResult, error)
+	// Renomeia ou move um arquivo ou diretório; falha com ALREADY_EXISTS se o destino existir
+	RenameFile(context.Context, *RenameRequest) (*OperationResult, error)
+	// Retorna as informações de um arquivo ou diretório
+	StatFile(context.Context, *StatRequest) (*FileInfo, error)
+	// Cria um diretório e os diretórios intermediários que ainda não existirem
+	CreateDirectory(context.Context, *CreateDirectoryRequest) (*OperationResult, error)
+	// Lista as versões anteriores de um arquivo; UNIMPLEMENTED se o
+	// armazenamento não mantiver versões
+	ListVersions(context.Context, *ListVersionsRequest) (*VersionListResponse, error)
+	// Faz download de uma versão anterior em blocos, terminando com um trailer de verificação
+	DownloadVersionStream(*VersionRequest, grpc.ServerStreamingServer[DownloadChunk]) error
+	// Torna uma versão anterior a versão atual do arquivo
+	RestoreVersion(context.Context, *VersionRequest) (*OperationResult, error)
+	// Lista os arquivos removidos e substituídos que estão na lixeira;
+	// UNIMPLEMENTED se o armazenamento não mantiver lixeira
+	ListTrash(context.Context, *ListTrashRequest) (*TrashListResponse, error)
+	// Grava uma entrada da lixeira de volta no seu nome, substituindo o arquivo atual
+	RestoreTrash(context.Context, *TrashRequest) (*OperationResult, error)
+	// Remove definitivamente uma entrada da lixeira, ou todas com all, e retorna as removidas
+	PurgeTrash(context.Context, *TrashRequest) (*TrashListResponse, error)
+	// Inicia uma sessão de upload retomável
+	CreateUploadSession(context.Context, *CreateUploadSessionRequest) (*UploadSessionInfo, error)
+	// Consulta o offset confirmado de uma sessão de upload
+	GetUploadSession(context.Context, *UploadSessionRequest) (*UploadSessionInfo, error)
+	// Envia dados para uma sessão a partir de um offset
+	AppendUploadSession(grpc.ClientStreamingServer[UploadSessionChunk, UploadSessionInfo]) error
+	// Conclui a sessão, movendo o arquivo recebido para o armazenamento
+	CommitUploadSession(context.Context, *UploadSessionRequest) (*OperationResult, error)
+	// Cancela a sessão e descarta os dados recebidos
+	AbortUploadSession(context.Context, *UploadSessionRequest) (*OperationResult, error)
 	mustEmbedUnimplementedFileServiceServer()
 }
 
@@ -98,15 +409,69 @@ type FileServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedFileServiceServer struct{}
 
-func (UnimplementedFileServiceServer) ListFiles(context.Context, *Empty) (*FileListResponse, error) {
+func (UnimplementedFileServiceServer) ListFiles(context.Context, *ListRequest) (*FileListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
 }
+func (UnimplementedFileServiceServer) ListFilesStream(*ListRequest, grpc.ServerStreamingServer[FileListResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ListFilesStream not implemented")
+}
 func (UnimplementedFileServiceServer) UploadFile(context.Context, *UploadRequest) (*OperationResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
 }
+func (UnimplementedFileServiceServer) UploadFileStream(grpc.ClientStreamingServer[UploadChunk, OperationResult]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFileStream not implemented")
+}
 func (UnimplementedFileServiceServer) DownloadFile(context.Context, *DownloadRequest) (*DownloadResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
 }
+func (UnimplementedFileServiceServer) DownloadFileStream(*DownloadRequest, grpc.ServerStreamingServer[DownloadChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFileStream not implemented")
+}
+func (UnimplementedFileServiceServer) DeleteFile(context.Context, *DeleteRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteFile not implemented")
+}
+func (UnimplementedFileServiceServer) RenameFile(context.Context, *RenameRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameFile not implemented")
+}
+func (UnimplementedFileServiceServer) StatFile(context.Context, *StatRequest) (*FileInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFile not implemented")
+}
+func (UnimplementedFileServiceServer) CreateDirectory(context.Context, *CreateDirectoryRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDirectory not implemented")
+}
+func (UnimplementedFileServiceServer) ListVersions(context.Context, *ListVersionsRequest) (*VersionListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVersions not implemented")
+}
+func (UnimplementedFileServiceServer) DownloadVersionStream(*VersionRequest, grpc.ServerStreamingServer[DownloadChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadVersionStream not implemented")
+}
+func (UnimplementedFileServiceServer) RestoreVersion(context.Context, *VersionRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreVersion not implemented")
+}
+func (UnimplementedFileServiceServer) ListTrash(context.Context, *ListTrashRequest) (*TrashListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedFileServiceServer) RestoreTrash(context.Context, *TrashRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreTrash not implemented")
+}
+func (UnimplementedFileServiceServer) PurgeTrash(context.Context, *TrashRequest) (*TrashListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeTrash not implemented")
+}
+func (UnimplementedFileServiceServer) CreateUploadSession(context.Context, *CreateUploadSessionRequest) (*UploadSessionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUploadSession not implemented")
+}
+func (UnimplementedFileServiceServer) GetUploadSession(context.Context, *UploadSessionRequest) (*UploadSessionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUploadSession not implemented")
+}
+func (UnimplementedFileServiceServer) AppendUploadSession(grpc.ClientStreamingServer[UploadSessionChunk, UploadSessionInfo]) error {
+	return status.Errorf(codes.Unimplemented, "method AppendUploadSession not implemented")
+}
+func (UnimplementedFileServiceServer) CommitUploadSession(context.Context, *UploadSessionRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitUploadSession not implemented")
+}
+func (UnimplementedFileServiceServer) AbortUploadSession(context.Context, *UploadSessionRequest) (*OperationResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbortUploadSession not implemented")
+}
 func (UnimplementedFileServiceServer) mustEmbedUnimplementedFileServiceServer() {}
 func (UnimplementedFileServiceServer) testEmbeddedByValue()                     {}
 
@@ -129,7 +494,7 @@ func RegisterFileServiceServer(s grpc.ServiceRegistrar, srv FileServiceServer) {
 }
 
 func _FileService_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(ListRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -141,11 +506,22 @@ func _FileService_ListFiles_Handler(srv interface{}, ctx context.Context, dec fu
 		FullMethod: FileService_ListFiles_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(FileServiceServer).ListFiles(ctx, req.(*Empty))
+		return srv.(FileServiceServer).ListFiles(ctx, req.(*ListRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileService_ListFilesStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).ListFilesStream(m, &grpc.GenericServerStream[ListRequest, FileListResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_ListFilesStreamServer = grpc.ServerStreamingServer[FileListResponse]
+
 func _FileService_UploadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UploadRequest)
 	if err := dec(in); err != nil {
@@ -164,6 +540,13 @@ func _FileService_UploadFile_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileService_UploadFileStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileServiceServer).UploadFileStream(&grpc.GenericServerStream[UploadChunk, OperationResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_UploadFileStreamServer = grpc.ClientStreamingServer[UploadChunk, OperationResult]
+
 func _FileService_DownloadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DownloadRequest)
 	if err := dec(in); err != nil {
@@ -182,6 +565,269 @@ func _FileService_DownloadFile_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FileService_DownloadFileStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).DownloadFileStream(m, &grpc.GenericServerStream[DownloadRequest, DownloadChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_DownloadFileStreamServer = grpc.ServerStreamingServer[DownloadChunk]
+
+func _FileService_DeleteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).DeleteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_DeleteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).DeleteFile(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).RenameFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_RenameFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).RenameFile(ctx, req.(*RenameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_StatFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).StatFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_StatFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).StatFile(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_CreateDirectory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDirectoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).CreateDirectory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_CreateDirectory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).CreateDirectory(ctx, req.(*CreateDirectoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_ListVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).ListVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_ListVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).ListVersions(ctx, req.(*ListVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_DownloadVersionStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VersionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileServiceServer).DownloadVersionStream(m, &grpc.GenericServerStream[VersionRequest, DownloadChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_DownloadVersionStreamServer = grpc.ServerStreamingServer[DownloadChunk]
+
+func _FileService_RestoreVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).RestoreVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_RestoreVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).RestoreVersion(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_RestoreTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).RestoreTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_RestoreTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).RestoreTrash(ctx, req.(*TrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_PurgeTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).PurgeTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_PurgeTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).PurgeTrash(ctx, req.(*TrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_CreateUploadSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).CreateUploadSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_CreateUploadSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).CreateUploadSession(ctx, req.(*CreateUploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_GetUploadSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).GetUploadSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_GetUploadSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).GetUploadSession(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_AppendUploadSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileServiceServer).AppendUploadSession(&grpc.GenericServerStream[UploadSessionChunk, UploadSessionInfo]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FileService_AppendUploadSessionServer = grpc.ClientStreamingServer[UploadSessionChunk, UploadSessionInfo]
+
+func _FileService_CommitUploadSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).CommitUploadSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_CommitUploadSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).CommitUploadSession(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileService_AbortUploadSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).AbortUploadSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileService_AbortUploadSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).AbortUploadSession(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FileService_ServiceDesc is the grpc.ServiceDesc for FileService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -201,7 +847,85 @@ var FileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DownloadFile",
 			Handler:    _FileService_DownloadFile_Handler,
 		},
+		{
+			MethodName: "DeleteFile",
+			Handler:    _FileService_DeleteFile_Handler,
+		},
+		{
+			MethodName: "RenameFile",
+			Handler:    _FileService_RenameFile_Handler,
+		},
+		{
+			MethodName: "StatFile",
+			Handler:    _FileService_StatFile_Handler,
+		},
+		{
+			MethodName: "CreateDirectory",
+			Handler:    _FileService_CreateDirectory_Handler,
+		},
+		{
+			MethodName: "ListVersions",
+			Handler:    _FileService_ListVersions_Handler,
+		},
+		{
+			MethodName: "RestoreVersion",
+			Handler:    _FileService_RestoreVersion_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _FileService_ListTrash_Handler,
+		},
+		{
+			MethodName: "RestoreTrash",
+			Handler:    _FileService_RestoreTrash_Handler,
+		},
+		{
+			MethodName: "PurgeTrash",
+			Handler:    _FileService_PurgeTrash_Handler,
+		},
+		{
+			MethodName: "CreateUploadSession",
+			Handler:    _FileService_CreateUploadSession_Handler,
+		},
+		{
+			MethodName: "GetUploadSession",
+			Handler:    _FileService_GetUploadSession_Handler,
+		},
+		{
+			MethodName: "CommitUploadSession",
+			Handler:    _FileService_CommitUploadSession_Handler,
+		},
+		{
+			MethodName: "AbortUploadSession",
+			Handler:    _FileService_AbortUploadSession_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListFilesStream",
+			Handler:       _FileService_ListFilesStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadFileStream",
+			Handler:       _FileService_UploadFileStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadFileStream",
+			Handler:       _FileService_DownloadFileStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadVersionStream",
+			Handler:       _FileService_DownloadVersionStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AppendUploadSession",
+			Handler:       _FileService_AppendUploadSession_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "grpc-server/proto/fileservice.proto",
 }
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"time"
 
 	"grpc-rabbitmq-fileshare/common"
 	"grpc-rabbitmq-fileshare/grpc-server/proto"
@@ -12,37 +18,154 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // fileServiceServer implementa o servidor gRPC para FileService
 type fileServiceServer struct {
 	proto.UnimplementedFileServiceServer
-	storage common.FileService
+	storage    common.FileService
+	sessions   *common.UploadSessions
+	defaultTTL time.Duration // TTL dos uploads que não informam um; 0 não expira
 }
 
 // NewFileServiceServer cria uma nova instância do servidor gRPC
-func NewFileServiceServer(storage common.FileService) *fileServiceServer {
+func NewFileServiceServer(storage common.FileService, sessions *common.UploadSessions, defaultTTL time.Duration) *fileServiceServer {
 	return &fileServiceServer{
-		storage: storage,
+		storage:    storage,
+		sessions:   sessions,
+		defaultTTL: defaultTTL,
 	}
 }
 
-// ListFiles lista todos os arquivos disponíveis
-func (s *fileServiceServer) ListFiles(ctx context.Context, req *proto.Empty) (*proto.FileListResponse, error) {
-	log.Printf("[ListFiles] Requisição recebida")
+// ListFiles lista os arquivos e diretórios de um diretório, ou uma página
+// da listagem se req.PageSize for informado
+func (s *fileServiceServer) ListFiles(ctx context.Context, req *proto.ListRequest) (*proto.FileListResponse, error) {
+	log.Printf("[ListFiles] Requisição recebida (diretório: %q, recursivo: %v)", req.Dir, req.Recursive)
 
-	files, err := s.storage.ListFiles()
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	files, next, err := s.storage.ListFiles(opts)
 	if err != nil {
 		log.Printf("[ListFiles] Erro ao listar arquivos: %v", err)
-		return nil, status.Errorf(codes.Internal, "erro ao listar arquivos: %v", err)
+		return nil, storageError("erro ao listar arquivos", err)
 	}
 
 	log.Printf("[ListFiles] %d arquivo(s) encontrado(s)", len(files))
 	return &proto.FileListResponse{
-		Files: files,
+		Files:         common.FileNames(files),
+		FileInfos:     fileInfoProtos(files),
+		NextPageToken: next,
 	}, nil
 }
 
+// defaultStreamPageSize é o número de entradas por mensagem de
+// ListFilesStream quando a requisição não informa page_size
+const defaultStreamPageSize = 1000
+
+// ListFilesStream lista um diretório enviando uma página por mensagem, até a
+// última. Cada mensagem leva o token da página seguinte, com o qual um
+// cliente interrompido pode continuar a listagem
+func (s *fileServiceServer) ListFilesStream(req *proto.ListRequest, stream grpc.ServerStreamingServer[proto.FileListResponse]) error {
+	log.Printf("[ListFilesStream] Requisição recebida (diretório: %q, recursivo: %v)", req.Dir, req.Recursive)
+
+	opts, err := listOptions(req)
+	if err != nil {
+		return err
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = defaultStreamPageSize
+	}
+
+	total := 0
+	for {
+		files, next, err := s.storage.ListFiles(opts)
+		if err != nil {
+			log.Printf("[ListFilesStream] Erro ao listar arquivos: %v", err)
+			return storageError("erro ao listar arquivos", err)
+		}
+
+		err = stream.Send(&proto.FileListResponse{
+			Files:         common.FileNames(files),
+			FileInfos:     fileInfoProtos(files),
+			NextPageToken: next,
+		})
+		if err != nil {
+			return err
+		}
+
+		total += len(files)
+		if next == "" {
+			log.Printf("[ListFilesStream] %d arquivo(s) encontrado(s)", total)
+			return nil
+		}
+		opts.PageToken = next
+	}
+}
+
+// listOptions converte uma requisição de listagem para as opções do armazenamento
+func listOptions(req *proto.ListRequest) (common.ListOptions, error) {
+	opts := common.ListOptions{
+		Dir:       req.Dir,
+		Recursive: req.Recursive,
+		Prefix:    req.Prefix,
+		Pattern:   req.Pattern,
+		Reverse:   req.Reverse,
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+		Selector:  req.Selector,
+	}
+
+	switch req.Sort {
+	case proto.ListSort_LIST_SORT_NAME:
+		opts.Sort = common.SortByName
+	case proto.ListSort_LIST_SORT_SIZE:
+		opts.Sort = common.SortBySize
+	case proto.ListSort_LIST_SORT_MOD_TIME:
+		opts.Sort = common.SortByModTime
+	default:
+		return opts, status.Errorf(codes.InvalidArgument, "ordem de listagem desconhecida: %d", req.Sort)
+	}
+
+	return opts, nil
+}
+
+// fileInfoProtos converte as informações de arquivos para mensagens do protocolo
+func fileInfoProtos(files []common.FileInfo) []*proto.FileInfo {
+	infos := make([]*proto.FileInfo, len(files))
+	for i, file := range files {
+		infos[i] = fileInfoProto(file)
+	}
+	return infos
+}
+
+// fileInfoProto converte as informações de um arquivo para a mensagem do protocolo
+func fileInfoProto(file common.FileInfo) *proto.FileInfo {
+	return &proto.FileInfo{
+		Name:        file.Name,
+		Size:        file.Size,
+		ModTime:     timestamppb.New(file.ModTime),
+		Sha256:      file.SHA256,
+		ContentType: file.ContentType,
+		IsDir:       file.IsDir,
+		Etag:        file.ETag,
+		StoredSize:  file.StoredSize,
+		ExpiresAt:   optionalTimestamp(file.ExpiresAt),
+		Tags:        file.Tags,
+	}
+}
+
+// optionalTimestamp converte um instante opcional, como a expiração de um
+// arquivo, para o protocolo, onde o instante zero é um campo ausente
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
 // UploadFile faz upload de um arquivo
 func (s *fileServiceServer) UploadFile(ctx context.Context, req *proto.UploadRequest) (*proto.OperationResult, error) {
 	log.Printf("[UploadFile] Requisição recebida para arquivo: %s (tamanho: %d bytes)", req.Name, len(req.Data))
@@ -64,9 +187,18 @@ func (s *fileServiceServer) UploadFile(ctx context.Context, req *proto.UploadReq
 	}
 
 	log.Printf("[UploadFile] Iniciando escrita do arquivo %s", req.Name)
-	err := s.storage.UploadFile(req.Name, req.Data)
+	opts := common.UploadOptions{
+		IfMatch:     req.IfMatch,
+		IfNoneMatch: req.IfNoneMatch,
+		TTL:         common.UploadTTL(req.TtlSeconds, s.defaultTTL),
+		Tags:        req.Tags,
+	}
+	info, err := s.storage.UploadFile(req.Name, bytes.NewReader(req.Data), opts)
 	if err != nil {
 		log.Printf("[UploadFile] Erro ao fazer upload do arquivo %s: %v", req.Name, err)
+		if errors.Is(err, common.ErrPreconditionFailed) {
+			return nil, storageError("erro ao fazer upload", err)
+		}
 		return &proto.OperationResult{
 			Success: false,
 			Message: fmt.Sprintf("erro ao fazer upload: %v", err),
@@ -75,11 +207,108 @@ func (s *fileServiceServer) UploadFile(ctx context.Context, req *proto.UploadReq
 
 	log.Printf("[UploadFile] Arquivo %s enviado com sucesso (%d bytes)", req.Name, len(req.Data))
 	return &proto.OperationResult{
-		Success: true,
-		Message: fmt.Sprintf("arquivo %s enviado com sucesso", req.Name),
+		Success:   true,
+		Message:   fmt.Sprintf("arquivo %s enviado com sucesso", req.Name),
+		Etag:      info.ETag,
+		ExpiresAt: optionalTimestamp(info.ExpiresAt),
 	}, nil
 }
 
+// UploadFileStream recebe um arquivo em blocos: a primeira mensagem traz o
+// cabeçalho com nome, tamanho e checksum, e as seguintes trazem os dados
+func (s *fileServiceServer) UploadFileStream(stream grpc.ClientStreamingServer[proto.UploadChunk, proto.OperationResult]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		log.Printf("[UploadFileStream] Erro ao receber cabeçalho: %v", err)
+		return status.Errorf(codes.InvalidArgument, "erro ao receber cabeçalho: %v", err)
+	}
+
+	header := first.GetHeader()
+	if header == nil {
+		log.Printf("[UploadFileStream] Erro: primeira mensagem não contém cabeçalho")
+		return status.Errorf(codes.InvalidArgument, "a primeira mensagem deve conter o cabeçalho do arquivo")
+	}
+
+	log.Printf("[UploadFileStream] Requisição recebida para arquivo: %s (tamanho: %d bytes)", header.Name, header.Size)
+
+	if header.Name == "" {
+		log.Printf("[UploadFileStream] Erro: nome do arquivo vazio")
+		return stream.SendAndClose(&proto.OperationResult{
+			Success: false,
+			Message: "nome do arquivo não pode ser vazio",
+		})
+	}
+
+	if header.Size <= 0 {
+		log.Printf("[UploadFileStream] Erro: dados do arquivo vazios")
+		return stream.SendAndClose(&proto.OperationResult{
+			Success: false,
+			Message: "dados do arquivo não podem ser vazios",
+		})
+	}
+
+	// Os blocos são repassados ao armazenamento à medida que chegam e o
+	// conteúdo é validado contra o cabeçalho ao final do stream
+	chunks := &chunkReader{recv: func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.GetHeader() != nil {
+			return nil, fmt.Errorf("cabeçalho duplicado no stream de upload")
+		}
+		return chunk.GetData(), nil
+	}}
+	reader := common.NewVerifyingReader(chunks, header.Size, header.Checksum)
+
+	opts := common.UploadOptions{
+		IfMatch:     header.IfMatch,
+		IfNoneMatch: header.IfNoneMatch,
+		TTL:         common.UploadTTL(header.TtlSeconds, s.defaultTTL),
+		Tags:        header.Tags,
+	}
+	info, err := s.storage.UploadFile(header.Name, reader, opts)
+	if err != nil {
+		log.Printf("[UploadFileStream] Erro ao fazer upload do arquivo %s: %v", header.Name, err)
+		if errors.Is(err, common.ErrPreconditionFailed) {
+			return storageError("erro ao fazer upload", err)
+		}
+		return stream.SendAndClose(&proto.OperationResult{
+			Success: false,
+			Message: fmt.Sprintf("erro ao fazer upload: %v", err),
+		})
+	}
+
+	log.Printf("[UploadFileStream] Arquivo %s enviado com sucesso (%d bytes)", header.Name, info.Size)
+	return stream.SendAndClose(&proto.OperationResult{
+		Success:   true,
+		Message:   fmt.Sprintf("arquivo %s enviado com sucesso", header.Name),
+		Etag:      info.ETag,
+		ExpiresAt: optionalTimestamp(info.ExpiresAt),
+	})
+}
+
+// chunkReader adapta uma sequência de blocos recebidos de um stream para io.Reader
+type chunkReader struct {
+	recv func() ([]byte, error)
+	buf  []byte
+}
+
+// Read copia o bloco atual para p, recebendo o próximo bloco quando necessário
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		data, err := r.recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
 // DownloadFile faz download de um arquivo
 func (s *fileServiceServer) DownloadFile(ctx context.Context, req *proto.DownloadRequest) (*proto.DownloadResponse, error) {
 	log.Printf("[DownloadFile] Requisição recebida para arquivo: %s", req.Name)
@@ -89,34 +318,216 @@ func (s *fileServiceServer) DownloadFile(ctx context.Context, req *proto.Downloa
 		return nil, status.Errorf(codes.InvalidArgument, "nome do arquivo não pode ser vazio")
 	}
 
-	data, err := s.storage.DownloadFile(req.Name)
+	opts := common.DownloadOptions{Offset: req.Offset, Length: req.Length}
+	file, info, err := s.storage.DownloadFile(req.Name, opts)
 	if err != nil {
 		log.Printf("[DownloadFile] Erro ao fazer download do arquivo %s: %v", req.Name, err)
-		return nil, status.Errorf(codes.NotFound, "erro ao fazer download: %v", err)
+		return nil, storageError("erro ao fazer download", err)
+	}
+	defer file.Close()
+	offset, _, _ := opts.Range(info.Size)
+
+	// A resposta unária precisa do conteúdo completo em uma única mensagem
+	// O armazenamento confere o conteúdo com o checksum durante a leitura
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("[DownloadFile] Erro ao ler arquivo %s: %v", req.Name, err)
+		return nil, storageError("erro ao ler arquivo", err)
+	}
+
+	// O checksum registrado só descreve o arquivo inteiro
+	checksum := info.SHA256
+	if checksum == "" || opts.Partial() {
+		checksum, _, _ = common.Checksum(bytes.NewReader(data))
 	}
 
 	log.Printf("[DownloadFile] Arquivo %s baixado com sucesso (%d bytes)", req.Name, len(data))
 	return &proto.DownloadResponse{
-		Data: data,
+		Data:     data,
+		Checksum: checksum,
+		Offset:   offset,
+		FileSize: info.Size,
+	}, nil
+}
+
+// DownloadFileStream envia um arquivo, ou o trecho selecionado por offset e
+// length, em blocos de common.ChunkSize e termina com um trailer contendo o
+// tamanho e o SHA-256 do conteúdo enviado
+func (s *fileServiceServer) DownloadFileStream(req *proto.DownloadRequest, stream grpc.ServerStreamingServer[proto.DownloadChunk]) error {
+	log.Printf("[DownloadFileStream] Requisição recebida para arquivo: %s", req.Name)
+
+	if req.Name == "" {
+		log.Printf("[DownloadFileStream] Erro: nome do arquivo vazio")
+		return status.Errorf(codes.InvalidArgument, "nome do arquivo não pode ser vazio")
+	}
+
+	opts := common.DownloadOptions{Offset: req.Offset, Length: req.Length}
+	file, info, err := s.storage.DownloadFile(req.Name, opts)
+	if err != nil {
+		log.Printf("[DownloadFileStream] Erro ao fazer download do arquivo %s: %v", req.Name, err)
+		return storageError("erro ao fazer download", err)
+	}
+	defer file.Close()
+
+	trailer := &proto.DownloadTrailer{FileSize: info.Size}
+	trailer.Offset, _, _ = opts.Range(info.Size)
+	if !opts.Partial() {
+		trailer.Checksum = info.SHA256
+	}
+
+	size, err := sendChunks(file, trailer, stream)
+	if err != nil {
+		log.Printf("[DownloadFileStream] Erro ao enviar arquivo %s: %v", req.Name, err)
+		return err
+	}
+
+	log.Printf("[DownloadFileStream] Arquivo %s baixado com sucesso (%d bytes)", req.Name, size)
+	return nil
+}
+
+// sendChunks envia o conteúdo de r em blocos de common.ChunkSize seguidos de
+// trailer, completado com o tamanho enviado, e retorna o tamanho. Se o
+// trailer não trouxer o SHA-256 registrado no upload, é enviado o do
+// conteúdo lido; r deve falhar antes do fim se o conteúdo não conferir com
+// o registrado, para que o trailer nunca seja enviado nesse caso. Sem
+// FileSize, o conteúdo enviado é considerado o arquivo inteiro
+func sendChunks(r io.Reader, trailer *proto.DownloadTrailer, stream grpc.ServerStreamingServer[proto.DownloadChunk]) (int64, error) {
+	hash := sha256.New()
+	var size int64
+
+	for {
+		// Um bloco novo a cada mensagem: o gRPC pode manter referência à
+		// mensagem depois de Send, então ela não pode ser reaproveitada
+		buf := make([]byte, common.ChunkSize)
+		n, err := r.Read(buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			size += int64(n)
+			if sendErr := stream.Send(&proto.DownloadChunk{
+				Payload: &proto.DownloadChunk_Data{Data: buf[:n]},
+			}); sendErr != nil {
+				return size, sendErr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return size, storageError("erro ao ler arquivo", err)
+		}
+	}
+
+	trailer.Size = size
+	if trailer.Checksum == "" {
+		trailer.Checksum = hex.EncodeToString(hash.Sum(nil))
+	}
+	if trailer.FileSize == 0 {
+		trailer.FileSize = size
+	}
+
+	return size, stream.Send(&proto.DownloadChunk{
+		Payload: &proto.DownloadChunk_Trailer{Trailer: trailer},
+	})
+}
+
+// DeleteFile remove um arquivo
+func (s *fileServiceServer) DeleteFile(ctx context.Context, req *proto.DeleteRequest) (*proto.OperationResult, error) {
+	log.Printf("[DeleteFile] Requisição recebida para arquivo: %s", req.Name)
+
+	if err := s.storage.DeleteFile(req.Name); err != nil {
+		log.Printf("[DeleteFile] Erro ao remover arquivo %s: %v", req.Name, err)
+		return nil, storageError("erro ao remover arquivo", err)
+	}
+
+	log.Printf("[DeleteFile] Arquivo %s removido com sucesso", req.Name)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("arquivo %s removido com sucesso", req.Name),
+	}, nil
+}
+
+// RenameFile renomeia um arquivo
+func (s *fileServiceServer) RenameFile(ctx context.Context, req *proto.RenameRequest) (*proto.OperationResult, error) {
+	log.Printf("[RenameFile] Requisição recebida: %s -> %s", req.OldName, req.NewName)
+
+	if err := s.storage.RenameFile(req.OldName, req.NewName); err != nil {
+		log.Printf("[RenameFile] Erro ao renomear %s para %s: %v", req.OldName, req.NewName, err)
+		return nil, storageError("erro ao renomear arquivo", err)
+	}
+
+	log.Printf("[RenameFile] Arquivo %s renomeado para %s", req.OldName, req.NewName)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("arquivo %s renomeado para %s", req.OldName, req.NewName),
+	}, nil
+}
+
+// StatFile retorna as informações de um arquivo
+func (s *fileServiceServer) StatFile(ctx context.Context, req *proto.StatRequest) (*proto.FileInfo, error) {
+	log.Printf("[StatFile] Requisição recebida para arquivo: %s", req.Name)
+
+	info, err := s.storage.StatFile(req.Name)
+	if err != nil {
+		log.Printf("[StatFile] Erro ao consultar arquivo %s: %v", req.Name, err)
+		return nil, storageError("erro ao consultar arquivo", err)
+	}
+
+	return fileInfoProto(info), nil
+}
+
+// CreateDirectory cria um diretório
+func (s *fileServiceServer) CreateDirectory(ctx context.Context, req *proto.CreateDirectoryRequest) (*proto.OperationResult, error) {
+	log.Printf("[CreateDirectory] Requisição recebida para diretório: %s", req.Name)
+
+	if err := s.storage.CreateDirectory(req.Name); err != nil {
+		log.Printf("[CreateDirectory] Erro ao criar diretório %s: %v", req.Name, err)
+		return nil, storageError("erro ao criar diretório", err)
+	}
+
+	log.Printf("[CreateDirectory] Diretório %s criado com sucesso", req.Name)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("diretório %s criado com sucesso", req.Name),
 	}, nil
 }
 
+// storageError converte um erro do armazenamento para o status gRPC correspondente
+func storageError(msg string, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, common.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, common.ErrAlreadyExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, common.ErrInvalidName), errors.Is(err, common.ErrIsDirectory), errors.Is(err, common.ErrInvalidListOptions), errors.Is(err, common.ErrInvalidTags):
+		code = codes.InvalidArgument
+	case errors.Is(err, common.ErrDirectoryNotEmpty), errors.Is(err, common.ErrPreconditionFailed):
+		code = codes.FailedPrecondition
+	case errors.Is(err, common.ErrInvalidRange):
+		code = codes.OutOfRange
+	case errors.Is(err, common.ErrDecryptionFailed), errors.Is(err, common.ErrCorrupted):
+		code = codes.DataLoss
+	}
+	return status.Errorf(code, "%s: %v", msg, err)
+}
+
 // StartServer inicia o servidor gRPC na porta especificada
-func StartServer(port string, storage common.FileService) error {
+func StartServer(port string, storage common.FileService, sessions *common.UploadSessions, defaultTTL time.Duration) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 	if err != nil {
 		return fmt.Errorf("falha ao escutar na porta %s: %w", port, err)
 	}
 
 	// Cria o servidor gRPC com tamanho máximo de mensagem de 50MB
-	// Isso permite upload/download de arquivos de até 50MB
+	// Isso permite upload/download de arquivos de até 50MB pelas RPCs unárias;
+	// as RPCs em streaming enviam blocos de common.ChunkSize e não dependem desse limite
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(50*1024*1024), // 50MB
 		grpc.MaxSendMsgSize(50*1024*1024), // 50MB
 	)
 
 	// Registra o serviço
-	fileServiceServer := NewFileServiceServer(storage)
+	fileServiceServer := NewFileServiceServer(storage, sessions, defaultTTL)
 	proto.RegisterFileServiceServer(grpcServer, fileServiceServer)
 
 	log.Printf("Servidor gRPC iniciado e escutando na porta %s", port)
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+	"grpc-rabbitmq-fileshare/grpc-server/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// trasher retorna o armazenamento como common.Trasher, ou um erro
+// Unimplemented se ele não mantiver lixeira
+func (s *fileServiceServer) trasher() (common.Trasher, error) {
+	trasher, ok := s.storage.(common.Trasher)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "o armazenamento não mantém lixeira")
+	}
+	return trasher, nil
+}
+
+// ListTrash lista as entradas da lixeira
+func (s *fileServiceServer) ListTrash(ctx context.Context, req *proto.ListTrashRequest) (*proto.TrashListResponse, error) {
+	log.Printf("[ListTrash] Requisição recebida")
+
+	trasher, err := s.trasher()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := trasher.ListTrash()
+	if err != nil {
+		log.Printf("[ListTrash] Erro ao listar a lixeira: %v", err)
+		return nil, storageError("erro ao listar a lixeira", err)
+	}
+
+	log.Printf("[ListTrash] %d entrada(s) encontrada(s)", len(entries))
+	return &proto.TrashListResponse{Entries: trashEntryProtos(entries)}, nil
+}
+
+// RestoreTrash grava uma entrada da lixeira de volta no seu nome
+func (s *fileServiceServer) RestoreTrash(ctx context.Context, req *proto.TrashRequest) (*proto.OperationResult, error) {
+	log.Printf("[RestoreTrash] Requisição recebida para entrada: %s", req.Id)
+
+	trasher, err := s.trasher()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := trasher.RestoreTrash(req.Id)
+	if err != nil {
+		log.Printf("[RestoreTrash] Erro ao restaurar entrada %s: %v", req.Id, err)
+		return nil, storageError("erro ao restaurar da lixeira", err)
+	}
+
+	log.Printf("[RestoreTrash] Entrada %s restaurada em %s", req.Id, info.Name)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("%s restaurado da lixeira", info.Name),
+		Etag:    info.ETag,
+	}, nil
+}
+
+// PurgeTrash remove definitivamente uma entrada da lixeira, ou todas
+func (s *fileServiceServer) PurgeTrash(ctx context.Context, req *proto.TrashRequest) (*proto.TrashListResponse, error) {
+	log.Printf("[PurgeTrash] Requisição recebida (entrada: %q, todas: %v)", req.Id, req.All)
+
+	if (req.Id == "") == !req.All {
+		return nil, status.Errorf(codes.InvalidArgument, "informe uma entrada da lixeira ou all, não ambos")
+	}
+
+	trasher, err := s.trasher()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := trasher.PurgeTrash(req.Id)
+	if err != nil {
+		log.Printf("[PurgeTrash] Erro ao esvaziar a lixeira: %v", err)
+		return nil, storageError("erro ao esvaziar a lixeira", err)
+	}
+
+	log.Printf("[PurgeTrash] %d entrada(s) removida(s) da lixeira", len(entries))
+	return &proto.TrashListResponse{Entries: trashEntryProtos(entries)}, nil
+}
+
+// trashEntryProtos converte entradas da lixeira para mensagens do protocolo
+func trashEntryProtos(entries []common.TrashEntry) []*proto.TrashEntry {
+	protos := make([]*proto.TrashEntry, len(entries))
+	for i, entry := range entries {
+		protos[i] = &proto.TrashEntry{
+			Id:          entry.ID,
+			Name:        entry.Name,
+			Size:        entry.Size,
+			Sha256:      entry.SHA256,
+			ContentType: entry.ContentType,
+			ModTime:     timestamppb.New(entry.ModTime),
+			DeletedAt:   timestamppb.New(entry.DeletedAt),
+			PurgeAt:     optionalTimestamp(entry.PurgeAt),
+			Overwritten: entry.Overwritten,
+			Tags:        entry.Tags,
+		}
+	}
+	return protos
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+	"grpc-rabbitmq-fileshare/grpc-server/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateUploadSession inicia uma sessão de upload retomável
+func (s *fileServiceServer) CreateUploadSession(ctx context.Context, req *proto.CreateUploadSessionRequest) (*proto.UploadSessionInfo, error) {
+	log.Printf("[CreateUploadSession] Requisição recebida para arquivo: %s (tamanho: %d bytes)", req.Name, req.Size)
+
+	session, err := s.sessions.Create(req.Name, req.Size, req.Checksum, common.UploadTTL(req.TtlSeconds, s.defaultTTL), req.Tags)
+	if err != nil {
+		log.Printf("[CreateUploadSession] Erro ao criar sessão para %s: %v", req.Name, err)
+		return nil, status.Errorf(codes.InvalidArgument, "erro ao criar sessão: %v", err)
+	}
+
+	log.Printf("[CreateUploadSession] Sessão %s criada para %s", session.ID, session.Name)
+	return sessionInfo(session), nil
+}
+
+// GetUploadSession retorna o offset confirmado de uma sessão
+func (s *fileServiceServer) GetUploadSession(ctx context.Context, req *proto.UploadSessionRequest) (*proto.UploadSessionInfo, error) {
+	log.Printf("[GetUploadSession] Requisição recebida para sessão: %s", req.SessionId)
+
+	session, err := s.sessions.Get(req.SessionId)
+	if err != nil {
+		log.Printf("[GetUploadSession] Erro ao consultar sessão %s: %v", req.SessionId, err)
+		return nil, sessionError(err)
+	}
+
+	return sessionInfo(session), nil
+}
+
+// AppendUploadSession grava os blocos recebidos na sessão a partir do offset
+// informado no cabeçalho. Se o stream for interrompido, os bytes recebidos
+// até então continuam confirmados
+func (s *fileServiceServer) AppendUploadSession(stream grpc.ClientStreamingServer[proto.UploadSessionChunk, proto.UploadSessionInfo]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		log.Printf("[AppendUploadSession] Erro ao receber cabeçalho: %v", err)
+		return status.Errorf(codes.InvalidArgument, "erro ao receber cabeçalho: %v", err)
+	}
+
+	header := first.GetHeader()
+	if header == nil {
+		log.Printf("[AppendUploadSession] Erro: primeira mensagem não contém cabeçalho")
+		return status.Errorf(codes.InvalidArgument, "a primeira mensagem deve conter o cabeçalho da sessão")
+	}
+
+	log.Printf("[AppendUploadSession] Recebendo dados da sessão %s a partir do offset %d", header.SessionId, header.Offset)
+
+	chunks := &chunkReader{recv: func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.GetHeader() != nil {
+			return nil, fmt.Errorf("cabeçalho duplicado no stream da sessão")
+		}
+		return chunk.GetData(), nil
+	}}
+
+	offset, err := s.sessions.Append(header.SessionId, header.Offset, chunks)
+	if err != nil {
+		log.Printf("[AppendUploadSession] Erro na sessão %s (offset confirmado: %d): %v", header.SessionId, offset, err)
+		return sessionError(err)
+	}
+
+	session, err := s.sessions.Get(header.SessionId)
+	if err != nil {
+		return sessionError(err)
+	}
+
+	log.Printf("[AppendUploadSession] Sessão %s em %d de %d bytes", session.ID, session.Offset, session.Size)
+	return stream.SendAndClose(sessionInfo(session))
+}
+
+// CommitUploadSession conclui a sessão e grava o arquivo no armazenamento
+func (s *fileServiceServer) CommitUploadSession(ctx context.Context, req *proto.UploadSessionRequest) (*proto.OperationResult, error) {
+	log.Printf("[CommitUploadSession] Requisição recebida para sessão: %s", req.SessionId)
+
+	session, err := s.sessions.Commit(req.SessionId)
+	if errors.Is(err, common.ErrSessionNotFound) || errors.Is(err, common.ErrSessionIncomplete) {
+		log.Printf("[CommitUploadSession] Erro ao concluir sessão %s: %v", req.SessionId, err)
+		return nil, sessionError(err)
+	}
+	if err != nil {
+		log.Printf("[CommitUploadSession] Erro ao concluir sessão %s: %v", req.SessionId, err)
+		return &proto.OperationResult{
+			Success: false,
+			Message: fmt.Sprintf("erro ao fazer upload: %v", err),
+		}, nil
+	}
+
+	log.Printf("[CommitUploadSession] Arquivo %s enviado com sucesso (%d bytes)", session.Name, session.Size)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("arquivo %s enviado com sucesso", session.Name),
+	}, nil
+}
+
+// AbortUploadSession cancela a sessão e descarta os dados recebidos
+func (s *fileServiceServer) AbortUploadSession(ctx context.Context, req *proto.UploadSessionRequest) (*proto.OperationResult, error) {
+	log.Printf("[AbortUploadSession] Requisição recebida para sessão: %s", req.SessionId)
+
+	if err := s.sessions.Abort(req.SessionId); err != nil {
+		log.Printf("[AbortUploadSession] Erro ao cancelar sessão %s: %v", req.SessionId, err)
+		return nil, sessionError(err)
+	}
+
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("sessão %s cancelada", req.SessionId),
+	}, nil
+}
+
+// sessionInfo converte uma sessão para a mensagem do protocolo
+func sessionInfo(session *common.UploadSession) *proto.UploadSessionInfo {
+	return &proto.UploadSessionInfo{
+		SessionId: session.ID,
+		Name:      session.Name,
+		Size:      session.Size,
+		Offset:    session.Offset,
+	}
+}
+
+// sessionError converte erros de sessão para o status gRPC correspondente
+func sessionError(err error) error {
+	switch {
+	case errors.Is(err, common.ErrSessionNotFound):
+		return status.Errorf(codes.NotFound, "%v", err)
+	case errors.Is(err, common.ErrOffsetMismatch), errors.Is(err, common.ErrSessionIncomplete):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, common.ErrSizeMismatch):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	default:
+		return status.Errorf(codes.Internal, "%v", err)
+	}
+}
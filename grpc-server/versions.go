@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+	"grpc-rabbitmq-fileshare/grpc-server/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// versioner retorna o armazenamento como common.Versioner, ou um erro
+// Unimplemented se ele não mantiver versões anteriores
+func (s *fileServiceServer) versioner() (common.Versioner, error) {
+	versioner, ok := s.storage.(common.Versioner)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "o armazenamento não mantém versões de arquivos")
+	}
+	return versioner, nil
+}
+
+// ListVersions lista as versões anteriores de um arquivo
+func (s *fileServiceServer) ListVersions(ctx context.Context, req *proto.ListVersionsRequest) (*proto.VersionListResponse, error) {
+	log.Printf("[ListVersions] Requisição recebida para arquivo: %s", req.Name)
+
+	versioner, err := s.versioner()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := versioner.ListVersions(req.Name)
+	if err != nil {
+		log.Printf("[ListVersions] Erro ao listar versões de %s: %v", req.Name, err)
+		return nil, storageError("erro ao listar versões", err)
+	}
+
+	resp := &proto.VersionListResponse{}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, &proto.FileVersion{
+			VersionId:  version.ID,
+			Size:       version.Size,
+			ModTime:    timestamppb.New(version.ModTime),
+			ReplacedAt: timestamppb.New(version.ReplacedAt),
+		})
+	}
+
+	log.Printf("[ListVersions] %d versão(ões) encontrada(s) para %s", len(versions), req.Name)
+	return resp, nil
+}
+
+// DownloadVersionStream envia uma versão anterior de um arquivo em blocos,
+// terminando com o mesmo trailer de DownloadFileStream
+func (s *fileServiceServer) DownloadVersionStream(req *proto.VersionRequest, stream grpc.ServerStreamingServer[proto.DownloadChunk]) error {
+	log.Printf("[DownloadVersionStream] Requisição recebida para arquivo: %s (versão: %s)", req.Name, req.VersionId)
+
+	versioner, err := s.versioner()
+	if err != nil {
+		return err
+	}
+
+	file, version, err := versioner.DownloadVersion(req.Name, req.VersionId)
+	if err != nil {
+		log.Printf("[DownloadVersionStream] Erro ao abrir versão %s de %s: %v", req.VersionId, req.Name, err)
+		return storageError("erro ao fazer download da versão", err)
+	}
+	defer file.Close()
+
+	// Como em DownloadFileStream, o trailer leva o checksum registrado quando
+	// a versão foi guardada, com o qual o armazenamento confere a leitura
+	size, err := sendChunks(file, &proto.DownloadTrailer{Checksum: version.SHA256}, stream)
+	if err != nil {
+		log.Printf("[DownloadVersionStream] Erro ao enviar versão %s de %s: %v", req.VersionId, req.Name, err)
+		return err
+	}
+
+	log.Printf("[DownloadVersionStream] Versão %s de %s baixada com sucesso (%d bytes)", req.VersionId, req.Name, size)
+	return nil
+}
+
+// RestoreVersion torna uma versão anterior a versão atual do arquivo
+func (s *fileServiceServer) RestoreVersion(ctx context.Context, req *proto.VersionRequest) (*proto.OperationResult, error) {
+	log.Printf("[RestoreVersion] Requisição recebida para arquivo: %s (versão: %s)", req.Name, req.VersionId)
+
+	versioner, err := s.versioner()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := versioner.RestoreVersion(req.Name, req.VersionId); err != nil {
+		log.Printf("[RestoreVersion] Erro ao restaurar versão %s de %s: %v", req.VersionId, req.Name, err)
+		return nil, storageError("erro ao restaurar versão", err)
+	}
+
+	log.Printf("[RestoreVersion] Versão %s de %s restaurada com sucesso", req.VersionId, req.Name)
+	return &proto.OperationResult{
+		Success: true,
+		Message: fmt.Sprintf("versão %s de %s restaurada com sucesso", req.VersionId, req.Name),
+	}, nil
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"grpc-rabbitmq-fileshare/common"
@@ -17,13 +23,17 @@ import (
 const (
 	requestQueue = "rpc-file-requests"
 	timeout      = 30 * time.Second
+
+	// sessionChunkSize é o tamanho de cada bloco enviado em uploads retomáveis
+	sessionChunkSize = 1024 * 1024
 )
 
 // Client representa o cliente RabbitMQ
 type Client struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	conn       *amqp.Connection
+	channel    *amqp.Channel
 	replyQueue amqp.Queue
+	replies    <-chan amqp.Delivery
 }
 
 // NewClient cria uma nova instância do cliente RabbitMQ
@@ -71,10 +81,28 @@ func NewClient(amqpURL string) (*Client, error) {
 		return nil, fmt.Errorf("falha ao criar fila de resposta: %w", err)
 	}
 
+	// Registra um único consumidor da fila de resposta, reutilizado por todas
+	// as requisições; consumidores extras dividiriam as respostas entre si
+	replies, err := channel.Consume(
+		replyQueue.Name, // queue
+		"",              // consumer
+		true,            // auto-ack
+		false,           // exclusive
+		false,           // no-local
+		false,           // no-wait
+		nil,             // args
+	)
+	if err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("falha ao registrar consumidor: %w", err)
+	}
+
 	return &Client{
 		conn:       conn,
 		channel:    channel,
 		replyQueue: replyQueue,
+		replies:    replies,
 	}, nil
 }
 
@@ -100,20 +128,6 @@ func (c *Client) sendRequest(req common.RequestMessage) (*common.ResponseMessage
 		return nil, fmt.Errorf("erro ao serializar requisição: %w", err)
 	}
 
-	// Consome mensagens da fila de resposta
-	msgs, err := c.channel.Consume(
-		c.replyQueue.Name, // queue
-		"",                 // consumer
-		true,               // auto-ack
-		false,              // exclusive
-		false,              // no-local
-		false,              // no-wait
-		nil,                // args
-	)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao registrar consumidor: %w", err)
-	}
-
 	// Publica a requisição
 	err = c.channel.Publish(
 		"",           // exchange
@@ -135,7 +149,10 @@ func (c *Client) sendRequest(req common.RequestMessage) (*common.ResponseMessage
 	timeoutChan := time.After(timeout)
 	for {
 		select {
-		case msg := <-msgs:
+		case msg, ok := <-c.replies:
+			if !ok {
+				return nil, fmt.Errorf("conexão encerrada aguardando resposta")
+			}
 			// Verifica se é a resposta correta
 			if msg.CorrelationId == correlationID {
 				var resp common.ResponseMessage
@@ -150,10 +167,21 @@ func (c *Client) sendRequest(req common.RequestMessage) (*common.ResponseMessage
 	}
 }
 
-// ListFiles lista todos os arquivos disponíveis no servidor
-func (c *Client) ListFiles() error {
+// ListFiles lista os arquivos e diretórios de opts.Dir no servidor, com os
+// filtros e a ordem de opts. Com opts.PageSize, lista uma página e exibe o
+// token da seguinte
+func (c *Client) ListFiles(opts common.ListOptions) error {
 	req := common.RequestMessage{
 		Operation: "list",
+		FileName:  opts.Dir,
+		Recursive: opts.Recursive,
+		Prefix:    opts.Prefix,
+		Pattern:   opts.Pattern,
+		Sort:      string(opts.Sort),
+		Reverse:   opts.Reverse,
+		PageSize:  opts.PageSize,
+		PageToken: opts.PageToken,
+		Selector:  opts.Selector,
 	}
 
 	resp, err := c.sendRequest(req)
@@ -165,24 +193,70 @@ func (c *Client) ListFiles() error {
 		return fmt.Errorf("erro: %s", resp.Message)
 	}
 
-	if len(resp.Files) == 0 {
+	if len(resp.FileInfos) == 0 {
 		fmt.Println("📁 Nenhum arquivo encontrado no servidor")
 		return nil
 	}
 
 	fmt.Println("📁 Arquivos disponíveis no servidor:")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	for i, file := range resp.Files {
-		fmt.Printf("  %d. %s\n", i+1, file)
+	for i, file := range resp.FileInfos {
+		if file.IsDir {
+			fmt.Printf("  %d. %s/\n", i+1, file.Name)
+		} else {
+			fmt.Printf("  %d. %s\n", i+1, file.Name)
+		}
+		printFileDetails(file)
 	}
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("Total: %d arquivo(s)\n", len(resp.Files))
+	fmt.Printf("Total: %d item(ns)\n", len(resp.FileInfos))
+	if resp.NextPageToken != "" {
+		fmt.Printf("Próxima página: --page-token %s\n", resp.NextPageToken)
+	}
 
 	return nil
 }
 
+// printFileDetails exibe tamanho, tipo, data de modificação e checksum de um
+// arquivo, ou apenas a data de modificação de um diretório
+func printFileDetails(file common.FileInfo) {
+	if file.IsDir {
+		fmt.Printf("     diretório | %s\n", file.ModTime.Local().Format("2006-01-02 15:04:05"))
+		return
+	}
+	fmt.Printf("     %d bytes | %s | %s\n", file.Size, file.ContentType,
+		file.ModTime.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("     SHA-256: %s\n", file.SHA256)
+	if file.StoredSize > 0 && file.StoredSize < file.Size {
+		fmt.Printf("     Armazenado: %d bytes (compactado)\n", file.StoredSize)
+	} else if file.StoredSize > file.Size {
+		fmt.Printf("     Armazenado: %d bytes\n", file.StoredSize)
+	}
+	if file.ETag != "" {
+		fmt.Printf("     ETag: %s\n", file.ETag)
+	}
+	if !file.ExpiresAt.IsZero() {
+		fmt.Printf("     Expira em: %s\n", file.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if len(file.Tags) > 0 {
+		fmt.Printf("     Tags: %s\n", formatTags(file.Tags))
+	}
+}
+
+// formatTags formata tags como "chave=valor", em ordem alfabética de chave
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
 // UploadFile faz upload de um arquivo para o servidor
-func (c *Client) UploadFile(filePath string) error {
+// As condições de opts são avaliadas pelo servidor antes de substituir o arquivo
+// ttl é o TTL do arquivo em segundos, negativo para não expirar e zero para o
+// padrão do servidor
+func (c *Client) UploadFile(filePath string, dest string, opts common.UploadOptions, ttl int64) error {
 	// Abre o arquivo
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -190,22 +264,35 @@ func (c *Client) UploadFile(filePath string) error {
 	}
 	defer file.Close()
 
+	// O conteúdo vai inteiro em uma mensagem; arquivos maiores que
+	// MaxMessageFileSize são enviados com o upload retomável
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("erro ao consultar arquivo %s: %w", filePath, err)
+	}
+	if stat.Size() > common.MaxMessageFileSize {
+		return fmt.Errorf("arquivo %s tem %d bytes e o limite de uma mensagem é de %d: use upload-resume", filePath, stat.Size(), common.MaxMessageFileSize)
+	}
+
 	// Lê o conteúdo do arquivo
 	data, err := io.ReadAll(file)
 	if err != nil {
 		return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
 	}
 
-	// Obtém apenas o nome do arquivo
-	fileName := filepath.Base(filePath)
+	fileName := remoteName(filePath, dest)
 
 	// Codifica os dados em base64 para JSON
 	encodedData := base64.StdEncoding.EncodeToString(data)
 
 	req := common.RequestMessage{
-		Operation: "upload",
-		FileName:  fileName,
-		FileData:  []byte(encodedData),
+		Operation:   "upload",
+		FileName:    fileName,
+		FileData:    []byte(encodedData),
+		IfMatch:     opts.IfMatch,
+		IfNoneMatch: opts.IfNoneMatch,
+		TTL:         ttl,
+		Tags:        opts.Tags,
 	}
 
 	resp, err := c.sendRequest(req)
@@ -213,6 +300,12 @@ func (c *Client) UploadFile(filePath string) error {
 		return err
 	}
 
+	if resp.ErrorCode == common.ErrorCodeFailedPrecondition {
+		fmt.Printf("⚠️  Upload recusado: o arquivo no servidor não atende à condição\n")
+		fmt.Printf("   Mensagem: %s\n", resp.Message)
+		return fmt.Errorf("upload recusado: %s", resp.Message)
+	}
+
 	if !resp.Success {
 		fmt.Printf("❌ Falha no upload!\n")
 		fmt.Printf("   Mensagem: %s\n", resp.Message)
@@ -222,16 +315,72 @@ func (c *Client) UploadFile(filePath string) error {
 	fmt.Printf("✅ Upload realizado com sucesso!\n")
 	fmt.Printf("   Arquivo: %s\n", fileName)
 	fmt.Printf("   Tamanho: %d bytes\n", len(data))
+	if resp.File != nil {
+		fmt.Printf("   ETag: %s\n", resp.File.ETag)
+		if !resp.File.ExpiresAt.IsZero() {
+			fmt.Printf("   Expira em: %s\n", resp.File.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
 	fmt.Printf("   Mensagem: %s\n", resp.Message)
 
 	return nil
 }
 
-// DownloadFile faz download de um arquivo do servidor
-func (c *Client) DownloadFile(fileName string, outputPath string) error {
+// decodeFileData decodifica os dados de um arquivo recebidos em base64,
+// retornando-os sem alteração se não estiverem codificados
+func decodeFileData(data []byte) []byte {
+	if len(data) > 0 {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err == nil {
+			return decoded
+		}
+	}
+	return data
+}
+
+// verifyChecksum confere data com checksum, o SHA-256 enviado pelo servidor
+func verifyChecksum(data []byte, checksum string) error {
+	if checksum == "" {
+		return fmt.Errorf("%w: o servidor não enviou o checksum", common.ErrChecksumMismatch)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != checksum {
+		return fmt.Errorf("%w: esperado %s, calculado %x", common.ErrChecksumMismatch, checksum, sum)
+	}
+	return nil
+}
+
+// formatRange descreve o trecho de length bytes a partir de offset de um
+// arquivo de fileSize bytes
+func formatRange(offset, length, fileSize int64) string {
+	if length == 0 {
+		return fmt.Sprintf("vazio, a partir do byte %d de %d", offset, fileSize)
+	}
+	return fmt.Sprintf("bytes %d-%d de %d", offset, offset+length-1, fileSize)
+}
+
+// remoteName retorna o nome no servidor de um upload de filePath: dest, se
+// informado, ou o nome do arquivo local. Um dest terminado em "/" indica o
+// diretório de destino, mantendo o nome do arquivo local
+func remoteName(filePath string, dest string) string {
+	base := filepath.Base(filePath)
+	if dest == "" {
+		return base
+	}
+	if strings.HasSuffix(dest, "/") {
+		return dest + base
+	}
+	return dest
+}
+
+// DownloadFile faz download de um arquivo, ou do trecho selecionado por opts,
+// do servidor. O conteúdo chega inteiro em uma mensagem, e o servidor recusa
+// trechos maiores que MaxMessageFileSize
+func (c *Client) DownloadFile(fileName string, outputPath string, opts common.DownloadOptions) error {
 	req := common.RequestMessage{
 		Operation: "download",
 		FileName:  fileName,
+		Offset:    opts.Offset,
+		Length:    opts.Length,
 	}
 
 	resp, err := c.sendRequest(req)
@@ -243,18 +392,19 @@ func (c *Client) DownloadFile(fileName string, outputPath string) error {
 		return fmt.Errorf("erro: %s", resp.Message)
 	}
 
-	// Decodifica os dados (se vierem em base64)
-	data := resp.FileData
-	if len(data) > 0 {
-		decoded, err := base64.StdEncoding.DecodeString(string(data))
-		if err == nil {
-			data = decoded
-		}
+	data := decodeFileData(resp.FileData)
+
+	// Confere o conteúdo recebido com o checksum enviado pelo servidor, o
+	// registrado no upload quando o arquivo é baixado inteiro, antes de
+	// salvá-lo, para que um conteúdo corrompido nunca chegue ao disco
+	if err := verifyChecksum(data, resp.Checksum); err != nil {
+		return fmt.Errorf("erro ao fazer download: %w", err)
 	}
 
-	// Se outputPath não foi especificado, usa o nome do arquivo
+	// Se outputPath não foi especificado, usa o nome do arquivo sem os
+	// diretórios do servidor
 	if outputPath == "" {
-		outputPath = fileName
+		outputPath = path.Base(fileName)
 	}
 
 	// Escreve o arquivo
@@ -266,8 +416,359 @@ func (c *Client) DownloadFile(fileName string, outputPath string) error {
 	fmt.Printf("✅ Download realizado com sucesso!\n")
 	fmt.Printf("   Arquivo: %s\n", fileName)
 	fmt.Printf("   Tamanho: %d bytes\n", len(data))
+	if opts.Partial() {
+		fmt.Printf("   Trecho: %s\n", formatRange(resp.Offset, int64(len(data)), resp.Size))
+	}
+	fmt.Printf("   SHA-256: %s (verificado)\n", resp.Checksum)
+	fmt.Printf("   Salvo em: %s\n", outputPath)
+
+	return nil
+}
+
+// UploadFileResumable faz upload de um arquivo usando uma sessão retomável
+// O arquivo é enviado em blocos de sessionChunkSize; se sessionID for
+// informado, o envio continua a partir do último byte confirmado pelo servidor
+// ttl, como em UploadFile, e as tags só são usados ao criar a sessão
+func (c *Client) UploadFileResumable(filePath string, sessionID string, ttl int64, tags map[string]string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	checksum, size, err := common.Checksum(file)
+	if err != nil {
+		return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+	}
+
+	fileName := filepath.Base(filePath)
+
+	// Cria a sessão ou consulta o progresso de uma sessão existente
+	req := common.RequestMessage{
+		Operation: "session_create",
+		FileName:  fileName,
+		Size:      size,
+		Checksum:  checksum,
+		TTL:       ttl,
+		Tags:      tags,
+	}
+	if sessionID != "" {
+		req = common.RequestMessage{
+			Operation: "session_status",
+			SessionID: sessionID,
+		}
+	}
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	if sessionID == "" {
+		fmt.Printf("🆕 Sessão de upload criada: %s\n", resp.SessionID)
+	} else {
+		if resp.Size != size {
+			return fmt.Errorf("a sessão %s espera %d bytes, mas o arquivo tem %d", sessionID, resp.Size, size)
+		}
+		fmt.Printf("🔄 Retomando sessão %s a partir de %d de %d bytes\n", resp.SessionID, resp.Offset, resp.Size)
+	}
+	sessionID = resp.SessionID
+	offset := resp.Offset
+
+	// Envia os blocos restantes, um por mensagem
+	buf := make([]byte, sessionChunkSize)
+	for offset < size {
+		n, err := file.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("erro ao ler arquivo %s: %w", filePath, err)
+		}
+
+		resp, err := c.sendRequest(common.RequestMessage{
+			Operation: "session_append",
+			SessionID: sessionID,
+			Offset:    offset,
+			FileData:  []byte(base64.StdEncoding.EncodeToString(buf[:n])),
+		})
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("erro: %s", resp.Message)
+		}
+		if err != nil {
+			fmt.Printf("❌ Upload interrompido!\n")
+			fmt.Printf("   Para retomar: upload-resume %s %s\n", filePath, sessionID)
+			return err
+		}
+
+		offset = resp.Offset
+	}
+
+	resp, err = c.sendRequest(common.RequestMessage{
+		Operation: "session_commit",
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		fmt.Printf("❌ Falha no upload!\n")
+		fmt.Printf("   Mensagem: %s\n", resp.Message)
+		return fmt.Errorf("upload falhou: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ Upload realizado com sucesso!\n")
+	fmt.Printf("   Arquivo: %s\n", fileName)
+	fmt.Printf("   Tamanho: %d bytes\n", size)
+	fmt.Printf("   Mensagem: %s\n", resp.Message)
+
+	return nil
+}
+
+// DeleteFile remove um arquivo do servidor
+func (c *Client) DeleteFile(fileName string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "delete",
+		FileName:  fileName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// RenameFile renomeia um arquivo no servidor
+func (c *Client) RenameFile(oldName string, newName string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "rename",
+		FileName:  oldName,
+		NewName:   newName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// StatFile exibe as informações de um arquivo do servidor
+func (c *Client) StatFile(fileName string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "stat",
+		FileName:  fileName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success || resp.File == nil {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("📄 %s\n", resp.File.Name)
+	printFileDetails(*resp.File)
+	return nil
+}
+
+// CreateDirectory cria um diretório no servidor
+func (c *Client) CreateDirectory(name string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "mkdir",
+		FileName:  name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// ListVersions lista as versões anteriores de um arquivo no servidor
+func (c *Client) ListVersions(fileName string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "versions",
+		FileName:  fileName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	if len(resp.Versions) == 0 {
+		fmt.Printf("🕘 Nenhuma versão anterior de %s\n", fileName)
+		return nil
+	}
+
+	fmt.Printf("🕘 Versões anteriores de %s:\n", fileName)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for i, version := range resp.Versions {
+		fmt.Printf("  %d. %s\n", i+1, version.ID)
+		fmt.Printf("     %d bytes | gravada em %s | substituída em %s\n", version.Size,
+			version.ModTime.Local().Format("2006-01-02 15:04:05"),
+			version.ReplacedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total: %d versão(ões)\n", len(resp.Versions))
+
+	return nil
+}
+
+// DownloadVersion faz download de uma versão anterior de um arquivo
+func (c *Client) DownloadVersion(fileName string, versionID string, outputPath string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "download_version",
+		FileName:  fileName,
+		VersionID: versionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	data := decodeFileData(resp.FileData)
+
+	// Como em DownloadFile, o conteúdo é conferido com o checksum registrado
+	// quando a versão foi guardada antes de ser salvo
+	if err := verifyChecksum(data, resp.Checksum); err != nil {
+		return fmt.Errorf("erro ao fazer download da versão: %w", err)
+	}
+
+	if outputPath == "" {
+		outputPath = path.Base(fileName) + "." + versionID
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar arquivo: %w", err)
+	}
+
+	fmt.Printf("✅ Download realizado com sucesso!\n")
+	fmt.Printf("   Arquivo: %s (versão %s)\n", fileName, versionID)
+	fmt.Printf("   Tamanho: %d bytes\n", len(data))
+	fmt.Printf("   SHA-256: %s (verificado)\n", resp.Checksum)
 	fmt.Printf("   Salvo em: %s\n", outputPath)
 
 	return nil
 }
 
+// RestoreVersion torna uma versão anterior a versão atual de um arquivo
+func (c *Client) RestoreVersion(fileName string, versionID string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "restore_version",
+		FileName:  fileName,
+		VersionID: versionID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// ListTrash lista os arquivos removidos e substituídos que estão na lixeira
+func (c *Client) ListTrash() error {
+	resp, err := c.sendRequest(common.RequestMessage{Operation: "list_trash"})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	if len(resp.Trash) == 0 {
+		fmt.Println("🗑️  A lixeira está vazia")
+		return nil
+	}
+
+	fmt.Println("🗑️  Arquivos na lixeira:")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for i, entry := range resp.Trash {
+		action := "removido"
+		if entry.Overwritten {
+			action = "substituído"
+		}
+		fmt.Printf("  %d. %s\n", i+1, entry.Name)
+		fmt.Printf("     %d bytes | %s | %s em %s\n", entry.Size, entry.ContentType, action,
+			entry.DeletedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("     ID: %s\n", entry.ID)
+		if !entry.PurgeAt.IsZero() {
+			fmt.Printf("     Removido definitivamente em: %s\n", entry.PurgeAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		if len(entry.Tags) > 0 {
+			fmt.Printf("     Tags: %s\n", formatTags(entry.Tags))
+		}
+	}
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total: %d entrada(s)\n", len(resp.Trash))
+
+	return nil
+}
+
+// RestoreTrash grava uma entrada da lixeira de volta no nome de onde foi removida
+func (c *Client) RestoreTrash(id string) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "restore_trash",
+		TrashID:   id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	fmt.Printf("✅ %s\n", resp.Message)
+	return nil
+}
+
+// PurgeTrash remove definitivamente uma entrada da lixeira ou, com all,
+// esvazia a lixeira
+func (c *Client) PurgeTrash(id string, all bool) error {
+	resp, err := c.sendRequest(common.RequestMessage{
+		Operation: "purge_trash",
+		TrashID:   id,
+		All:       all,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("erro: %s", resp.Message)
+	}
+
+	for _, entry := range resp.Trash {
+		fmt.Printf("🔥 %s (%s)\n", entry.Name, entry.ID)
+	}
+	fmt.Printf("✅ %d entrada(s) removida(s) da lixeira\n", len(resp.Trash))
+	return nil
+}
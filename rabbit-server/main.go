@@ -5,7 +5,9 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"grpc-rabbitmq-fileshare/common"
 )
@@ -19,24 +21,167 @@ func main() {
 	// Define flags
 	amqpURL := flag.String("amqp-url", defaultAMQPURL, "URL de conexão do RabbitMQ")
 	dataDir := flag.String("data-dir", defaultDataDir, "Diretório para armazenar arquivos")
+	keepVersions := flag.Int("keep-versions", 0, "Versões anteriores mantidas por arquivo (0 não limita; com -version-max-age também 0, desativa o versionamento)")
+	versionMaxAge := flag.Duration("version-max-age", 0, "Tempo que uma versão substituída é mantida, ex: 720h (0 não limita)")
+	compressionLevel := flag.Int("compression-level", 0, "Nível de compressão gzip dos arquivos gravados no armazenamento local, de 1 (mais rápido) a 9 (menor); 0 desativa")
+	keyFile := flag.String("encryption-key-file", "", "Arquivo com as chaves mestras que cifram os arquivos, uma por linha no formato id:chave (padrão: variável ENCRYPTION_KEYS; sem chaves os arquivos não são cifrados)")
+	rotateKeys := flag.Bool("rotate-keys", false, "Recifra as chaves de dados com a primeira chave mestra e encerra, sem iniciar o servidor")
+	storageKind := flag.String("storage", "local", "Armazenamento dos arquivos: local (em -data-dir), memory (perdido ao encerrar o servidor), dedup (em -data-dir, com conteúdo idêntico gravado uma única vez) ou s3")
+	s3Bucket := flag.String("s3-bucket", "", "Bucket do armazenamento s3")
+	s3Prefix := flag.String("s3-prefix", "", "Prefixo das chaves dos arquivos no bucket, ex: fileshare/")
+	s3Endpoint := flag.String("s3-endpoint", "", "URL de um serviço compatível com S3, ex: http://minio:9000 (vazio usa a AWS)")
+	s3Region := flag.String("s3-region", os.Getenv("AWS_REGION"), "Região do bucket (padrão: $AWS_REGION ou us-east-1)")
+	s3PathStyle := flag.Bool("s3-path-style", false, "Endereça o bucket no caminho da URL, como exigem MinIO e outros serviços compatíveis")
+	trashRetention := flag.Duration("trash-retention", 0, "Tempo que os arquivos removidos ou substituídos ficam na lixeira, ex: 168h (0 desativa a lixeira)")
+	index := flag.Bool("index", false, "Mantém um índice dos arquivos do armazenamento local em -data-dir, consultado nas listagens em vez dos diretórios")
+	reindex := flag.Bool("reindex", false, "Reconstrói o índice do armazenamento local a partir de -data-dir e encerra, sem iniciar o servidor")
+	defaultTTL := flag.Duration("default-ttl", 0, "TTL dos arquivos enviados sem um TTL próprio, ex: 24h (0: não expiram)")
+	janitorInterval := flag.Duration("janitor-interval", time.Minute, "Intervalo entre as remoções de arquivos expirados (0 desativa a remoção)")
+	sessionMaxAge := flag.Duration("session-max-age", 24*time.Hour, "Tempo sem receber blocos depois do qual um upload retomável é descartado pelo janitor, ex: 72h (0 mantém as sessões)")
+	keyGCInterval := flag.Duration("key-gc-interval", 24*time.Hour, "Intervalo entre as remoções das chaves de dados que nenhum arquivo, versão ou entrada da lixeira usa mais (0 desativa a remoção)")
 	flag.Parse()
 
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	log.Println("  RabbitMQ Server - File Sharing System")
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	log.Printf("Conectando ao RabbitMQ: %s", *amqpURL)
+	log.Printf("Armazenamento: %s", *storageKind)
 	log.Printf("Diretório de dados: %s", *dataDir)
+	if *keepVersions > 0 || *versionMaxAge > 0 {
+		log.Printf("Versionamento ativo (máximo de versões: %d, idade máxima: %v)", *keepVersions, *versionMaxAge)
+	}
+	if *compressionLevel > 0 {
+		log.Printf("Compressão ativa (nível %d)", *compressionLevel)
+	}
+	if *trashRetention > 0 {
+		log.Printf("Lixeira ativa (retenção: %v)", *trashRetention)
+	}
+	if *index {
+		log.Printf("Índice ativo")
+	}
+	if *defaultTTL > 0 {
+		log.Printf("TTL padrão dos arquivos: %v", *defaultTTL)
+	}
+
+	if *defaultTTL < 0 {
+		log.Fatalf("-default-ttl não pode ser negativo")
+	}
 
-	// Cria o serviço de armazenamento local
-	storage, err := common.NewLocalStorage(*dataDir)
+	// Carrega as chaves mestras da criptografia, se configuradas
+	keys, err := common.LoadServerKeyRing(*keyFile)
+	if err != nil {
+		log.Fatalf("Erro ao carregar chaves de criptografia: %v", err)
+	}
+
+	if *rotateKeys {
+		if keys == nil {
+			log.Fatalf("-rotate-keys exige -encryption-key-file ou ENCRYPTION_KEYS")
+		}
+		rotated, err := common.RotateKeys(filepath.Join(*dataDir, common.KeysDirName), keys)
+		if err != nil {
+			log.Fatalf("Erro ao rotacionar chaves (%d recifradas): %v", rotated, err)
+		}
+		log.Printf("%d chaves de dados recifradas com a chave mestra %s", rotated, keys.CurrentID())
+		return
+	}
+
+	if *reindex {
+		if *storageKind != "local" {
+			log.Fatalf("-reindex exige o armazenamento local")
+		}
+		local, err := common.NewLocalStorage(*dataDir, common.LocalStorageOptions{Index: true})
+		if err != nil {
+			log.Fatalf("Erro ao abrir o armazenamento: %v", err)
+		}
+		indexed, err := local.Reindex()
+		if err != nil {
+			log.Fatalf("Erro ao reconstruir o índice: %v", err)
+		}
+		log.Printf("Índice reconstruído com %d entradas", indexed)
+		return
+	}
+
+	// Cria o serviço de armazenamento
+	policy := common.VersionPolicy{
+		MaxVersions: *keepVersions,
+		MaxAge:      *versionMaxAge,
+	}
+	if keys != nil && *compressionLevel > 0 {
+		// Conteúdo cifrado não diminui com a compressão
+		log.Printf("Aviso: arquivos cifrados não são compactáveis; -compression-level será ignorado")
+	}
+	storage, err := common.NewStorage(common.StorageConfig{
+		Kind:    *storageKind,
+		DataDir: *dataDir,
+		Local: common.LocalStorageOptions{
+			Versions:         policy,
+			CompressionLevel: *compressionLevel,
+			TrashRetention:   *trashRetention,
+			Index:            *index,
+		},
+		// As credenciais do S3 vêm das variáveis de ambiente padrão da AWS
+		S3: common.S3StorageOptions{
+			Bucket:          *s3Bucket,
+			Prefix:          *s3Prefix,
+			Region:          *s3Region,
+			Endpoint:        *s3Endpoint,
+			UsePathStyle:    *s3PathStyle,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		Keys: keys,
+	})
 	if err != nil {
 		log.Fatalf("Erro ao criar serviço de armazenamento: %v", err)
 	}
+	if keys != nil {
+		log.Printf("Criptografia ativa (chave mestra atual: %s)", keys.CurrentID())
+	}
+
+	if _, ok := storage.(common.Versioner); !ok && policy.Enabled() {
+		log.Printf("Aviso: o armazenamento %s não mantém versões anteriores; -keep-versions e -version-max-age serão ignorados", *storageKind)
+	}
+	if _, ok := storage.(common.Trasher); !ok && *trashRetention > 0 {
+		log.Printf("Aviso: o armazenamento %s não mantém lixeira; -trash-retention será ignorado", *storageKind)
+	}
+	if *storageKind != "local" && *compressionLevel > 0 {
+		log.Printf("Aviso: o armazenamento %s não compacta os arquivos; -compression-level será ignorado", *storageKind)
+	}
+	if *storageKind != "local" && *index {
+		log.Printf("Aviso: o armazenamento %s não mantém índice; -index será ignorado", *storageKind)
+	}
 
 	log.Println("Serviço de armazenamento inicializado com sucesso")
 
+	// Cria o gerenciador de uploads retomáveis, com staging dentro do diretório
+	// de dados mesmo quando os arquivos ficam em memória
+	sessions, err := common.NewUploadSessions(filepath.Join(*dataDir, common.UploadsDirName), storage)
+	if err != nil {
+		log.Fatalf("Erro ao criar gerenciador de sessões de upload: %v", err)
+	}
+
+	// O janitor e a remoção de chaves registram no log com o mesmo prefixo
+	janitorLog := log.New(log.Writer(), "🧹 ", log.Flags()|log.Lmsgprefix)
+
+	// Remove os arquivos expirados em segundo plano
+	if *janitorInterval > 0 {
+		go common.RunJanitor(storage, sessions, *janitorInterval, *sessionMaxAge, janitorLog)
+	}
+
+	// Remove as chaves de dados sem uso em segundo plano. No armazenamento em
+	// memória cada servidor vê só os seus arquivos, mas as chaves ficam no
+	// mesmo -data-dir, então uma chave sem uso aqui pode ser de outro servidor
+	if collector, ok := storage.(common.KeyCollector); ok && *keyGCInterval > 0 {
+		if *storageKind == "memory" {
+			log.Printf("Aviso: o armazenamento memory não remove chaves sem uso; -key-gc-interval será ignorado")
+		} else {
+			go common.RunKeyCollector(collector, *keyGCInterval, janitorLog)
+		}
+	}
+
 	// Cria o servidor RabbitMQ
-	server, err := NewServer(*amqpURL, storage)
+	server, err := NewServer(*amqpURL, storage, sessions, *defaultTTL)
 	if err != nil {
 		log.Fatalf("Erro ao criar servidor: %v", err)
 	}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"time"
 
 	"grpc-rabbitmq-fileshare/common"
 
@@ -17,13 +22,15 @@ const (
 
 // Server representa o servidor RabbitMQ
 type Server struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	storage common.FileService
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	storage    common.FileService
+	sessions   *common.UploadSessions
+	defaultTTL time.Duration // TTL dos uploads que não informam um; 0 não expira
 }
 
 // NewServer cria uma nova instância do servidor RabbitMQ
-func NewServer(amqpURL string, storage common.FileService) (*Server, error) {
+func NewServer(amqpURL string, storage common.FileService, sessions *common.UploadSessions, defaultTTL time.Duration) (*Server, error) {
 	// Conecta ao RabbitMQ
 	conn, err := amqp.Dial(amqpURL)
 	if err != nil {
@@ -65,9 +72,11 @@ func NewServer(amqpURL string, storage common.FileService) (*Server, error) {
 	}
 
 	return &Server{
-		conn:    conn,
-		channel: channel,
-		storage: storage,
+		conn:       conn,
+		channel:    channel,
+		storage:    storage,
+		sessions:   sessions,
+		defaultTTL: defaultTTL,
 	}, nil
 }
 
@@ -122,11 +131,41 @@ func (s *Server) handleMessage(msg amqp.Delivery) {
 
 	switch req.Operation {
 	case "list":
-		resp, err = s.handleList()
+		resp, err = s.handleList(req)
 	case "upload":
 		resp, err = s.handleUpload(req)
 	case "download":
 		resp, err = s.handleDownload(req)
+	case "delete":
+		resp, err = s.handleDelete(req)
+	case "rename":
+		resp, err = s.handleRename(req)
+	case "stat":
+		resp, err = s.handleStat(req)
+	case "mkdir":
+		resp, err = s.handleMkdir(req)
+	case "versions":
+		resp, err = s.handleVersions(req)
+	case "download_version":
+		resp, err = s.handleDownloadVersion(req)
+	case "restore_version":
+		resp, err = s.handleRestoreVersion(req)
+	case "list_trash":
+		resp, err = s.handleListTrash(req)
+	case "restore_trash":
+		resp, err = s.handleRestoreTrash(req)
+	case "purge_trash":
+		resp, err = s.handlePurgeTrash(req)
+	case "session_create":
+		resp, err = s.handleSessionCreate(req)
+	case "session_status":
+		resp, err = s.handleSessionStatus(req)
+	case "session_append":
+		resp, err = s.handleSessionAppend(req)
+	case "session_commit":
+		resp, err = s.handleSessionCommit(req)
+	case "session_abort":
+		resp, err = s.handleSessionAbort(req)
 	default:
 		err = fmt.Errorf("operação desconhecida: %s", req.Operation)
 	}
@@ -151,20 +190,35 @@ func (s *Server) handleMessage(msg amqp.Delivery) {
 }
 
 // handleList processa a operação de listar arquivos
-func (s *Server) handleList() (common.ResponseMessage, error) {
-	files, err := s.storage.ListFiles()
+// Com page_size, a resposta traz uma página e o token da seguinte
+func (s *Server) handleList(req common.RequestMessage) (common.ResponseMessage, error) {
+	sort, err := common.ParseListSort(req.Sort)
 	if err != nil {
-		return common.ResponseMessage{
-			Success: false,
-			Message: fmt.Sprintf("erro ao listar arquivos: %v", err),
-		}, nil
+		return errorResponse("erro ao listar arquivos", err), nil
+	}
+
+	files, next, err := s.storage.ListFiles(common.ListOptions{
+		Dir:       req.FileName,
+		Recursive: req.Recursive,
+		Prefix:    req.Prefix,
+		Pattern:   req.Pattern,
+		Sort:      sort,
+		Reverse:   req.Reverse,
+		PageSize:  req.PageSize,
+		PageToken: req.PageToken,
+		Selector:  req.Selector,
+	})
+	if err != nil {
+		return errorResponse("erro ao listar arquivos", err), nil
 	}
 
 	log.Printf("📋 Listados %d arquivo(s)", len(files))
 	return common.ResponseMessage{
-		Success: true,
-		Files:   files,
-		Message: fmt.Sprintf("%d arquivo(s) encontrado(s)", len(files)),
+		Success:       true,
+		Files:         common.FileNames(files),
+		FileInfos:     files,
+		NextPageToken: next,
+		Message:       fmt.Sprintf("%d arquivo(s) encontrado(s)", len(files)),
 	}, nil
 }
 
@@ -172,44 +226,47 @@ func (s *Server) handleList() (common.ResponseMessage, error) {
 func (s *Server) handleUpload(req common.RequestMessage) (common.ResponseMessage, error) {
 	if req.FileName == "" {
 		return common.ResponseMessage{
-			Success: false,
-			Message: "nome do arquivo não pode ser vazio",
+			Success:   false,
+			Message:   "nome do arquivo não pode ser vazio",
+			ErrorCode: common.ErrorCodeInvalidArgument,
 		}, nil
 	}
 
 	if len(req.FileData) == 0 {
 		return common.ResponseMessage{
-			Success: false,
-			Message: "dados do arquivo não podem ser vazios",
+			Success:   false,
+			Message:   "dados do arquivo não podem ser vazios",
+			ErrorCode: common.ErrorCodeInvalidArgument,
 		}, nil
 	}
 
-	// Decodifica os dados do arquivo (vêm como string base64 em FileData)
-	// FileData é []byte mas contém uma string base64
-	var data []byte
-	if len(req.FileData) > 0 {
-		decoded, err := base64.StdEncoding.DecodeString(string(req.FileData))
-		if err != nil {
-			return common.ResponseMessage{
-				Success: false,
-				Message: fmt.Sprintf("erro ao decodificar dados do arquivo: %v", err),
-			}, nil
-		}
-		data = decoded
+	// O conteúdo chega inteiro na mensagem; acima de MaxMessageFileSize o
+	// cliente deve usar o upload retomável, enviado em blocos
+	if len(req.FileData) > base64.StdEncoding.EncodedLen(common.MaxMessageFileSize) {
+		return errorResponse("erro ao fazer upload", fmt.Errorf("%w: o limite é de %d bytes; use o upload retomável", common.ErrTooLarge, common.MaxMessageFileSize)), nil
 	}
 
-	err := s.storage.UploadFile(req.FileName, data)
+	// Decodifica os dados do arquivo (vêm como string base64 em FileData)
+	// FileData é []byte mas contém uma string base64, decodificada à medida
+	// que é gravada no armazenamento
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(req.FileData))
+
+	opts := common.UploadOptions{
+		IfMatch:     req.IfMatch,
+		IfNoneMatch: req.IfNoneMatch,
+		TTL:         common.UploadTTL(req.TTL, s.defaultTTL),
+		Tags:        req.Tags,
+	}
+	info, err := s.storage.UploadFile(req.FileName, decoder, opts)
 	if err != nil {
-		return common.ResponseMessage{
-			Success: false,
-			Message: fmt.Sprintf("erro ao fazer upload: %v", err),
-		}, nil
+		return errorResponse("erro ao fazer upload", err), nil
 	}
 
-	log.Printf("📤 Upload realizado: %s (%d bytes)", req.FileName, len(data))
+	log.Printf("📤 Upload realizado: %s (%d bytes)", req.FileName, info.Size)
 	return common.ResponseMessage{
 		Success: true,
 		Message: fmt.Sprintf("arquivo %s enviado com sucesso", req.FileName),
+		File:    &info,
 	}, nil
 }
 
@@ -217,32 +274,138 @@ func (s *Server) handleUpload(req common.RequestMessage) (common.ResponseMessage
 func (s *Server) handleDownload(req common.RequestMessage) (common.ResponseMessage, error) {
 	if req.FileName == "" {
 		return common.ResponseMessage{
-			Success: false,
-			Message: "nome do arquivo não pode ser vazio",
+			Success:   false,
+			Message:   "nome do arquivo não pode ser vazio",
+			ErrorCode: common.ErrorCodeInvalidArgument,
 		}, nil
 	}
 
-	data, err := s.storage.DownloadFile(req.FileName)
+	opts := common.DownloadOptions{Offset: req.Offset, Length: req.Length}
+	file, info, err := s.storage.DownloadFile(req.FileName, opts)
 	if err != nil {
-		return common.ResponseMessage{
-			Success: false,
-			Message: fmt.Sprintf("erro ao fazer download: %v", err),
-		}, nil
+		return errorResponse("erro ao fazer download", err), nil
+	}
+	defer file.Close()
+
+	// A resposta leva o trecho inteiro, por isso trechos maiores que
+	// MaxMessageFileSize são recusados antes da leitura
+	offset, length, _ := opts.Range(info.Size)
+	if length > common.MaxMessageFileSize {
+		return errorResponse("erro ao fazer download", fmt.Errorf("%w: o trecho tem %d bytes e o limite é de %d; baixe em trechos com offset e length", common.ErrTooLarge, length, common.MaxMessageFileSize)), nil
 	}
 
-	log.Printf("📥 Download realizado: %s (%d bytes)", req.FileName, len(data))
-	
-	// Codifica os dados em base64 para JSON
-	encodedData := base64.StdEncoding.EncodeToString(data)
-	
+	// O armazenamento confere o conteúdo com o checksum durante a leitura;
+	// sem checksum registrado, ou num trecho, é enviado o do conteúdo lido
+	hash := sha256.New()
+	encoded, n, err := encodeBase64(io.TeeReader(file, hash))
+	if err != nil {
+		return errorResponse("erro ao ler arquivo", err), nil
+	}
+	checksum := info.SHA256
+	if checksum == "" || opts.Partial() {
+		checksum = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	log.Printf("📥 Download realizado: %s (%d bytes)", req.FileName, n)
+
 	return common.ResponseMessage{
 		Success:  true,
 		FileName: req.FileName,
-		FileData: []byte(encodedData),
+		FileData: encoded,
+		Checksum: checksum,
+		Offset:   offset,
+		Size:     info.Size,
 		Message:  fmt.Sprintf("arquivo %s baixado com sucesso", req.FileName),
 	}, nil
 }
 
+// encodeBase64 lê r até o fim e retorna o conteúdo codificado em base64 para
+// JSON, junto com o número de bytes lidos. O conteúdo codificado é mantido em
+// memória para a resposta, por isso a leitura falha com ErrTooLarge se r tiver
+// mais que MaxMessageFileSize bytes
+func encodeBase64(r io.Reader) ([]byte, int64, error) {
+	var encoded bytes.Buffer
+	encoder := base64.NewEncoder(base64.StdEncoding, &encoded)
+	n, err := io.CopyBuffer(encoder, io.LimitReader(r, common.MaxMessageFileSize+1), make([]byte, common.ChunkSize))
+	if err == nil && n > common.MaxMessageFileSize {
+		err = fmt.Errorf("%w: o limite é de %d bytes", common.ErrTooLarge, common.MaxMessageFileSize)
+	}
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		return nil, n, err
+	}
+	return encoded.Bytes(), n, nil
+}
+
+// handleDelete processa a operação de remover um arquivo
+func (s *Server) handleDelete(req common.RequestMessage) (common.ResponseMessage, error) {
+	if err := s.storage.DeleteFile(req.FileName); err != nil {
+		return errorResponse("erro ao remover arquivo", err), nil
+	}
+
+	log.Printf("🗑️  Arquivo removido: %s", req.FileName)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.FileName,
+		Message:  fmt.Sprintf("arquivo %s removido com sucesso", req.FileName),
+	}, nil
+}
+
+// handleRename processa a operação de renomear um arquivo
+func (s *Server) handleRename(req common.RequestMessage) (common.ResponseMessage, error) {
+	if err := s.storage.RenameFile(req.FileName, req.NewName); err != nil {
+		return errorResponse("erro ao renomear arquivo", err), nil
+	}
+
+	log.Printf("✏️  Arquivo renomeado: %s -> %s", req.FileName, req.NewName)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.NewName,
+		Message:  fmt.Sprintf("arquivo %s renomeado para %s", req.FileName, req.NewName),
+	}, nil
+}
+
+// handleStat processa a operação de consultar as informações de um arquivo
+func (s *Server) handleStat(req common.RequestMessage) (common.ResponseMessage, error) {
+	info, err := s.storage.StatFile(req.FileName)
+	if err != nil {
+		return errorResponse("erro ao consultar arquivo", err), nil
+	}
+
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: info.Name,
+		File:     &info,
+		Message:  fmt.Sprintf("arquivo %s encontrado", info.Name),
+	}, nil
+}
+
+// handleMkdir processa a operação de criar um diretório
+func (s *Server) handleMkdir(req common.RequestMessage) (common.ResponseMessage, error) {
+	if err := s.storage.CreateDirectory(req.FileName); err != nil {
+		return errorResponse("erro ao criar diretório", err), nil
+	}
+
+	log.Printf("📁 Diretório criado: %s", req.FileName)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.FileName,
+		Message:  fmt.Sprintf("diretório %s criado com sucesso", req.FileName),
+	}, nil
+}
+
+// errorResponse monta uma resposta de falha com a mensagem e o código de erro
+// correspondentes a err
+func errorResponse(msg string, err error) common.ResponseMessage {
+	return common.ResponseMessage{
+		Success:   false,
+		Message:   fmt.Sprintf("%s: %v", msg, err),
+		ErrorCode: common.ErrorCode(err),
+	}
+}
+
 // sendResponse envia uma resposta para a fila de retorno
 func (s *Server) sendResponse(msg amqp.Delivery, resp common.ResponseMessage) error {
 	// Serializa a resposta
@@ -253,10 +416,10 @@ func (s *Server) sendResponse(msg amqp.Delivery, resp common.ResponseMessage) er
 
 	// Publica na fila de resposta (usando ReplyTo da mensagem original)
 	err = s.channel.Publish(
-		"",              // exchange
-		msg.ReplyTo,     // routing key (fila de resposta)
-		false,           // mandatory
-		false,            // immediate
+		"",          // exchange
+		msg.ReplyTo, // routing key (fila de resposta)
+		false,       // mandatory
+		false,       // immediate
 		amqp.Publishing{
 			ContentType:   "application/json",
 			CorrelationId: msg.CorrelationId,
@@ -286,4 +449,3 @@ func (s *Server) Close() error {
 	}
 	return nil
 }
-
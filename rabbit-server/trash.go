@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+)
+
+// trasher retorna o armazenamento como common.Trasher, ou uma resposta de
+// falha se ele não mantiver lixeira
+func (s *Server) trasher() (common.Trasher, *common.ResponseMessage) {
+	trasher, ok := s.storage.(common.Trasher)
+	if !ok {
+		return nil, &common.ResponseMessage{
+			Success:   false,
+			Message:   "o armazenamento não mantém lixeira",
+			ErrorCode: common.ErrorCodeUnimplemented,
+		}
+	}
+	return trasher, nil
+}
+
+// handleListTrash processa a operação de listar a lixeira
+func (s *Server) handleListTrash(req common.RequestMessage) (common.ResponseMessage, error) {
+	trasher, failure := s.trasher()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	entries, err := trasher.ListTrash()
+	if err != nil {
+		return errorResponse("erro ao listar a lixeira", err), nil
+	}
+
+	log.Printf("🗑️  Listadas %d entrada(s) da lixeira", len(entries))
+	return common.ResponseMessage{
+		Success: true,
+		Trash:   entries,
+		Message: fmt.Sprintf("%d entrada(s) na lixeira", len(entries)),
+	}, nil
+}
+
+// handleRestoreTrash processa a operação de restaurar uma entrada da lixeira
+func (s *Server) handleRestoreTrash(req common.RequestMessage) (common.ResponseMessage, error) {
+	trasher, failure := s.trasher()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	info, err := trasher.RestoreTrash(req.TrashID)
+	if err != nil {
+		return errorResponse("erro ao restaurar da lixeira", err), nil
+	}
+
+	log.Printf("♻️  Entrada %s da lixeira restaurada em %s", req.TrashID, info.Name)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: info.Name,
+		File:     &info,
+		Message:  fmt.Sprintf("%s restaurado da lixeira", info.Name),
+	}, nil
+}
+
+// handlePurgeTrash processa a operação de remover definitivamente uma
+// entrada da lixeira ou, com All, todas elas
+func (s *Server) handlePurgeTrash(req common.RequestMessage) (common.ResponseMessage, error) {
+	if (req.TrashID == "") == !req.All {
+		return common.ResponseMessage{
+			Success:   false,
+			Message:   "informe uma entrada da lixeira ou all, não ambos",
+			ErrorCode: common.ErrorCodeInvalidArgument,
+		}, nil
+	}
+
+	trasher, failure := s.trasher()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	entries, err := trasher.PurgeTrash(req.TrashID)
+	if err != nil {
+		return errorResponse("erro ao esvaziar a lixeira", err), nil
+	}
+
+	log.Printf("🔥 %d entrada(s) removida(s) da lixeira", len(entries))
+	return common.ResponseMessage{
+		Success: true,
+		Trash:   entries,
+		Message: fmt.Sprintf("%d entrada(s) removida(s) da lixeira", len(entries)),
+	}, nil
+}
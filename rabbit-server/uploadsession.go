@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+)
+
+// handleSessionCreate inicia uma sessão de upload retomável
+func (s *Server) handleSessionCreate(req common.RequestMessage) (common.ResponseMessage, error) {
+	session, err := s.sessions.Create(req.FileName, req.Size, req.Checksum, common.UploadTTL(req.TTL, s.defaultTTL), req.Tags)
+	if err != nil {
+		return errorResponse("erro ao criar sessão", err), nil
+	}
+
+	log.Printf("🆕 Sessão %s criada para %s (%d bytes)", session.ID, session.Name, session.Size)
+	return sessionResponse(session, fmt.Sprintf("sessão %s criada", session.ID)), nil
+}
+
+// handleSessionStatus retorna o offset confirmado de uma sessão
+func (s *Server) handleSessionStatus(req common.RequestMessage) (common.ResponseMessage, error) {
+	session, err := s.sessions.Get(req.SessionID)
+	if err != nil {
+		return errorResponse("erro ao consultar sessão", err), nil
+	}
+
+	return sessionResponse(session, fmt.Sprintf("%d de %d bytes recebidos", session.Offset, session.Size)), nil
+}
+
+// handleSessionAppend grava um bloco de dados na sessão a partir do offset informado
+func (s *Server) handleSessionAppend(req common.RequestMessage) (common.ResponseMessage, error) {
+	if len(req.FileData) == 0 {
+		return common.ResponseMessage{
+			Success:   false,
+			Message:   "dados do arquivo não podem ser vazios",
+			ErrorCode: common.ErrorCodeInvalidArgument,
+		}, nil
+	}
+
+	// FileData contém o bloco codificado em base64, como na operação "upload"
+	decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(req.FileData))
+
+	offset, err := s.sessions.Append(req.SessionID, req.Offset, decoder)
+	if err != nil {
+		return common.ResponseMessage{
+			Success:   false,
+			SessionID: req.SessionID,
+			Offset:    offset,
+			Message:   fmt.Sprintf("erro ao gravar bloco: %v", err),
+			ErrorCode: common.ErrorCode(err),
+		}, nil
+	}
+
+	session, err := s.sessions.Get(req.SessionID)
+	if err != nil {
+		return errorResponse("erro ao consultar sessão", err), nil
+	}
+
+	log.Printf("📦 Sessão %s em %d de %d bytes", session.ID, session.Offset, session.Size)
+	return sessionResponse(session, fmt.Sprintf("%d de %d bytes recebidos", session.Offset, session.Size)), nil
+}
+
+// handleSessionCommit conclui a sessão e grava o arquivo no armazenamento
+func (s *Server) handleSessionCommit(req common.RequestMessage) (common.ResponseMessage, error) {
+	session, err := s.sessions.Commit(req.SessionID)
+	if err != nil {
+		return errorResponse("erro ao concluir sessão", err), nil
+	}
+
+	log.Printf("📤 Upload realizado: %s (%d bytes, sessão %s)", session.Name, session.Size, session.ID)
+	return sessionResponse(session, fmt.Sprintf("arquivo %s enviado com sucesso", session.Name)), nil
+}
+
+// handleSessionAbort cancela a sessão e descarta os dados recebidos
+func (s *Server) handleSessionAbort(req common.RequestMessage) (common.ResponseMessage, error) {
+	if err := s.sessions.Abort(req.SessionID); err != nil {
+		return errorResponse("erro ao cancelar sessão", err), nil
+	}
+
+	log.Printf("🗑️  Sessão %s cancelada", req.SessionID)
+	return common.ResponseMessage{
+		Success:   true,
+		SessionID: req.SessionID,
+		Message:   fmt.Sprintf("sessão %s cancelada", req.SessionID),
+	}, nil
+}
+
+// sessionResponse monta a resposta com o estado atual da sessão
+func sessionResponse(session *common.UploadSession, message string) common.ResponseMessage {
+	return common.ResponseMessage{
+		Success:   true,
+		SessionID: session.ID,
+		FileName:  session.Name,
+		Offset:    session.Offset,
+		Size:      session.Size,
+		Message:   message,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+
+	"grpc-rabbitmq-fileshare/common"
+)
+
+// versioner retorna o armazenamento como common.Versioner, ou uma resposta de
+// falha se ele não mantiver versões anteriores
+func (s *Server) versioner() (common.Versioner, *common.ResponseMessage) {
+	versioner, ok := s.storage.(common.Versioner)
+	if !ok {
+		return nil, &common.ResponseMessage{
+			Success:   false,
+			Message:   "o armazenamento não mantém versões de arquivos",
+			ErrorCode: common.ErrorCodeUnimplemented,
+		}
+	}
+	return versioner, nil
+}
+
+// handleVersions processa a operação de listar as versões anteriores de um arquivo
+func (s *Server) handleVersions(req common.RequestMessage) (common.ResponseMessage, error) {
+	versioner, failure := s.versioner()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	versions, err := versioner.ListVersions(req.FileName)
+	if err != nil {
+		return errorResponse("erro ao listar versões", err), nil
+	}
+
+	log.Printf("🕘 Listadas %d versão(ões) de %s", len(versions), req.FileName)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.FileName,
+		Versions: versions,
+		Message:  fmt.Sprintf("%d versão(ões) encontrada(s)", len(versions)),
+	}, nil
+}
+
+// handleDownloadVersion processa a operação de download de uma versão anterior
+func (s *Server) handleDownloadVersion(req common.RequestMessage) (common.ResponseMessage, error) {
+	versioner, failure := s.versioner()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	file, version, err := versioner.DownloadVersion(req.FileName, req.VersionID)
+	if err != nil {
+		return errorResponse("erro ao fazer download da versão", err), nil
+	}
+	defer file.Close()
+
+	// Como em handleDownload, o armazenamento confere o conteúdo com o
+	// checksum registrado quando a versão foi guardada; sem ele, é enviado o
+	// do conteúdo lido
+	hash := sha256.New()
+	encoded, n, err := encodeBase64(io.TeeReader(file, hash))
+	if err != nil {
+		return errorResponse("erro ao ler versão", err), nil
+	}
+	checksum := version.SHA256
+	if checksum == "" {
+		checksum = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	log.Printf("📥 Download da versão %s de %s realizado (%d bytes)", req.VersionID, req.FileName, n)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.FileName,
+		FileData: encoded,
+		Checksum: checksum,
+		Size:     n,
+		Message:  fmt.Sprintf("versão %s de %s baixada com sucesso", req.VersionID, req.FileName),
+	}, nil
+}
+
+// handleRestoreVersion processa a operação de restaurar uma versão anterior
+func (s *Server) handleRestoreVersion(req common.RequestMessage) (common.ResponseMessage, error) {
+	versioner, failure := s.versioner()
+	if failure != nil {
+		return *failure, nil
+	}
+
+	if err := versioner.RestoreVersion(req.FileName, req.VersionID); err != nil {
+		return errorResponse("erro ao restaurar versão", err), nil
+	}
+
+	log.Printf("⏪ Versão %s de %s restaurada", req.VersionID, req.FileName)
+	return common.ResponseMessage{
+		Success:  true,
+		FileName: req.FileName,
+		Message:  fmt.Sprintf("versão %s de %s restaurada com sucesso", req.VersionID, req.FileName),
+	}, nil
+}
@@ -149,10 +149,10 @@ func WarmUpGRPCConnection(serverAddr string, operation string) error {
 	// Executa uma operação simples para aquecer a conexão
 	switch operation {
 	case "list":
-		_, err = client.ListFiles(ctx, &proto.Empty{})
+		_, err = client.ListFiles(ctx, &proto.ListRequest{})
 	default:
 		// Para outras operações, faz list como warm-up
-		_, err = client.ListFiles(ctx, &proto.Empty{})
+		_, err = client.ListFiles(ctx, &proto.ListRequest{})
 	}
 
 	return err
@@ -194,7 +194,7 @@ func (br *BenchmarkRunner) RunGRPCOperation(
 
 	switch operation {
 	case "list":
-		_, err = client.ListFiles(ctx, &proto.Empty{})
+		_, err = client.ListFiles(ctx, &proto.ListRequest{})
 	case "upload":
 		if filePath != "" {
 			data, readErr := os.ReadFile(filePath)
@@ -315,7 +315,7 @@ func (mcr *MixedConcurrencyRunner) runGRPCOperation(
 
 	switch operation {
 	case "list":
-		_, err = client.ListFiles(ctx, &proto.Empty{})
+		_, err = client.ListFiles(ctx, &proto.ListRequest{})
 	case "upload":
 		if filePath != "" {
 			data, readErr := os.ReadFile(filePath)